@@ -2,73 +2,136 @@ package agent
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
-	"runtime"
+	"os"
 	"sync"
 	"time"
 )
 
 // Agent 监控和管理MEV Bot的代理程序
 type Agent struct {
-	mevConfigPath   string
-	mevConfig       *Config
-	agentConfig     *FlashAgentConfig
-	proc            *ProcessManager
-	ws              *WebSocketServer
-	mu              sync.RWMutex
-	isRunning       bool
-	manuallyStopped bool // 新增: 标记是否为主动停止
-	ctx             context.Context
-	cancelFunc      context.CancelFunc
-	statusChecks    chan struct{}
+	store         *ConfigStore // MEV Bot配置文件的唯一读写入口
+	agentConfig   *FlashAgentConfig
+	proc          *ProcessManager
+	ws            *WebSocketServer
+	mu            sync.RWMutex
+	isRunning     bool
+	restartPolicy *RestartPolicy
+	history       *RunHistory // MEV Bot运行历史
+	audit         *AuditLog   // 控制操作审计日志
+	metrics       *Metrics    // Prometheus指标
+	liveness      *LivenessMonitor
+	hotTokens     *HotTokensTracker
+	ctx           context.Context
+	cancelFunc    context.CancelFunc
+
+	// MEV Bot的生命周期状态，独立于mu，查询状态时不必等待正在进行的启停操作
+	stateMu         sync.RWMutex
+	state           BotState
+	stateSince      time.Time
+	manuallyStopped bool     // 标记是否为主动停止
+	operation       BotState // 正在进行的启停或配置更新操作，没有时为空，见 beginOperation
+
+	// 健康检查状态
+	healthMu        sync.RWMutex
+	startedAt       time.Time // 代理启动时间
+	loopBeat        time.Time // 监控循环最近一次运行的时间
+	hotTokenRefresh time.Time // 最近一次成功刷新热门代币的时间
+	hotTokenErr     string    // 最近一次热门代币刷新的错误，成功后清空
+
+	// systemd通知状态，只在监控循环中访问
+	sdReady        bool
+	sdWatchdog     time.Duration
+	sdWatchdogSent time.Time
 }
 
 // NewAgent 创建一个新的代理实例
-func NewAgent(mevConfigPath string, agentConfigPath string) (*Agent, error) {
+// overrides 中的命令行参数优先于YAML配置
+func NewAgent(agentConfigPath string, overrides Overrides) (*Agent, error) {
+	// 初始化 FlashAgent 配置文件
+	// 只有配置文件不存在时使用默认设置；配置无效时拒绝启动，避免以默认设置运行
+	agentConfig, err := LoadFlashAgentConfig(agentConfigPath)
+	if errors.Is(err, os.ErrNotExist) {
+		log.Printf("加载FlashAgent配置文件失败，使用默认设置: %v", err)
+		agentConfig = DefaultFlashAgentConfig()
+	} else if err != nil {
+		return nil, err
+	}
+	agentConfig.ApplyOverrides(overrides)
+	// 设置日志输出
+	SetupLogger(&agentConfig.Logging)
+
 	// 加载 MEV 配置文件
-	mevConfig, err := LoadConfig(mevConfigPath)
+	configHistory, err := NewConfigHistory(agentConfig.ConfigHistory.Dir, agentConfig.ConfigHistory.Limit)
 	if err != nil {
 		return nil, err
 	}
-
-	// 初始化 FlashAgent 配置文件
-	agentConfig, err := LoadFlashAgentConfig(agentConfigPath)
-	var logConfig *LogConfig
+	store, err := NewConfigStore(agentConfig.Process.ConfigPath, configHistory)
 	if err != nil {
-		log.Printf("加载FlashAgent配置文件失败，使用默认设置: %v", err)
-		logConfig = GetDefaultLogConfig()
-	} else {
-		logConfig = &agentConfig.Logging
+		return nil, err
 	}
-	// 设置日志输出
-	SetupLogger(logConfig)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	agent := &Agent{
-		mevConfig:    mevConfig,
-		agentConfig:  agentConfig,
-		isRunning:    false,
-		ctx:          ctx,
-		cancelFunc:   cancel,
-		statusChecks: make(chan struct{}, 1),
+		store:         store,
+		agentConfig:   agentConfig,
+		isRunning:     false,
+		ctx:           ctx,
+		cancelFunc:    cancel,
+		state:         BotStateStopped,
+		stateSince:    time.Now(),
+		restartPolicy: NewRestartPolicy(agentConfig.RestartPolicy),
+		metrics:       NewMetrics(),
 	}
 
 	// 创建进程管理器
-	execName := "smb-onchain"
-	if runtime.GOOS == "windows" {
-		execName = "smb-onchain.exe"
+	executable, args, err := agentConfig.Process.BotCommand()
+	if err != nil {
+		return nil, err
 	}
 
 	agent.proc = NewProcessManager(
-		"MEV Bot",     // 名称
-		"./"+execName, // 可执行文件路径
-		"run",
-		"config.toml",
+		"MEV Bot",  // 名称
+		executable, // 可执行文件路径
+		args...,
 	)
+	agent.proc.SetWorkDir(agentConfig.Process.WorkDir)
+	agent.proc.SetEnv(agentConfig.Process.BotEnv())
+
+	// 设置MEV Bot输出的捕获方式
+	botLogOutput, err := NewBotLogOutput(&agentConfig.BotLog)
+	if err != nil {
+		log.Printf("初始化MEV Bot日志文件失败，仅保留内存日志: %v", err)
+	}
+	agent.proc.SetLogOutput(NewLogBuffer(agentConfig.BotLog.BufferLines), botLogOutput)
+	agent.proc.SetStopTimeout(time.Duration(agentConfig.Process.StopTimeout) * time.Second)
+	agent.history = NewRunHistory(agentConfig.Process.HistoryPath, agentConfig.Process.HistorySize)
+	agent.proc.SetRunHistory(agent.history, agentConfig.Process.HistoryLogLines)
+
+	agent.liveness, err = NewLivenessMonitor(agentConfig.Liveness)
+	if err != nil {
+		return nil, fmt.Errorf("加载存活检测配置失败: %w", err)
+	}
+
+	agent.hotTokens, err = NewHotTokensTracker(agentConfig, agent)
+	if err != nil {
+		return nil, fmt.Errorf("加载热门代币配置失败: %w", err)
+	}
+
+	agent.audit, err = NewAuditLog(agentConfig.Audit)
+	if err != nil {
+		return nil, err
+	}
 
 	// 创建WebSocket服务器
-	agent.ws = NewWebSocketServer(":8080", agent)
+	auth, err := NewAuthenticator(agentConfig)
+	if err != nil {
+		return nil, fmt.Errorf("加载认证配置失败: %w", err)
+	}
+	agent.ws = NewWebSocketServer(agentConfig.Server, auth, agent)
 
 	return agent, nil
 }
@@ -83,6 +146,10 @@ func (a *Agent) Start() error {
 	}
 
 	log.Println("启动MEV Bot代理...")
+	a.healthMu.Lock()
+	a.startedAt = time.Now()
+	a.healthMu.Unlock()
+	a.sdWatchdog = sdWatchdogInterval()
 
 	// 启动WebSocket服务器
 	if err := a.ws.Start(); err != nil {
@@ -90,14 +157,17 @@ func (a *Agent) Start() error {
 	}
 
 	// 启动MEV Bot进程
+	a.setState(BotStateStarting)
 	if err := a.proc.Start(); err != nil {
+		a.setState(BotStateStopped)
 		a.ws.Stop()
 		return err
 	}
+	a.restartPolicy.MarkStarted(time.Now())
+	a.setState(BotStateRunning)
 
-	hotTokensTracker := NewHotTokensTracker(a.mevConfig, a.agentConfig, a)
 	// 启动热点跟踪器
-	go hotTokensTracker.StartTracking()
+	go a.hotTokens.StartTracking()
 
 	// 启动状态监控
 	go a.monitorStatus()
@@ -118,22 +188,30 @@ func (a *Agent) Stop() error {
 	}
 
 	log.Println("停止MEV Bot代理...")
+	if _, err := sdNotify(sdNotifyStopping); err != nil {
+		log.Printf("发送systemd停止通知失败: %v", err)
+	}
 
 	// 标记为主动停止
-	a.manuallyStopped = true
+	a.setManuallyStopped(true)
 
 	// 停止监控
 	a.cancelFunc()
 
 	// 停止MEV Bot进程
-	if err := a.proc.Stop(); err != nil {
+	a.setState(BotStateStopping)
+	if _, err := a.proc.Stop(StopCauseShutdown); err != nil {
 		log.Printf("停止MEV Bot进程时出错: %v", err)
 	}
+	a.setState(BotStateStopped)
 
 	// 停止WebSocket服务器
 	if err := a.ws.Stop(); err != nil {
 		log.Printf("停止WebSocket服务器时出错: %v", err)
 	}
+	if err := a.audit.Close(); err != nil {
+		log.Printf("关闭审计日志时出错: %v", err)
+	}
 
 	a.isRunning = false
 	log.Println("MEV Bot代理已停止")
@@ -141,20 +219,32 @@ func (a *Agent) Stop() error {
 	return nil
 }
 
-// 新增方法: 手动停止MEV Bot但保持代理运行
+// StopMEVBot 手动停止MEV Bot但保持代理运行
+// 有启停或配置更新正在进行时返回错误
 func (a *Agent) StopMEVBot() error {
+	if err := a.beginOperation(BotStateStopping); err != nil {
+		return err
+	}
+	defer a.endOperation()
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
 	log.Println("手动停止MEV Bot...")
 
 	// 标记为主动停止
-	a.manuallyStopped = true
+	a.setManuallyStopped(true)
 
 	// 停止MEV Bot
-	if err := a.proc.Stop(); err != nil {
+	a.setState(BotStateStopping)
+	status, err := a.proc.Stop(StopCauseManual)
+	if err != nil {
+		a.syncState()
 		return err
 	}
+	a.setState(BotStateStopped)
+	if status != nil {
+		log.Printf("MEV Bot已停止: %s", status)
+	}
 
 	// 通知所有客户端
 	a.ws.BroadcastMessage("MEV Bot已手动停止")
@@ -162,20 +252,32 @@ func (a *Agent) StopMEVBot() error {
 	return nil
 }
 
-// 新增方法: 手动启动MEV Bot
+// StartMEVBot 手动启动MEV Bot
+// 有启停或配置更新正在进行时返回错误
 func (a *Agent) StartMEVBot() error {
+	if err := a.beginOperation(BotStateStarting); err != nil {
+		return err
+	}
+	defer a.endOperation()
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
 	log.Println("手动启动MEV Bot...")
 
-	// 取消主动停止标记
-	a.manuallyStopped = false
+	// 取消主动停止标记，手动启动同时清除崩溃循环状态
+	a.setManuallyStopped(false)
+	a.restartPolicy.Reset()
 
 	// 启动MEV Bot
+	if !a.proc.IsRunning() {
+		a.setState(BotStateStarting)
+	}
 	if err := a.proc.Start(); err != nil {
+		a.setState(BotStateStopped)
 		return err
 	}
+	a.restartPolicy.MarkStarted(time.Now())
+	a.setState(BotStateRunning)
 
 	// 通知所有客户端
 	a.ws.BroadcastMessage("MEV Bot已手动启动")
@@ -183,27 +285,40 @@ func (a *Agent) StartMEVBot() error {
 	return nil
 }
 
-// RestartMEVBot 重启MEV Bot进程
-func (a *Agent) RestartMEVBot() error {
+// RestartMEVBot 重启MEV Bot进程，cause记录重启的触发方式
+// 有启停或配置更新正在进行时返回错误
+func (a *Agent) RestartMEVBot(cause StopCause) error {
+	if err := a.beginOperation(BotStateStopping); err != nil {
+		return err
+	}
+	defer a.endOperation()
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
 	log.Println("正在重启MEV Bot...")
 
-	// 停止MEV Bot
-	if err := a.proc.Stop(); err != nil {
+	// 标记为主动停止
+	a.setManuallyStopped(true)
+
+	// 停止MEV Bot，等待旧进程退出后再启动
+	a.setState(BotStateStopping)
+	if _, err := a.proc.Stop(cause); err != nil {
+		a.syncState()
 		return err
 	}
 
-	// 标记为主动停止
-	a.manuallyStopped = true
-
-	// 启动MEV Bot
+	// 启动MEV Bot，手动重启同时清除崩溃循环状态
+	a.restartPolicy.Reset()
+	a.setState(BotStateStarting)
 	if err := a.proc.Start(); err != nil {
+		a.setState(BotStateStopped)
 		return err
 	}
-	// 标记为主动停止
-	a.manuallyStopped = false
+	a.restartPolicy.MarkStarted(time.Now())
+	a.metrics.ObserveRestart(cause)
+	a.setState(BotStateRunning)
+	// 取消主动停止标记
+	a.setManuallyStopped(false)
 
 	// 通知所有客户端
 	a.ws.BroadcastMessage("MEV Bot已重启")
@@ -211,29 +326,57 @@ func (a *Agent) RestartMEVBot() error {
 	return nil
 }
 
-// UpdateConfig 更新配置文件并重启MEV Bot
-func (a *Agent) UpdateConfig(updatedConfig *Config) error {
+// UpdateConfig 更新配置文件并重启MEV Bot，info记录修改者和原因
+// MEV Bot已手动停止或处于崩溃循环时只保存配置，MEV Bot保持停止，等待手动启动
+// 有启停或配置更新正在进行时返回错误
+func (a *Agent) UpdateConfig(updatedConfig *Config, info ChangeInfo) error {
+	if err := a.beginOperation(BotStateUpdatingConfig); err != nil {
+		return err
+	}
+	defer a.endOperation()
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	log.Println("更新MEV Bot配置...")
+	log.Printf("更新MEV Bot配置 (修改者: %s, 原因: %s)...", info.Author, info.Reason)
 
 	// 保存配置文件
-	if err := updatedConfig.SaveToFile(a.mevConfigPath); err != nil {
+	previous := a.State()
+	a.setState(BotStateUpdatingConfig)
+	revision, err := a.store.Save(updatedConfig, info)
+	if err != nil {
+		a.setState(previous)
 		return err
 	}
+	log.Printf("MEV Bot配置已保存为版本 %d", revision)
 
-	a.mevConfig = updatedConfig
+	if a.isManuallyStopped() || a.restartPolicy.InCrashLoop() {
+		a.setState(previous)
+		log.Println("MEV Bot已停止，新配置将在手动启动后生效")
+		a.ws.BroadcastMessage("MEV Bot配置已更新，MEV Bot保持停止")
+		return nil
+	}
 
-	// 重启MEV Bot
-	if err := a.proc.Stop(); err != nil {
+	// 重启MEV Bot，旧进程未能退出时不启动新进程，避免两个实例同时运行
+	a.setManuallyStopped(true)
+	if _, err := a.proc.Stop(StopCauseConfig); err != nil {
 		log.Printf("停止MEV Bot进程时出错: %v", err)
+		a.setManuallyStopped(false)
+		a.syncState()
+		return err
 	}
 
+	// 与手动重启一样清除退避状态
+	a.restartPolicy.Reset()
 	if err := a.proc.Start(); err != nil {
 		log.Printf("启动MEV Bot进程时出错: %v", err)
+		a.setManuallyStopped(false)
+		a.setState(BotStateStopped)
 		return err
 	}
+	a.restartPolicy.MarkStarted(time.Now())
+	a.metrics.ObserveRestart(StopCauseConfig)
+	a.setState(BotStateRunning)
+	a.setManuallyStopped(false)
 
 	// 通知所有客户端
 	a.ws.BroadcastMessage("MEV Bot配置已更新并重启")
@@ -241,54 +384,152 @@ func (a *Agent) UpdateConfig(updatedConfig *Config) error {
 	return nil
 }
 
-// monitorStatus 监控MEV Bot的状态
+// RollbackConfig 将配置恢复到指定版本并重启MEV Bot
+// 回滚本身会记录为一个新版本；版本中的敏感字段已脱敏，回滚时保持当前配置中的值
+func (a *Agent) RollbackConfig(revision int64, info ChangeInfo) error {
+	rev, err := a.store.History().Get(revision)
+	if err != nil {
+		return err
+	}
+	if rev.Config == nil {
+		return fmt.Errorf("配置版本 %d 没有配置内容", revision)
+	}
+
+	KeepSecrets(rev.Config, a.store.Current())
+
+	if info.Reason == "" {
+		info.Reason = fmt.Sprintf("回滚到版本 %d", revision)
+	}
+	return a.UpdateConfig(rev.Config, info)
+}
+
+// monitorStatus 监控MEV Bot的状态，同时驱动健康检查的心跳和systemd通知
 func (a *Agent) monitorStatus() {
-	ticker := time.NewTicker(10 * time.Second)
+	ticker := time.NewTicker(time.Second)
 	defer ticker.Stop()
 
+	now := time.Now()
+	a.markLoopBeat(now)
+	a.notifySystemd(now)
+
 	for {
 		select {
 		case <-a.ctx.Done():
 			return
-		case <-ticker.C:
-			a.mu.RLock()
-			if !a.proc.IsRunning() && !a.manuallyStopped {
-				// 只有在非主动停止的情况下才自动重启
-				log.Println("检测到MEV Bot意外停止，尝试重启...")
-
-				// 释放读锁，获取写锁以便修改状态
-				a.mu.RUnlock()
-				a.mu.Lock()
-
-				if err := a.proc.Start(); err != nil {
-					log.Printf("重启MEV Bot失败: %v", err)
-					a.ws.BroadcastMessage("MEV Bot重启失败: " + err.Error())
-				} else {
-					log.Println("MEV Bot已成功重启")
-					a.ws.BroadcastMessage("MEV Bot已自动重启")
-				}
-
-				a.mu.Unlock()
-			} else {
-				a.mu.RUnlock()
-			}
-		case <-a.statusChecks:
-			// 手动检查状态
-			status := "运行中"
-			manualStatus := ""
-
-			a.mu.RLock()
-			if !a.proc.IsRunning() {
-				status = "已停止"
-				if a.manuallyStopped {
-					manualStatus = " (手动停止)"
-				} else {
-					manualStatus = " (意外停止)"
-				}
-			}
-			a.mu.RUnlock()
+		case now := <-ticker.C:
+			a.superviseBot(now)
+			a.markLoopBeat(now)
+			a.notifySystemd(now)
+		}
+	}
+}
 
-			a.ws.BroadcastMessage("MEV Bot状态: " + status + manualStatus)
+// superviseBot 按重启策略处理MEV Bot的意外退出和存活检测失败
+// 有启停或配置更新正在进行时跳过本次检查，避免阻塞监控循环
+func (a *Agent) superviseBot(now time.Time) {
+	if !a.mu.TryLock() {
+		return
+	}
+	defer a.mu.Unlock()
+
+	// 有命令已占用操作并在等待mu，由它决定MEV Bot的状态
+	if a.operationInProgress() {
+		return
+	}
+
+	if a.proc.IsRunning() {
+		a.setState(BotStateRunning)
+		pid := a.proc.PID()
+		if reason := a.liveness.Check(now, pid, a.proc.StartTime(), a.proc.Logs()); reason != "" {
+			log.Printf("[告警] MEV Bot存活检测失败: %s", reason)
+			a.ws.BroadcastMessage(fmt.Sprintf("[告警] MEV Bot存活检测失败: %s，正在停止并按重启策略重启", reason))
+			if err := a.beginOperation(BotStateStopping); err != nil {
+				return
+			}
+			go a.stopUnhealthy(pid, reason)
+			return
 		}
+		// 持续运行足够长时间后重置退避
+		if a.restartPolicy.CheckHealthy(now) {
+			log.Println("MEV Bot已稳定运行，重置自动重启退避")
+		}
+		return
+	}
+
+	if a.State() == BotStateRunning {
+		a.setState(BotStateStopped)
+	}
+	// 只有在非主动停止的情况下才自动重启
+	if a.isManuallyStopped() || a.restartPolicy.InCrashLoop() {
+		return
+	}
+
+	// 首次发现退出时按退避时间安排重启
+	if !a.restartPolicy.Pending() {
+		delay := a.restartPolicy.ScheduleRestart(now)
+		log.Printf("检测到MEV Bot意外停止，%.1f秒后尝试重启...", delay.Seconds())
+		a.ws.BroadcastMessage(fmt.Sprintf("MEV Bot意外停止，%.0f秒后自动重启", delay.Seconds()))
+		return
+	}
+
+	if !a.restartPolicy.Due(now) {
+		return
+	}
+
+	if !a.restartPolicy.AllowRestart(now) {
+		status := a.restartPolicy.Status()
+		a.setState(BotStateCrashLooping)
+		log.Printf("MEV Bot在%d秒内重启次数达到上限，判定为崩溃循环，停止自动重启", a.agentConfig.RestartPolicy.Window)
+		a.ws.BroadcastMessage(fmt.Sprintf("[告警] MEV Bot进入崩溃循环: 连续失败%d次，已停止自动重启，请检查配置后手动启动",
+			status.ConsecutiveFailures))
+		return
+	}
+
+	if err := a.beginOperation(BotStateStarting); err != nil {
+		return
+	}
+	defer a.endOperation()
+	log.Println("MEV Bot意外停止，按重启策略尝试重启...")
+	a.setState(BotStateStarting)
+	if err := a.proc.Start(); err != nil {
+		a.setState(BotStateStopped)
+		log.Printf("重启MEV Bot失败: %v", err)
+		a.ws.BroadcastMessage("MEV Bot重启失败: " + err.Error())
+		return
+	}
+
+	a.restartPolicy.MarkStarted(now)
+	cause := StopCauseExited
+	if record, ok := a.history.Last(); ok {
+		cause = record.StopCause
+	}
+	a.metrics.ObserveRestart(cause)
+	a.setState(BotStateRunning)
+	log.Println("MEV Bot已成功重启")
+	a.ws.BroadcastMessage("MEV Bot已自动重启")
+}
+
+// stopUnhealthy 停止存活检测失败的MEV Bot进程，reason记录到运行历史，之后由superviseBot像崩溃一样按重启策略重启
+// 停止可能需要等待优雅关闭的超时时间，因此在监控循环之外执行；调用方已占用操作，结束时释放
+func (a *Agent) stopUnhealthy(pid int, reason string) {
+	defer a.endOperation()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// 等待锁期间进程可能已被手动停止或重启
+	if a.proc.PID() != pid {
+		return
+	}
+
+	a.setState(BotStateStopping)
+	status, err := a.proc.StopWithReason(StopCauseUnhealthy, reason)
+	if err != nil {
+		log.Printf("停止存活检测失败的MEV Bot时出错: %v", err)
+		a.syncState()
+		return
+	}
+	if status != nil {
+		log.Printf("MEV Bot已因存活检测失败停止: %s", status)
 	}
+	a.setState(BotStateStopped)
 }
@@ -7,6 +7,10 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
 
 	"gopkg.in/natefinch/lumberjack.v2"
 	"gopkg.in/yaml.v3"
@@ -14,15 +18,77 @@ import (
 
 // FlashAgentConfig 表示整个代理配置
 type FlashAgentConfig struct {
-	Logging        LogConfig      `yaml:"logging"`  // 日志配置
-	Ave            AveConfig      `yaml:"ave"`      // Ave服务配置
-	Wechat         WechatConfig   `yaml:"wechat"`   // 微信配置
-	SolScan        SolScanConfig  `yaml:"solscan"`  // Solscan配置
-	HotTokenConfig HotTokenConfig `yaml:"hottoken"` // 热点Token配置
+	Logging        LogConfig           `yaml:"logging"`        // 日志配置
+	Ave            AveConfig           `yaml:"ave"`            // Ave服务配置
+	Wechat         WechatConfig        `yaml:"wechat"`         // 微信配置
+	SolScan        SolScanConfig       `yaml:"solscan"`        // Solscan配置
+	HotTokenConfig HotTokenConfig      `yaml:"hottoken"`       // 热点Token配置
+	BotLog         BotLogConfig        `yaml:"bot_log"`        // MEV Bot输出日志配置
+	RestartPolicy  RestartPolicyConfig `yaml:"restart_policy"` // 自动重启策略配置
+	Process        ProcessConfig       `yaml:"process"`        // MEV Bot进程配置
+	Server         ServerConfig        `yaml:"server"`         // WebSocket服务配置
+	ConfigHistory  ConfigHistoryConfig `yaml:"config_history"` // MEV Bot配置版本历史
+	Auth           AuthConfig          `yaml:"auth"`           // 控制接口认证配置
+	Audit          AuditConfig         `yaml:"audit"`          // 审计日志配置
+	Health         HealthConfig        `yaml:"health"`         // 健康检查配置
+	Liveness       LivenessConfig      `yaml:"liveness"`       // MEV Bot存活检测配置
 }
 
+// HealthConfig 表示 /healthz、/readyz 和systemd看门狗的配置
+type HealthConfig struct {
+	HotTokenStaleAfter int `yaml:"hot_token_stale_after"` // 热门代币超过该时间未成功刷新则判定为未就绪（秒），默认为刷新间隔的3倍
+}
+
+// AuditConfig 表示控制操作审计日志的配置
+type AuditConfig struct {
+	Path       string `yaml:"path"`        // JSON Lines审计日志文件路径
+	MaxSize    int    `yaml:"max_size"`    // 单个文件最大大小，MB，超过后轮换
+	MaxBackups int    `yaml:"max_backups"` // 保留的旧文件数，0表示全部保留
+	MaxAge     int    `yaml:"max_age"`     // 保留旧文件的最大天数，0表示不按时间删除
+}
+
+// ConfigHistoryConfig 表示MEV Bot配置版本历史的存储配置
+type ConfigHistoryConfig struct {
+	Dir   string `yaml:"dir"`   // 版本文件存放目录
+	Limit int    `yaml:"limit"` // 保留的版本数
+}
+
+// ServerConfig 表示代理HTTP/WebSocket服务的配置
+type ServerConfig struct {
+	ListenAddr       string `yaml:"listen_addr"`        // 监听地址
+	WriteTimeout     int    `yaml:"write_timeout"`      // 单条消息的写超时（秒）
+	PingInterval     int    `yaml:"ping_interval"`      // 向客户端发送ping的间隔（秒）
+	PongTimeout      int    `yaml:"pong_timeout"`       // 超过该时间没有收到pong或消息则断开连接（秒）
+	SendQueue        int    `yaml:"send_queue"`         // 每个客户端的发送队列长度
+	SlowClientPolicy string `yaml:"slow_client_policy"` // 发送队列已满时的处理方式：drop 或 disconnect
+
+	TLS            TLSConfig `yaml:"tls"`             // TLS配置，未配置证书时使用明文
+	AllowedOrigins []string  `yaml:"allowed_origins"` // 允许的浏览器来源，同源请求总是允许
+	AllowInsecure  bool      `yaml:"allow_insecure"`  // 允许在非回环地址上以明文监听
+}
+
+// ProcessConfig 表示MEV Bot进程的运行配置
+type ProcessConfig struct {
+	Executable      string            `yaml:"executable"`        // Bot可执行文件路径，相对路径基于work_dir
+	Args            []string          `yaml:"args"`              // 启动参数模板，{config} 会被替换为TOML配置文件路径
+	WorkDir         string            `yaml:"work_dir"`          // Bot的工作目录，为空则使用代理的工作目录
+	Env             map[string]string `yaml:"env"`               // 额外的环境变量
+	ConfigPath      string            `yaml:"config_path"`       // MEV Bot的TOML配置文件路径
+	StopTimeout     int               `yaml:"stop_timeout"`      // 发送SIGTERM后等待退出的秒数，超时后发送SIGKILL
+	HistoryPath     string            `yaml:"history_path"`      // 运行历史的持久化文件
+	HistorySize     int               `yaml:"history_size"`      // 保留的运行记录条数
+	HistoryLogLines int               `yaml:"history_log_lines"` // 每条运行记录保留的退出前日志行数
+}
+
+// HotTokenConfig 表示热门代币跟踪器的配置
 type HotTokenConfig struct {
-	Interval int `yaml:"interval"` // 热点Token
+	Interval int                    `yaml:"interval"` // 热点Token刷新间隔，分钟
+	Sources  []HotTokenSourceConfig `yaml:"sources"`  // 热门代币数据源，为空时只使用Ave
+
+	PoolDiscovery PoolDiscoveryConfig `yaml:"pool_discovery"` // 热门代币的池发现配置
+	PoolRanking   PoolRankingConfig   `yaml:"pool_ranking"`   // 热门代币池的评分和过滤配置
+
+	Selection TokenSelectionPolicy `yaml:"selection"` // 写入MintConfig的代币选择策略
 }
 
 // LogConfig 表示日志配置
@@ -35,6 +101,24 @@ type LogConfig struct {
 	LocalTime  bool   `yaml:"local_time"`  // 使用本地时间而非UTC时间
 }
 
+// BotLogConfig 表示MEV Bot输出日志的捕获配置
+type BotLogConfig struct {
+	BufferLines int       `yaml:"buffer_lines"` // 内存环形缓冲区保留的行数
+	Echo        bool      `yaml:"echo"`         // 是否同时输出到代理的标准输出
+	File        LogConfig `yaml:"file"`         // 可选的日志文件，output_path为空时不写文件
+}
+
+// RestartPolicyConfig 表示MEV Bot意外退出后的自动重启策略
+type RestartPolicyConfig struct {
+	InitialBackoff int      `yaml:"initial_backoff"` // 首次重启前的等待时间，秒
+	MaxBackoff     int      `yaml:"max_backoff"`     // 重启等待时间上限，秒
+	Multiplier     float64  `yaml:"multiplier"`      // 每次连续失败后等待时间的倍数
+	Jitter         *float64 `yaml:"jitter"`          // 等待时间的随机抖动比例，0~1，未设置时为0.2，设为0表示不抖动
+	MaxRestarts    int      `yaml:"max_restarts"`    // 时间窗口内允许的最大重启次数，超出即判定为崩溃循环
+	Window         int      `yaml:"window"`          // 统计重启次数的时间窗口，秒
+	HealthyAfter   int      `yaml:"healthy_after"`   // 连续运行多少秒后视为恢复健康并重置退避
+}
+
 // AveConfig 表示Ave服务配置
 type AveConfig struct {
 	Token string `yaml:"token"` // Ave服务认证令牌
@@ -57,7 +141,7 @@ type SolScanConfig struct {
 func LoadFlashAgentConfig(path string) (*FlashAgentConfig, error) {
 	// 检查文件是否存在
 	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return nil, fmt.Errorf("配置文件 %s 不存在", path)
+		return nil, fmt.Errorf("配置文件 %s 不存在: %w", path, err)
 	}
 
 	// 读取文件内容
@@ -72,7 +156,44 @@ func LoadFlashAgentConfig(path string) (*FlashAgentConfig, error) {
 		return nil, fmt.Errorf("解析YAML配置失败: %w", err)
 	}
 
-	// 设置默认值（如果必要的话）
+	config.applyDefaults()
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("配置文件 %s 无效: %w", path, err)
+	}
+
+	return &config, nil
+}
+
+// DefaultFlashAgentConfig 返回配置文件缺失时使用的默认代理配置
+func DefaultFlashAgentConfig() *FlashAgentConfig {
+	config := &FlashAgentConfig{
+		Logging: *GetDefaultLogConfig(),
+		BotLog: BotLogConfig{
+			Echo: true,
+		},
+	}
+	config.applyDefaults()
+	return config
+}
+
+// validate 检查填充默认值后仍然无效的配置项
+// 显式设置的无效值返回错误，而不是替换为默认值
+func (config *FlashAgentConfig) validate() error {
+	switch config.Server.SlowClientPolicy {
+	case SlowClientDrop, SlowClientDisconnect:
+	default:
+		return fmt.Errorf("server.slow_client_policy: 未知的处理方式 %q，可选 %s、%s",
+			config.Server.SlowClientPolicy, SlowClientDrop, SlowClientDisconnect)
+	}
+	if config.Server.PongTimeout <= config.Server.PingInterval {
+		return fmt.Errorf("server.pong_timeout: %d秒必须大于 server.ping_interval（%d秒）",
+			config.Server.PongTimeout, config.Server.PingInterval)
+	}
+	return nil
+}
+
+// applyDefaults 为未设置的配置项填充默认值
+func (config *FlashAgentConfig) applyDefaults() {
 	if config.Logging.OutputPath == "" {
 		config.Logging.OutputPath = "flash.log"
 	}
@@ -85,8 +206,134 @@ func LoadFlashAgentConfig(path string) (*FlashAgentConfig, error) {
 	if config.Logging.MaxAge <= 0 {
 		config.Logging.MaxAge = 30
 	}
+	if config.BotLog.BufferLines <= 0 {
+		config.BotLog.BufferLines = defaultLogBufferLines
+	}
 
-	return &config, nil
+	if config.Process.Executable == "" {
+		config.Process.Executable = defaultBotExecutable()
+	}
+	if len(config.Process.Args) == 0 {
+		config.Process.Args = []string{"run", configPathPlaceholder}
+	}
+	if config.Process.ConfigPath == "" {
+		config.Process.ConfigPath = "config.toml"
+	}
+	if config.ConfigHistory.Dir == "" {
+		config.ConfigHistory.Dir = "config_history"
+	}
+	if config.ConfigHistory.Limit <= 0 {
+		config.ConfigHistory.Limit = 200
+	}
+	if config.Health.HotTokenStaleAfter <= 0 {
+		config.Health.HotTokenStaleAfter = 1800
+		if config.HotTokenConfig.Interval > 0 {
+			config.Health.HotTokenStaleAfter = config.HotTokenConfig.Interval * 60 * 3
+		}
+	}
+	if config.Audit.Path == "" {
+		config.Audit.Path = "audit.log"
+	}
+	if config.Audit.MaxSize <= 0 {
+		config.Audit.MaxSize = 100
+	}
+	if config.Server.ListenAddr == "" {
+		config.Server.ListenAddr = "127.0.0.1:8080"
+	}
+	if config.Server.WriteTimeout <= 0 {
+		config.Server.WriteTimeout = 10
+	}
+	if config.Server.PingInterval <= 0 {
+		config.Server.PingInterval = 30
+	}
+	if config.Server.PongTimeout <= 0 {
+		config.Server.PongTimeout = config.Server.PingInterval * 2
+	}
+	if config.Server.SendQueue <= 0 {
+		config.Server.SendQueue = 256
+	}
+	if config.Server.SlowClientPolicy == "" {
+		config.Server.SlowClientPolicy = SlowClientDisconnect
+	}
+	if config.Process.StopTimeout <= 0 {
+		config.Process.StopTimeout = int(defaultStopTimeout / time.Second)
+	}
+	if config.Process.HistoryPath == "" {
+		config.Process.HistoryPath = "run_history.json"
+	}
+	if config.Process.HistorySize <= 0 {
+		config.Process.HistorySize = 100
+	}
+	if config.Process.HistoryLogLines <= 0 {
+		config.Process.HistoryLogLines = 50
+	}
+
+	if config.HotTokenConfig.PoolDiscovery.Timeout <= 0 {
+		config.HotTokenConfig.PoolDiscovery.Timeout = 30
+	}
+
+	ranking := &config.HotTokenConfig.PoolRanking
+	if weights := ranking.Weights; weights.Liquidity <= 0 && weights.Volume <= 0 && weights.Trades <= 0 && weights.Fee <= 0 {
+		ranking.Weights = PoolScoreWeights{Liquidity: 0.5, Volume: 0.3, Trades: 0.1, Fee: 0.1}
+	}
+
+	selection := &config.HotTokenConfig.Selection
+	if selection.SortBy == "" {
+		selection.SortBy = SortByBuyVolume15m
+	}
+	if selection.TopN <= 0 {
+		selection.TopN = 10
+	}
+	if selection.MintSuffixes == nil {
+		selection.MintSuffixes = []string{"pump"} // 当前只交易pump代币，设为 [] 表示不限制
+	}
+	if selection.MinPoolTypes <= 0 {
+		selection.MinPoolTypes = 2
+	}
+	if selection.MaxTokens <= 0 {
+		selection.MaxTokens = 2
+	}
+	if selection.MaxPoolsPerKind <= 0 {
+		selection.MaxPoolsPerKind = 2
+	}
+
+	liveness := &config.Liveness
+	if liveness.StartupGrace <= 0 {
+		liveness.StartupGrace = 30
+	}
+	if liveness.Probe.Interval <= 0 {
+		liveness.Probe.Interval = 10
+	}
+	if liveness.Probe.Timeout <= 0 {
+		liveness.Probe.Timeout = 5
+	}
+	if liveness.Probe.FailureThreshold <= 0 {
+		liveness.Probe.FailureThreshold = 3
+	}
+
+	policy := &config.RestartPolicy
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = 5
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = 300
+	}
+	if policy.Multiplier < 1 {
+		policy.Multiplier = 2
+	}
+	if policy.Jitter == nil || *policy.Jitter < 0 || *policy.Jitter > 1 {
+		jitter := 0.2
+		policy.Jitter = &jitter
+	}
+	if policy.MaxRestarts <= 0 {
+		policy.MaxRestarts = 5
+	}
+	if policy.Window <= 0 {
+		policy.Window = 600
+	}
+	if policy.HealthyAfter <= 0 {
+		policy.HealthyAfter = 120
+	}
 }
 
 // GetDefaultLogConfig 返回默认日志配置
@@ -112,8 +359,107 @@ func SetupLogger(config *LogConfig) error {
 		}
 	}
 
-	// 设置日志轮换
-	logRotator := &lumberjack.Logger{
+	// 同时输出到文件和控制台
+	multiWriter := io.MultiWriter(os.Stdout, newLogRotator(config))
+	log.SetOutput(multiWriter)
+
+	// 设置日志格式，包含日期、时间和文件信息
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	log.Printf("日志已配置为输出到: %s", config.OutputPath)
+
+	return nil
+}
+
+// configPathPlaceholder 启动参数模板中代表TOML配置文件路径的占位符
+const configPathPlaceholder = "{config}"
+
+// defaultBotExecutable 返回当前平台默认的Bot可执行文件路径
+func defaultBotExecutable() string {
+	if runtime.GOOS == "windows" {
+		return "./smb-onchain.exe"
+	}
+	return "./smb-onchain"
+}
+
+// Overrides 表示命令行参数对YAML配置的覆盖，空值表示不覆盖
+type Overrides struct {
+	MevConfigPath string
+	ListenAddr    string
+	Executable    string
+	Args          []string
+	WorkDir       string
+	Env           map[string]string
+}
+
+// ApplyOverrides 使用命令行参数覆盖配置
+func (config *FlashAgentConfig) ApplyOverrides(o Overrides) {
+	if o.MevConfigPath != "" {
+		config.Process.ConfigPath = o.MevConfigPath
+	}
+	if o.ListenAddr != "" {
+		config.Server.ListenAddr = o.ListenAddr
+	}
+	if o.Executable != "" {
+		config.Process.Executable = o.Executable
+	}
+	if len(o.Args) > 0 {
+		config.Process.Args = o.Args
+	}
+	if o.WorkDir != "" {
+		config.Process.WorkDir = o.WorkDir
+	}
+	if len(o.Env) > 0 && config.Process.Env == nil {
+		config.Process.Env = make(map[string]string, len(o.Env))
+	}
+	for k, v := range o.Env {
+		config.Process.Env[k] = v
+	}
+}
+
+// BotCommand 解析Bot的可执行文件路径和启动参数
+// 带目录的相对路径基于工作目录解析为绝对路径，不带目录的名称保持不变以便在PATH中查找
+// 参数中的 {config} 替换为TOML配置文件的绝对路径
+func (p *ProcessConfig) BotCommand() (string, []string, error) {
+	executable := p.Executable
+	if !filepath.IsAbs(executable) && strings.ContainsAny(executable, "/"+string(filepath.Separator)) {
+		abs, err := filepath.Abs(filepath.Join(p.WorkDir, executable))
+		if err != nil {
+			return "", nil, fmt.Errorf("解析可执行文件路径失败: %w", err)
+		}
+		executable = abs
+	}
+
+	configPath, err := filepath.Abs(p.ConfigPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("解析配置文件路径失败: %w", err)
+	}
+
+	args := make([]string, len(p.Args))
+	for i, arg := range p.Args {
+		args[i] = strings.ReplaceAll(arg, configPathPlaceholder, configPath)
+	}
+	return executable, args, nil
+}
+
+// BotEnv 返回Bot进程的环境变量：继承代理的环境变量并追加额外配置
+func (p *ProcessConfig) BotEnv() []string {
+	env := os.Environ()
+
+	keys := make([]string, 0, len(p.Env))
+	for k := range p.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+p.Env[k])
+	}
+	return env
+}
+
+// newLogRotator 根据日志配置创建按大小轮换的日志文件写入器
+func newLogRotator(config *LogConfig) *lumberjack.Logger {
+	return &lumberjack.Logger{
 		Filename:   config.OutputPath,
 		MaxSize:    config.MaxSize,
 		MaxBackups: config.MaxBackups,
@@ -121,17 +467,34 @@ func SetupLogger(config *LogConfig) error {
 		Compress:   config.Compress,
 		LocalTime:  config.LocalTime,
 	}
+}
 
-	// 同时输出到文件和控制台
-	multiWriter := io.MultiWriter(os.Stdout, logRotator)
-	log.SetOutput(multiWriter)
-
-	// 设置日志格式，包含日期、时间和文件信息
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+// NewBotLogOutput 根据配置创建MEV Bot输出的额外写入目标
+// 返回nil表示只捕获到内存缓冲区
+func NewBotLogOutput(config *BotLogConfig) (io.Writer, error) {
+	var writers []io.Writer
+	if config.Echo {
+		writers = append(writers, os.Stdout)
+	}
 
-	log.Printf("日志已配置为输出到: %s", config.OutputPath)
+	if config.File.OutputPath != "" {
+		logDir := filepath.Dir(config.File.OutputPath)
+		if logDir != "" && logDir != "." {
+			if err := os.MkdirAll(logDir, 0755); err != nil {
+				return nil, fmt.Errorf("创建Bot日志目录失败: %w", err)
+			}
+		}
+		writers = append(writers, newLogRotator(&config.File))
+	}
 
-	return nil
+	switch len(writers) {
+	case 0:
+		return nil, nil
+	case 1:
+		return writers[0], nil
+	default:
+		return io.MultiWriter(writers...), nil
+	}
 }
 
 // SaveFlashAgentConfig 将配置保存回YAML文件
@@ -0,0 +1,64 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadFlashAgentConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		yaml       string
+		wantErr    string
+		wantPolicy string
+		wantPong   int
+	}{
+		{name: "defaults", yaml: "", wantPolicy: SlowClientDisconnect, wantPong: 60},
+		{name: "drop", yaml: "server:\n  slow_client_policy: drop\n", wantPolicy: SlowClientDrop, wantPong: 60},
+		{name: "pong derived from ping", yaml: "server:\n  ping_interval: 10\n", wantPolicy: SlowClientDisconnect, wantPong: 20},
+		{name: "explicit pong", yaml: "server:\n  ping_interval: 10\n  pong_timeout: 15\n", wantPolicy: SlowClientDisconnect, wantPong: 15},
+		{name: "unknown policy", yaml: "server:\n  slow_client_policy: dorp\n", wantErr: "server.slow_client_policy"},
+		{name: "pong equals ping", yaml: "server:\n  ping_interval: 30\n  pong_timeout: 30\n", wantErr: "server.pong_timeout"},
+		{name: "pong below default ping", yaml: "server:\n  pong_timeout: 10\n", wantErr: "server.pong_timeout"},
+		{name: "invalid yaml", yaml: "server: [", wantErr: "解析YAML配置失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0600); err != nil {
+				t.Fatal(err)
+			}
+			config, err := LoadFlashAgentConfig(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("错误 %v，期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Server.SlowClientPolicy != tt.wantPolicy || config.Server.PongTimeout != tt.wantPong {
+				t.Errorf("slow_client_policy=%q pong_timeout=%d，期望 %q %d",
+					config.Server.SlowClientPolicy, config.Server.PongTimeout, tt.wantPolicy, tt.wantPong)
+			}
+		})
+	}
+}
+
+// 配置文件不存在时可以与其他错误区分，代理使用默认设置
+func TestLoadFlashAgentConfigMissingFile(t *testing.T) {
+	_, err := LoadFlashAgentConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("错误 %v，期望 os.ErrNotExist", err)
+	}
+}
+
+func TestTemplateAgentConfigLoads(t *testing.T) {
+	if _, err := LoadFlashAgentConfig("../config.template.yaml"); err != nil {
+		t.Fatal(err)
+	}
+}
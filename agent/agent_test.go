@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+// newLifecycleTestAgent 创建带WebSocket服务器的测试代理，MEV Bot为长时间运行的sleep进程
+func newLifecycleTestAgent(t *testing.T) *Agent {
+	t.Helper()
+	a := newTestAgent(t)
+	auth, err := NewAuthenticator(a.agentConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.ws = NewWebSocketServer(a.agentConfig.Server, auth, a)
+	go a.ws.broadcastMessages()
+
+	a.proc = NewProcessManager("MEV Bot", "sleep", "30")
+	a.proc.SetRunHistory(a.history, 0)
+	t.Cleanup(func() { a.proc.Stop(StopCauseShutdown) })
+	return a
+}
+
+// updateTestConfig 修改一个字段后调用 UpdateConfig
+func updateTestConfig(t *testing.T, a *Agent) {
+	t.Helper()
+	config := a.store.Current()
+	config.Bot.ComputeUnitLimit++
+	if err := a.UpdateConfig(config, ChangeInfo{Author: "test", Reason: "test"}); err != nil {
+		t.Fatalf("UpdateConfig失败: %v", err)
+	}
+}
+
+func TestUpdateConfigKeepsStoppedBotDown(t *testing.T) {
+	a := newLifecycleTestAgent(t)
+	a.setManuallyStopped(true)
+	revision := a.store.Revision()
+
+	updateTestConfig(t, a)
+
+	if a.store.Revision() != revision+1 {
+		t.Errorf("配置版本 %d，期望 %d", a.store.Revision(), revision+1)
+	}
+	if a.proc.IsRunning() {
+		t.Error("手动停止的MEV Bot被配置更新启动")
+	}
+	if !a.isManuallyStopped() {
+		t.Error("配置更新清除了手动停止标记")
+	}
+	if state := a.State(); state != BotStateStopped {
+		t.Errorf("状态 %s，期望 %s", state, BotStateStopped)
+	}
+}
+
+func TestUpdateConfigRestartsRunningBot(t *testing.T) {
+	a := newLifecycleTestAgent(t)
+	if err := a.proc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	a.setState(BotStateRunning)
+	pid := a.proc.PID()
+
+	updateTestConfig(t, a)
+
+	if !a.proc.IsRunning() || a.proc.PID() == pid {
+		t.Errorf("MEV Bot没有重启: 运行 %v，PID %d -> %d", a.proc.IsRunning(), pid, a.proc.PID())
+	}
+	if a.isManuallyStopped() {
+		t.Error("配置更新后仍标记为手动停止")
+	}
+	if state := a.State(); state != BotStateRunning {
+		t.Errorf("状态 %s，期望 %s", state, BotStateRunning)
+	}
+}
+
+func TestSuperviseBotSyncsStateWhenManuallyStopped(t *testing.T) {
+	a := newLifecycleTestAgent(t)
+	a.setState(BotStateRunning)
+	a.setManuallyStopped(true)
+
+	a.superviseBot(time.Now())
+
+	if state := a.State(); state != BotStateStopped {
+		t.Errorf("进程已退出时状态 %s，期望 %s", state, BotStateStopped)
+	}
+	if a.restartPolicy.Pending() {
+		t.Error("手动停止后安排了自动重启")
+	}
+}
+
+// 一个操作占用后在等待mu时，其他启停和配置更新命令立即返回busy，而不是排队执行
+func TestConcurrentOperationsRejectedWhileBusy(t *testing.T) {
+	a := newLifecycleTestAgent(t)
+	if err := a.proc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	a.setState(BotStateRunning)
+
+	// 持有mu模拟正在进行的长时间操作，第一个重启占用操作后阻塞在mu上
+	a.mu.Lock()
+	first := make(chan error, 1)
+	go func() { first <- a.RestartMEVBot(StopCauseManual) }()
+	waitFor(t, time.Second, "重启占用操作", a.operationInProgress)
+
+	others := map[string]func() error{
+		"restart": func() error { return a.RestartMEVBot(StopCauseManual) },
+		"stop":    a.StopMEVBot,
+		"start":   a.StartMEVBot,
+		"update": func() error {
+			return a.UpdateConfig(a.store.Current(), ChangeInfo{Author: "test"})
+		},
+	}
+	for name, op := range others {
+		err := op()
+		if info := errorInfoOf(err); err == nil || info.Code != ErrCodeBusy {
+			t.Errorf("%s: 期望busy错误，得到 %v", name, err)
+		}
+	}
+
+	a.mu.Unlock()
+	if err := <-first; err != nil {
+		t.Fatalf("第一个重启失败: %v", err)
+	}
+	if a.operationInProgress() {
+		t.Error("重启结束后没有释放操作")
+	}
+	if !a.proc.IsRunning() || a.State() != BotStateRunning {
+		t.Errorf("重启后状态 %s，运行 %v", a.State(), a.proc.IsRunning())
+	}
+}
+
+// 广播队列已满时状态转换不会阻塞
+func TestSetStateDoesNotBlockOnFullBroadcast(t *testing.T) {
+	a := newTestAgent(t)
+	auth, err := NewAuthenticator(a.agentConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.ws = NewWebSocketServer(a.agentConfig.Server, auth, a) // 不启动广播goroutine，队列不会被消费
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < cap(a.ws.broadcast)+10; i++ {
+			a.setState(BotStateStarting)
+			a.setState(BotStateStopped)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("广播队列已满时setState阻塞")
+	}
+}
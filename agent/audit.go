@@ -0,0 +1,266 @@
+package agent
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// 审计结果
+const (
+	AuditResultOK    = "ok"
+	AuditResultError = "error"
+)
+
+// AuditEntry 审计日志中的一条记录
+type AuditEntry struct {
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor"`            // 令牌名称，自动修改时为 hot-token-tracker
+	Remote    string          `json:"remote,omitempty"` // 客户端地址
+	Command   string          `json:"command"`          // 命令名，如 config/update
+	RequestID string          `json:"request_id,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"` // 脱敏后的命令参数
+	Result    string          `json:"result"`            // ok 或 error
+	Error     string          `json:"error,omitempty"`
+	Revision  int64           `json:"revision,omitempty"` // 执行后的配置版本
+}
+
+// AuditQuery 审计日志的查询条件，零值字段不做过滤
+type AuditQuery struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Actor   string    `json:"actor"`
+	Command string    `json:"command"`
+	Limit   int       `json:"limit"` // 最多返回的条数，默认100
+}
+
+// AuditLog 只追加的JSON Lines审计日志，按大小轮换
+// 写入由lumberjack串行化；查询通过独立的文件句柄读取，不会阻塞写入
+type AuditLog struct {
+	path   string
+	writer *lumberjack.Logger
+}
+
+// NewAuditLog 打开（或创建）审计日志文件
+func NewAuditLog(config AuditConfig) (*AuditLog, error) {
+	if dir := filepath.Dir(config.Path); dir != "" {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("创建审计日志目录失败: %w", err)
+		}
+	}
+	// 提前创建文件，权限错误在启动时就能发现
+	file, err := os.OpenFile(config.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志失败: %w", err)
+	}
+	file.Close()
+
+	return &AuditLog{
+		path: config.Path,
+		writer: &lumberjack.Logger{
+			Filename:   config.Path,
+			MaxSize:    config.MaxSize,
+			MaxBackups: config.MaxBackups,
+			MaxAge:     config.MaxAge,
+			LocalTime:  true,
+		},
+	}, nil
+}
+
+// Record 追加一条审计记录，写入失败只记日志
+func (a *AuditLog) Record(entry AuditEntry) {
+	if a == nil {
+		return
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("序列化审计记录失败: %v", err)
+		return
+	}
+	if _, err := a.writer.Write(append(data, '\n')); err != nil {
+		log.Printf("写入审计日志失败: %v", err)
+	}
+}
+
+// Query 按条件查询审计记录，最新的在前
+// 依次读取轮换后的旧文件和当前文件；查询期间发生轮换时，边界附近的记录可能缺失
+func (a *AuditLog) Query(query AuditQuery) ([]AuditEntry, error) {
+	if query.Limit <= 0 {
+		query.Limit = 100
+	}
+
+	files, err := a.files()
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []AuditEntry
+	for _, path := range files {
+		if err := scanAuditFile(path, func(entry *AuditEntry) {
+			if !query.matches(entry) {
+				return
+			}
+			matched = append(matched, *entry)
+			// 只保留最近的Limit条
+			if len(matched) > query.Limit {
+				matched = matched[1:]
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	result := make([]AuditEntry, 0, len(matched))
+	for i := len(matched) - 1; i >= 0; i-- {
+		result = append(result, matched[i])
+	}
+	return result, nil
+}
+
+// files 返回按时间从旧到新排列的审计日志文件：轮换后的旧文件在前，当前文件在最后
+// lumberjack的旧文件名为 <名称>-<时间戳><扩展名>，时间戳按字典序即按时间排序；压缩的旧文件不参与查询
+func (a *AuditLog) files() ([]string, error) {
+	dir := filepath.Dir(a.path)
+	ext := filepath.Ext(a.path)
+	prefix := strings.TrimSuffix(filepath.Base(a.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取审计日志目录失败: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, ext) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return append(files, a.path), nil
+}
+
+// scanAuditFile 逐行解析审计日志文件，无法解析的行（如正在写入的最后一行）被跳过
+// 文件不存在时不返回错误
+func scanAuditFile(path string, fn func(*AuditEntry)) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取审计日志失败: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize*2)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		fn(&entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取审计日志 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// Close 关闭审计日志文件
+func (a *AuditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	return a.writer.Close()
+}
+
+// matches 判断记录是否满足查询条件
+func (q *AuditQuery) matches(entry *AuditEntry) bool {
+	if !q.From.IsZero() && entry.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && entry.Time.After(q.To) {
+		return false
+	}
+	if q.Actor != "" && entry.Actor != q.Actor {
+		return false
+	}
+	if q.Command != "" && entry.Command != q.Command {
+		return false
+	}
+	return true
+}
+
+// sanitizePayload 返回脱敏后的命令参数，敏感字段替换为占位符
+func sanitizePayload(cmd *Command) json.RawMessage {
+	if len(cmd.Value) == 0 {
+		return nil
+	}
+
+	// updateSection 直接修改敏感字段时整体脱敏
+	if cmd.Section != "" {
+		path := cmd.Section
+		if cmd.Key != "" {
+			path += "." + cmd.Key
+		}
+		if IsSecretPath(path) {
+			data, _ := json.Marshal(RedactedPlaceholder)
+			return data
+		}
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(cmd.Value, &value); err != nil {
+		return nil
+	}
+	data, err := json.Marshal(redactSecretKeys(value))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// secretKeys 敏感字段的键名，同时匹配TOML键名和Go字段名（忽略大小写和下划线）
+var secretKeys = func() map[string]bool {
+	keys := make(map[string]bool)
+	for path := range secretPaths {
+		keys[normalizeSecretKey(lastSegment(path))] = true
+	}
+	return keys
+}()
+
+// normalizeSecretKey 规范化键名：转为小写并去掉下划线
+func normalizeSecretKey(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", ""))
+}
+
+// redactSecretKeys 递归替换JSON值中敏感键对应的值
+func redactSecretKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if secretKeys[normalizeSecretKey(key)] {
+				v[key] = redactValue(child)
+			} else {
+				v[key] = redactSecretKeys(child)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactSecretKeys(child)
+		}
+	}
+	return value
+}
@@ -0,0 +1,205 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAuditLog 在临时目录中创建审计日志
+func newTestAuditLog(t *testing.T, config AuditConfig) *AuditLog {
+	t.Helper()
+	if config.Path == "" {
+		config.Path = filepath.Join(t.TempDir(), "audit.log")
+	}
+	audit, err := NewAuditLog(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { audit.Close() })
+	return audit
+}
+
+// auditCommands 返回记录的命令名和请求ID，用于比较查询结果
+func auditCommands(entries []AuditEntry) []string {
+	var result []string
+	for _, entry := range entries {
+		result = append(result, entry.Command+"#"+entry.RequestID)
+	}
+	return result
+}
+
+func TestAuditQuery(t *testing.T) {
+	audit := newTestAuditLog(t, AuditConfig{})
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	entries := []AuditEntry{
+		{Time: base, Actor: "ops", Command: "config/update", RequestID: "1", Result: AuditResultOK, Revision: 2},
+		{Time: base.Add(time.Minute), Actor: "viewer", Command: "config/get", RequestID: "2", Result: AuditResultOK},
+		{Time: base.Add(2 * time.Minute), Actor: "ops", Command: "bot/restart", RequestID: "3", Result: AuditResultError, Error: "busy"},
+		{Time: base.Add(3 * time.Minute), Actor: "hot-token-tracker", Command: "config/update", RequestID: "4", Result: AuditResultOK},
+		{Time: base.Add(4 * time.Minute), Actor: "ops", Command: "config/get", RequestID: "5", Result: AuditResultOK},
+	}
+	for _, entry := range entries {
+		audit.Record(entry)
+	}
+
+	tests := []struct {
+		name  string
+		query AuditQuery
+		want  []string
+	}{
+		{name: "all newest first", query: AuditQuery{},
+			want: []string{"config/get#5", "config/update#4", "bot/restart#3", "config/get#2", "config/update#1"}},
+		{name: "actor", query: AuditQuery{Actor: "ops"},
+			want: []string{"config/get#5", "bot/restart#3", "config/update#1"}},
+		{name: "command", query: AuditQuery{Command: "config/update"},
+			want: []string{"config/update#4", "config/update#1"}},
+		{name: "time range inclusive", query: AuditQuery{From: base.Add(time.Minute), To: base.Add(3 * time.Minute)},
+			want: []string{"config/update#4", "bot/restart#3", "config/get#2"}},
+		{name: "from only", query: AuditQuery{From: base.Add(150 * time.Second)},
+			want: []string{"config/get#5", "config/update#4"}},
+		{name: "actor and time", query: AuditQuery{Actor: "ops", To: base.Add(2 * time.Minute)},
+			want: []string{"bot/restart#3", "config/update#1"}},
+		{name: "limit keeps newest", query: AuditQuery{Limit: 2},
+			want: []string{"config/get#5", "config/update#4"}},
+		{name: "no match", query: AuditQuery{Actor: "nobody"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := audit.Query(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if commands := auditCommands(got); !reflect.DeepEqual(commands, tt.want) {
+				t.Errorf("查询结果 %v，期望 %v", commands, tt.want)
+			}
+		})
+	}
+
+	got, _ := audit.Query(AuditQuery{Command: "bot/restart"})
+	if len(got) != 1 || got[0].Error != "busy" || got[0].Result != AuditResultError || !got[0].Time.Equal(base.Add(2*time.Minute)) {
+		t.Errorf("记录内容不符合预期: %+v", got)
+	}
+}
+
+func TestAuditRecordDefaultsTimeAndSkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit := newTestAuditLog(t, AuditConfig{Path: path})
+
+	before := time.Now()
+	audit.Record(AuditEntry{Actor: "ops", Command: "hello"})
+	// 模拟写入中途崩溃留下的半行
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"time": "2024-`)
+	file.Close()
+
+	got, err := audit.Query(AuditQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Time.Before(before) {
+		t.Errorf("查询结果不符合预期: %+v", got)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("审计日志权限 %o，期望 600", perm)
+	}
+}
+
+func TestAuditRotationQueriesBackups(t *testing.T) {
+	dir := t.TempDir()
+	audit := newTestAuditLog(t, AuditConfig{Path: filepath.Join(dir, "audit.log"), MaxSize: 1})
+
+	// 每条约4KB，共约1.2MB，超过1MB后轮换
+	payload, _ := json.Marshal(strings.Repeat("x", 4000))
+	const total = 300
+	for i := 0; i < total; i++ {
+		audit.Record(AuditEntry{Actor: "ops", Command: "config/update", Payload: payload, Result: AuditResultOK, Revision: int64(i + 1)})
+	}
+
+	backups, err := filepath.Glob(filepath.Join(dir, "audit-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) == 0 {
+		t.Fatal("超过 max_size 后没有轮换")
+	}
+
+	got, err := audit.Query(AuditQuery{Limit: total})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != total {
+		t.Fatalf("查询到 %d 条，期望 %d 条", len(got), total)
+	}
+	for i, entry := range got {
+		if entry.Revision != int64(total-i) {
+			t.Fatalf("第%d条版本 %d，期望 %d（跨文件顺序错误）", i, entry.Revision, total-i)
+		}
+	}
+}
+
+func TestSanitizePayload(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Command
+		want string
+	}{
+		{
+			name: "no value",
+			cmd:  Command{Type: "config", Action: "get"},
+		},
+		{
+			name: "full config",
+			cmd: Command{Type: "config", Action: "update", Value: json.RawMessage(
+				`{"rpc":{"url":"https://key@rpc.example.com"},"wallet":{"private":"5Kd3"},` +
+					`"spam":{"enabled":true,"sending_rpc_urls":["https://a","https://b"]},` +
+					`"jito":{"uuid":"","enabled":true},"bot":{"compute_unit_limit":1}}`)},
+			want: `{"bot":{"compute_unit_limit":1},"jito":{"enabled":true,"uuid":""},"rpc":{"url":"********"},` +
+				`"spam":{"enabled":true,"sending_rpc_urls":["********","********"]},"wallet":{"private":"********"}}`,
+		},
+		{
+			name: "go field names",
+			cmd:  Command{Type: "config", Action: "update", Value: json.RawMessage(`{"Wallet":{"Private":"5Kd3"},"RPC":{"URL":"https://x"}}`)},
+			want: `{"RPC":{"URL":"********"},"Wallet":{"Private":"********"}}`,
+		},
+		{
+			name: "secret section key",
+			cmd:  Command{Type: "config", Action: "updateSection", Section: "wallet", Key: "private", Value: json.RawMessage(`"5Kd3"`)},
+			want: `"********"`,
+		},
+		{
+			name: "section with secret key",
+			cmd:  Command{Type: "config", Action: "updateSection", Section: "wallet", Value: json.RawMessage(`{"private":"5Kd3"}`)},
+			want: `{"private":"********"}`,
+		},
+		{
+			name: "section with secret child",
+			cmd:  Command{Type: "config", Action: "updateSection", Section: "spam", Value: json.RawMessage(`{"enabled":false,"sending_rpc_urls":["https://a"]}`)},
+			want: `{"enabled":false,"sending_rpc_urls":["********"]}`,
+		},
+		{
+			name: "plain value",
+			cmd:  Command{Type: "config", Action: "addMint", Value: json.RawMessage(`{"mint":"` + testMintA + `"}`)},
+			want: `{"mint":"` + testMintA + `"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(sanitizePayload(&tt.cmd)); got != tt.want {
+				t.Errorf("脱敏结果\n%s\n期望\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Role 表示API令牌的角色，高级别角色拥有低级别角色的全部权限
+type Role string
+
+const (
+	RoleViewer   Role = "viewer"   // 只读：查看配置、状态、历史和日志
+	RoleOperator Role = "operator" // 运维：重启MEV Bot、增删铸币、切换功能开关
+	RoleAdmin    Role = "admin"    // 管理：修改任意配置、RPC地址和回滚
+)
+
+// roleLevels 角色的权限级别
+var roleLevels = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// Allows 判断角色是否满足required要求的权限
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
+
+// AuthConfig 表示控制接口的认证配置
+type AuthConfig struct {
+	Tokens []APIToken `yaml:"tokens"` // API令牌列表
+}
+
+// APIToken 表示一个命名的API令牌
+type APIToken struct {
+	Name  string `yaml:"name"`  // 令牌名称，记录为配置修改者
+	Token string `yaml:"token"` // 令牌内容
+	Role  Role   `yaml:"role"`  // 角色：viewer、operator 或 admin
+}
+
+// Principal 表示通过认证的调用方
+type Principal struct {
+	Name string
+	Role Role
+}
+
+// Authenticator 根据请求中携带的令牌识别调用方
+type Authenticator struct {
+	tokens []APIToken
+}
+
+// NewAuthenticator 根据代理配置创建认证器
+// wechat.verify_token 作为名为 wechat 的admin令牌继续有效；没有配置任何令牌时不做认证
+func NewAuthenticator(config *FlashAgentConfig) (*Authenticator, error) {
+	tokens := append([]APIToken(nil), config.Auth.Tokens...)
+	if config.Wechat.VerifyToken != "" {
+		tokens = append(tokens, APIToken{Name: "wechat", Token: config.Wechat.VerifyToken, Role: RoleAdmin})
+	}
+
+	seen := make(map[string]string)
+	for i, token := range tokens {
+		if token.Name == "" {
+			return nil, fmt.Errorf("auth.tokens[%d]: 名称不能为空", i)
+		}
+		if token.Token == "" {
+			return nil, fmt.Errorf("auth.tokens[%d] (%s): 令牌不能为空", i, token.Name)
+		}
+		if _, ok := roleLevels[token.Role]; !ok {
+			return nil, fmt.Errorf("auth.tokens[%d] (%s): 未知角色 %q", i, token.Name, token.Role)
+		}
+		if other, ok := seen[token.Token]; ok {
+			return nil, fmt.Errorf("auth.tokens[%d] (%s): 令牌与 %s 重复", i, token.Name, other)
+		}
+		seen[token.Token] = token.Name
+	}
+
+	if len(tokens) == 0 {
+		log.Printf("[警告] 未配置任何API令牌，控制接口不做认证，所有连接都拥有admin权限")
+	}
+	return &Authenticator{tokens: tokens}, nil
+}
+
+// Authenticate 识别请求的调用方
+// 令牌可以通过 Authorization: Bearer <token>、X-Agent-Token 头或 token 查询参数传递
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, bool) {
+	if len(a.tokens) == 0 {
+		return &Principal{Name: "anonymous", Role: RoleAdmin}, true
+	}
+
+	presented := requestToken(r)
+	if presented == "" {
+		return nil, false
+	}
+
+	// 逐个进行常数时间比较，且不提前退出，避免通过响应时间推测令牌
+	var matched *APIToken
+	for i := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(a.tokens[i].Token)) == 1 {
+			matched = &a.tokens[i]
+		}
+	}
+	if matched == nil {
+		return nil, false
+	}
+	return &Principal{Name: matched.Name, Role: matched.Role}, true
+}
+
+// requestToken 从请求头或查询参数中取出令牌
+func requestToken(r *http.Request) string {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		const prefix = "Bearer "
+		if len(auth) > len(prefix) && strings.EqualFold(auth[:len(prefix)], prefix) {
+			return strings.TrimSpace(auth[len(prefix):])
+		}
+	}
+	if token := r.Header.Get("X-Agent-Token"); token != "" {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
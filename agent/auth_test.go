@@ -0,0 +1,249 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testTokens 每个角色一个令牌
+var testTokens = []APIToken{
+	{Name: "dashboard", Token: "viewer-token", Role: RoleViewer},
+	{Name: "ops", Token: "operator-token", Role: RoleOperator},
+	{Name: "root", Token: "admin-token", Role: RoleAdmin},
+}
+
+// newTestWebSocketServer 创建配置了给定令牌的服务，不监听端口
+func newTestWebSocketServer(t *testing.T, tokens ...APIToken) *WebSocketServer {
+	t.Helper()
+	agent := newTestAgent(t)
+	agent.agentConfig.Auth.Tokens = tokens
+	auth, err := NewAuthenticator(agent.agentConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := NewWebSocketServer(agent.agentConfig.Server, auth, agent)
+	agent.ws = ws
+	return ws
+}
+
+func TestNewAuthenticatorRejectsInvalidTokens(t *testing.T) {
+	tests := []struct {
+		name    string
+		tokens  []APIToken
+		wechat  string
+		wantErr string
+	}{
+		{name: "valid", tokens: testTokens},
+		{name: "none", tokens: nil},
+		{name: "empty name", tokens: []APIToken{{Token: "t", Role: RoleViewer}}, wantErr: "名称不能为空"},
+		{name: "empty token", tokens: []APIToken{{Name: "a", Role: RoleViewer}}, wantErr: "令牌不能为空"},
+		{name: "unknown role", tokens: []APIToken{{Name: "a", Token: "t", Role: "root"}}, wantErr: "未知角色"},
+		{name: "missing role", tokens: []APIToken{{Name: "a", Token: "t"}}, wantErr: "未知角色"},
+		{name: "duplicate", tokens: []APIToken{{Name: "a", Token: "t", Role: RoleViewer}, {Name: "b", Token: "t", Role: RoleAdmin}},
+			wantErr: "令牌与 a 重复"},
+		{name: "duplicate wechat", tokens: []APIToken{{Name: "a", Token: "t", Role: RoleViewer}}, wechat: "t", wantErr: "令牌与 a 重复"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultFlashAgentConfig()
+			config.Auth.Tokens = tt.tokens
+			config.Wechat.VerifyToken = tt.wechat
+			_, err := NewAuthenticator(config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("错误 %v，期望包含 %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthenticateTokenSources(t *testing.T) {
+	config := DefaultFlashAgentConfig()
+	config.Auth.Tokens = testTokens
+	config.Wechat.VerifyToken = "wechat-token"
+	auth, err := NewAuthenticator(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		header   map[string]string
+		query    string
+		wantName string // 为空表示认证失败
+		wantRole Role
+	}{
+		{name: "bearer", header: map[string]string{"Authorization": "Bearer operator-token"}, wantName: "ops", wantRole: RoleOperator},
+		{name: "bearer lowercase scheme", header: map[string]string{"Authorization": "bearer admin-token"}, wantName: "root", wantRole: RoleAdmin},
+		{name: "bearer surrounding spaces", header: map[string]string{"Authorization": "Bearer  viewer-token "}, wantName: "dashboard", wantRole: RoleViewer},
+		{name: "x-agent-token", header: map[string]string{"X-Agent-Token": "viewer-token"}, wantName: "dashboard", wantRole: RoleViewer},
+		{name: "query", query: "token=admin-token", wantName: "root", wantRole: RoleAdmin},
+		{name: "wechat verify token", query: "token=wechat-token", wantName: "wechat", wantRole: RoleAdmin},
+		{name: "authorization wins", header: map[string]string{"Authorization": "Bearer viewer-token", "X-Agent-Token": "admin-token"},
+			query: "token=admin-token", wantName: "dashboard", wantRole: RoleViewer},
+		{name: "header wins over query", header: map[string]string{"X-Agent-Token": "viewer-token"}, query: "token=admin-token",
+			wantName: "dashboard", wantRole: RoleViewer},
+		{name: "basic scheme falls through", header: map[string]string{"Authorization": "Basic YWRtaW4=", "X-Agent-Token": "operator-token"},
+			wantName: "ops", wantRole: RoleOperator},
+		{name: "no token"},
+		{name: "empty bearer", header: map[string]string{"Authorization": "Bearer "}},
+		{name: "wrong token", header: map[string]string{"Authorization": "Bearer nope"}},
+		{name: "token prefix", header: map[string]string{"X-Agent-Token": "admin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://example.com/ws?"+tt.query, nil)
+			for k, v := range tt.header {
+				req.Header.Set(k, v)
+			}
+			principal, ok := auth.Authenticate(req)
+			if tt.wantName == "" {
+				if ok {
+					t.Errorf("期望认证失败，得到 %+v", principal)
+				}
+				return
+			}
+			if !ok || principal.Name != tt.wantName || principal.Role != tt.wantRole {
+				t.Errorf("认证结果 %+v %v，期望 %s/%s", principal, ok, tt.wantName, tt.wantRole)
+			}
+		})
+	}
+}
+
+// 没有配置令牌时所有请求都以admin身份通过
+func TestAuthenticateWithoutTokens(t *testing.T) {
+	auth, err := NewAuthenticator(DefaultFlashAgentConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	principal, ok := auth.Authenticate(httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if !ok || principal.Role != RoleAdmin {
+		t.Errorf("认证结果 %+v %v，期望admin", principal, ok)
+	}
+}
+
+// commandRoles 每个命令要求的最低角色；新增命令时必须在这里声明权限
+var commandRoles = map[string]Role{
+	"hello":                RoleViewer,
+	"config/get":           RoleViewer,
+	"config/history":       RoleViewer,
+	"config/diff":          RoleViewer,
+	"bot/status":           RoleViewer,
+	"bot/history":          RoleViewer,
+	"logs/tail":            RoleViewer,
+	"logs/follow":          RoleViewer,
+	"logs/unfollow":        RoleViewer,
+	"config/addMint":       RoleOperator,
+	"config/removeMint":    RoleOperator,
+	"bot/start":            RoleOperator,
+	"bot/stop":             RoleOperator,
+	"bot/restart":          RoleOperator,
+	"bot/toggleFeature":    RoleOperator,
+	"audit/query":          RoleOperator,
+	"config/update":        RoleAdmin,
+	"config/updateSection": RoleAdmin,
+	"config/rollback":      RoleAdmin,
+	"bot/updateRPC":        RoleAdmin,
+}
+
+func TestRoleMatrix(t *testing.T) {
+	ws := newTestWebSocketServer(t, testTokens...)
+
+	registered := make(map[string]Role)
+	for name, spec := range ws.commands {
+		registered[strings.TrimSuffix(name, "/")] = spec.role
+	}
+	if !reflect.DeepEqual(registered, commandRoles) {
+		t.Fatalf("注册的命令权限 %v\n与期望的权限矩阵不一致 %v", registered, commandRoles)
+	}
+
+	for _, token := range testTokens {
+		principal := &Principal{Name: token.Name, Role: token.Role}
+		allowed := make(map[string]bool)
+		for _, name := range ws.commandNames(token.Role) {
+			allowed[name] = true
+		}
+
+		for command, required := range commandRoles {
+			want := token.Role.Allows(required)
+			if allowed[command] != want {
+				t.Errorf("%s 的hello命令列表中 %s: %v，期望 %v", token.Role, command, allowed[command], want)
+			}
+			if want {
+				continue
+			}
+
+			// 权限不足的命令在执行处理程序之前被拒绝
+			typ, action := command, ""
+			if i := strings.Index(command, "/"); i >= 0 {
+				typ, action = command[:i], command[i+1:]
+			}
+			response := ws.execute(&commandRequest{cmd: &Command{Type: typ, Action: action}, principal: principal, remote: "test"})
+			if response.OK || response.Error == nil || response.Error.Code != ErrCodeForbidden {
+				t.Errorf("%s 执行 %s 的响应 %+v，期望 forbidden", token.Role, command, response.Error)
+			}
+		}
+	}
+}
+
+func TestRoleAllows(t *testing.T) {
+	roles := []Role{RoleViewer, RoleOperator, RoleAdmin}
+	for i, role := range roles {
+		for j, required := range roles {
+			if got := role.Allows(required); got != (i >= j) {
+				t.Errorf("%s.Allows(%s) = %v", role, required, got)
+			}
+		}
+	}
+	if Role("root").Allows(RoleViewer) {
+		t.Error("未知角色不应拥有任何权限")
+	}
+}
+
+// HTTP接口使用同一套令牌和权限：缺少令牌返回401，权限不足返回403
+func TestRESTAuthentication(t *testing.T) {
+	ws := newTestWebSocketServer(t, testTokens...)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		want   int
+	}{
+		{name: "no token", method: http.MethodGet, path: "/api/status", want: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodGet, path: "/api/status", token: "nope", want: http.StatusUnauthorized},
+		{name: "viewer reads", method: http.MethodGet, path: "/api/status", token: "viewer-token", want: http.StatusOK},
+		{name: "viewer cannot stop", method: http.MethodPost, path: "/api/bot/stop", token: "viewer-token", want: http.StatusForbidden},
+		{name: "viewer cannot add mint", method: http.MethodPost, path: "/api/mints", token: "viewer-token",
+			body: `{"mint":"` + testMintB + `"}`, want: http.StatusForbidden},
+		{name: "operator cannot replace config", method: http.MethodPut, path: "/api/config", token: "operator-token",
+			body: `{}`, want: http.StatusForbidden},
+		{name: "operator reads config", method: http.MethodGet, path: "/api/config", token: "operator-token", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com"+tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			ws.handleAPI(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("状态码 %d，期望 %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Solana地址使用的Base58字母表
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Index = func() [256]int {
+	var index [256]int
+	for i := range index {
+		index[i] = -1
+	}
+	for i := 0; i < len(base58Alphabet); i++ {
+		index[base58Alphabet[i]] = i
+	}
+	return index
+}()
+
+// base58Decode 解码Base58字符串
+func base58Decode(s string) ([]byte, error) {
+	if s == "" {
+		return nil, errors.New("空字符串")
+	}
+
+	result := new(big.Int)
+	radix := big.NewInt(58)
+	for i := 0; i < len(s); i++ {
+		digit := base58Index[s[i]]
+		if digit < 0 {
+			return nil, errors.New("包含非法Base58字符: " + string(s[i]))
+		}
+		result.Mul(result, radix)
+		result.Add(result, big.NewInt(int64(digit)))
+	}
+
+	// 前导的 '1' 对应前导零字节
+	leadingZeros := 0
+	for leadingZeros < len(s) && s[leadingZeros] == '1' {
+		leadingZeros++
+	}
+
+	return append(make([]byte, leadingZeros), result.Bytes()...), nil
+}
+
+// isValidPublicKey 判断字符串是否为合法的Solana公钥（Base58编码的32字节）
+func isValidPublicKey(s string) bool {
+	decoded, err := base58Decode(s)
+	return err == nil && len(decoded) == 32
+}
+
+// base58Encode 将字节编码为Base58字符串
+func base58Encode(data []byte) string {
+	// 前导零字节对应前导的 '1'
+	leadingZeros := 0
+	for leadingZeros < len(data) && data[leadingZeros] == 0 {
+		leadingZeros++
+	}
+
+	value := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var encoded []byte
+	for value.Sign() > 0 {
+		value.DivMod(value, radix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	for i := 0; i < leadingZeros; i++ {
+		encoded = append(encoded, '1')
+	}
+
+	// 反转为高位在前
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
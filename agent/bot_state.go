@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// BotState 表示MEV Bot的生命周期状态
+type BotState string
+
+const (
+	BotStateStopped        BotState = "stopped"         // 未运行（手动停止、意外退出等待重启或启动失败）
+	BotStateStarting       BotState = "starting"        // 正在启动
+	BotStateRunning        BotState = "running"         // 运行中
+	BotStateStopping       BotState = "stopping"        // 正在停止
+	BotStateCrashLooping   BotState = "crash_looping"   // 崩溃循环，已停止自动重启，需要手动启动
+	BotStateUpdatingConfig BotState = "updating_config" // 正在保存配置并重启
+)
+
+// botTransitions 允许的状态转换
+var botTransitions = map[BotState][]BotState{
+	BotStateStopped:        {BotStateStarting, BotStateUpdatingConfig, BotStateCrashLooping},
+	BotStateStarting:       {BotStateRunning, BotStateStopped},
+	BotStateRunning:        {BotStateStopping, BotStateStopped, BotStateUpdatingConfig},
+	BotStateStopping:       {BotStateStopped, BotStateStarting},
+	BotStateCrashLooping:   {BotStateStarting, BotStateStopping, BotStateUpdatingConfig},
+	BotStateUpdatingConfig: {BotStateStopping, BotStateStarting, BotStateRunning, BotStateStopped},
+}
+
+// canTransition 判断是否允许从from转换到to
+func canTransition(from, to BotState) bool {
+	if from == to {
+		return true
+	}
+	for _, s := range botTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// LastExit 描述MEV Bot最近一次退出
+type LastExit struct {
+	Time      time.Time `json:"time"`
+	ExitCode  int       `json:"exit_code"`
+	Signal    string    `json:"signal,omitempty"`
+	StopCause StopCause `json:"stop_cause"`
+}
+
+// BotStatus 是 bot/status 命令返回的MEV Bot状态
+type BotStatus struct {
+	State           BotState            `json:"state"`
+	Since           time.Time           `json:"since"` // 进入当前状态的时间
+	PID             int                 `json:"pid,omitempty"`
+	Uptime          float64             `json:"uptime_seconds"` // 当前进程已运行的秒数
+	RestartCount    int                 `json:"restart_count"`  // 代理启动以来的自动重启次数
+	ConfigRevision  int64               `json:"config_revision"`
+	ManuallyStopped bool                `json:"manually_stopped"`
+	LastExit        *LastExit           `json:"last_exit,omitempty"`
+	RestartPolicy   RestartPolicyStatus `json:"restart_policy"`
+}
+
+// setState 转换MEV Bot的生命周期状态并通知客户端
+// 非预期的转换只记录警告，状态仍以实际进程为准
+func (a *Agent) setState(to BotState) {
+	a.stateMu.Lock()
+	from := a.state
+	if from == to {
+		a.stateMu.Unlock()
+		return
+	}
+	a.state = to
+	a.stateSince = time.Now()
+	a.stateMu.Unlock()
+
+	if !canTransition(from, to) {
+		log.Printf("[警告] MEV Bot非预期的状态转换: %s -> %s", from, to)
+	} else {
+		log.Printf("MEV Bot状态: %s -> %s", from, to)
+	}
+	if a.ws != nil {
+		a.ws.BroadcastMessage(fmt.Sprintf("MEV Bot状态: %s", to))
+	}
+}
+
+// State 返回MEV Bot当前的生命周期状态
+func (a *Agent) State() BotState {
+	a.stateMu.RLock()
+	defer a.stateMu.RUnlock()
+	return a.state
+}
+
+// beginOperation 占用一次启停或配置更新操作，op为操作对应的状态，用于错误信息
+// 检查和占用在stateMu下原子完成，并发的命令中只有一个能成功，其余立即返回busy错误而不是排队执行
+// 成功后调用方必须调用 endOperation
+func (a *Agent) beginOperation(op BotState) error {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	if a.operation != "" {
+		return newCommandError(ErrCodeBusy, "MEV Bot当前状态为 %s，请稍后重试", a.operation)
+	}
+	a.operation = op
+	return nil
+}
+
+// endOperation 释放 beginOperation 占用的操作
+func (a *Agent) endOperation() {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	a.operation = ""
+}
+
+// operationInProgress 判断是否有启停或配置更新操作正在进行
+func (a *Agent) operationInProgress() bool {
+	a.stateMu.RLock()
+	defer a.stateMu.RUnlock()
+	return a.operation != ""
+}
+
+// Status 返回MEV Bot的当前状态，不会等待正在进行的启停操作
+func (a *Agent) Status() BotStatus {
+	a.stateMu.RLock()
+	status := BotStatus{
+		State: a.state,
+		Since: a.stateSince,
+	}
+	a.stateMu.RUnlock()
+
+	status.PID = a.proc.PID()
+	if start := a.proc.StartTime(); !start.IsZero() {
+		status.Uptime = time.Since(start).Seconds()
+	}
+	status.RestartPolicy = a.restartPolicy.Status()
+	status.RestartCount = status.RestartPolicy.TotalRestarts
+	status.ConfigRevision = a.store.Revision()
+	status.ManuallyStopped = a.isManuallyStopped()
+
+	if record, ok := a.history.Last(); ok {
+		status.LastExit = &LastExit{
+			Time:      record.StopTime,
+			ExitCode:  record.ExitCode,
+			Signal:    record.Signal,
+			StopCause: record.StopCause,
+		}
+	}
+	return status
+}
+
+// setManuallyStopped 设置是否为主动停止，主动停止时不自动重启
+func (a *Agent) setManuallyStopped(stopped bool) {
+	a.stateMu.Lock()
+	defer a.stateMu.Unlock()
+	a.manuallyStopped = stopped
+}
+
+// isManuallyStopped 判断MEV Bot是否为主动停止
+func (a *Agent) isManuallyStopped() bool {
+	a.stateMu.RLock()
+	defer a.stateMu.RUnlock()
+	return a.manuallyStopped
+}
+
+// syncState 操作失败后按进程的实际情况恢复状态
+func (a *Agent) syncState() {
+	if a.proc.IsRunning() {
+		a.setState(BotStateRunning)
+	} else {
+		a.setState(BotStateStopped)
+	}
+}
@@ -0,0 +1,298 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pelletier/go-toml"
+)
+
+// ChangeInfo 描述一次配置修改的来源
+type ChangeInfo struct {
+	Author string // 修改者：客户端标识或 "hot-token-tracker"
+	Reason string // 修改原因
+}
+
+// ConfigRevision 表示一个已保存的配置版本
+type ConfigRevision struct {
+	Revision int64     `json:"revision"`
+	Time     time.Time `json:"time"`
+	Author   string    `json:"author"`
+	Reason   string    `json:"reason"`
+	Config   *Config   `json:"config,omitempty"`
+}
+
+// FieldChange 表示两个配置版本之间一个字段的差异
+type FieldChange struct {
+	Path string      `json:"path"` // TOML路径，如 jito.tip_config.from
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+// ConfigHistory 将每次保存的配置作为编号版本存放在目录中
+// 版本文件中的敏感字段已脱敏，回滚时由ConfigStore从当前配置恢复
+type ConfigHistory struct {
+	mu     sync.Mutex
+	dir    string
+	limit  int
+	latest int64
+}
+
+// NewConfigHistory 创建配置历史，limit为保留的版本数
+func NewConfigHistory(dir string, limit int) (*ConfigHistory, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建配置历史目录失败: %w", err)
+	}
+	if limit <= 0 {
+		limit = 200
+	}
+
+	h := &ConfigHistory{
+		dir:   dir,
+		limit: limit,
+	}
+
+	revisions, err := h.revisionNumbers()
+	if err != nil {
+		return nil, err
+	}
+	if len(revisions) > 0 {
+		h.latest = revisions[len(revisions)-1]
+	}
+	if err := h.redactExisting(revisions); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// redactExisting 将旧版本代理写入的明文版本文件改写为脱敏内容
+func (h *ConfigHistory) redactExisting(revisions []int64) error {
+	for _, revision := range revisions {
+		rev, err := h.Get(revision)
+		if err != nil || rev.Config == nil {
+			continue
+		}
+		redacted := RedactConfig(rev.Config)
+		if reflect.DeepEqual(redacted, rev.Config) {
+			continue
+		}
+		rev.Config = redacted
+		if err := h.write(rev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Latest 返回最新的版本号，没有任何版本时为0
+func (h *ConfigHistory) Latest() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.latest
+}
+
+// Record 保存一个新版本并返回版本号，敏感字段在写入前被替换为占位符
+func (h *ConfigHistory) Record(config *Config, info ChangeInfo) (int64, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	revision := &ConfigRevision{
+		Revision: h.latest + 1,
+		Time:     time.Now(),
+		Author:   info.Author,
+		Reason:   info.Reason,
+		Config:   RedactConfig(config),
+	}
+	if err := h.write(revision); err != nil {
+		return 0, err
+	}
+
+	h.latest = revision.Revision
+	h.prune()
+	return revision.Revision, nil
+}
+
+// write 写入版本文件
+func (h *ConfigHistory) write(revision *ConfigRevision) error {
+	data, err := json.MarshalIndent(revision, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(h.revisionPath(revision.Revision), data, 0600); err != nil {
+		return fmt.Errorf("保存配置版本失败: %w", err)
+	}
+	return nil
+}
+
+// Get 读取指定版本
+func (h *ConfigHistory) Get(revision int64) (*ConfigRevision, error) {
+	data, err := os.ReadFile(h.revisionPath(revision))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %d", ErrRevisionNotFound, revision)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rev ConfigRevision
+	if err := json.Unmarshal(data, &rev); err != nil {
+		return nil, fmt.Errorf("解析配置版本 %d 失败: %w", revision, err)
+	}
+	return &rev, nil
+}
+
+// List 返回最近limit个版本的元信息（不含配置内容），最新的在前
+func (h *ConfigHistory) List(limit int) ([]ConfigRevision, error) {
+	revisions, err := h.revisionNumbers()
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > len(revisions) {
+		limit = len(revisions)
+	}
+
+	result := make([]ConfigRevision, 0, limit)
+	for i := len(revisions) - 1; i >= len(revisions)-limit; i-- {
+		rev, err := h.Get(revisions[i])
+		if err != nil {
+			return nil, err
+		}
+		rev.Config = nil
+		result = append(result, *rev)
+	}
+	return result, nil
+}
+
+// prune 删除超出保留数量的旧版本
+func (h *ConfigHistory) prune() {
+	revisions, err := h.revisionNumbers()
+	if err != nil || len(revisions) <= h.limit {
+		return
+	}
+	for _, rev := range revisions[:len(revisions)-h.limit] {
+		os.Remove(h.revisionPath(rev))
+	}
+}
+
+// revisionNumbers 返回目录中所有版本号，按从小到大排列
+func (h *ConfigHistory) revisionNumbers() ([]int64, error) {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置历史目录失败: %w", err)
+	}
+
+	var revisions []int64
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "rev-") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		rev, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(name, "rev-"), ".json"), 10, 64)
+		if err != nil {
+			continue
+		}
+		revisions = append(revisions, rev)
+	}
+
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i] < revisions[j] })
+	return revisions, nil
+}
+
+// revisionPath 返回版本文件路径
+func (h *ConfigHistory) revisionPath(revision int64) string {
+	return filepath.Join(h.dir, fmt.Sprintf("rev-%06d.json", revision))
+}
+
+// DiffConfigs 比较两个配置，返回按路径排序的字段级差异
+func DiffConfigs(from, to *Config) ([]FieldChange, error) {
+	oldFields, err := flattenConfig(from)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flattenConfig(to)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]struct{})
+	for p := range oldFields {
+		paths[p] = struct{}{}
+	}
+	for p := range newFields {
+		paths[p] = struct{}{}
+	}
+
+	changes := []FieldChange{}
+	for p := range paths {
+		oldValue, newValue := oldFields[p], newFields[p]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, FieldChange{Path: p, Old: oldValue, New: newValue})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// flattenConfig 将配置展开为 TOML路径 -> 值 的映射
+// 表数组按下标展开（如 routing.mint_config_list[0].mint），标量数组作为整体比较
+func flattenConfig(config *Config) (map[string]interface{}, error) {
+	data, err := toml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	flattenValue("", tree.ToMap(), fields)
+	return fields, nil
+}
+
+// flattenValue 递归展开表和表数组
+func flattenValue(prefix string, value interface{}, fields map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			flattenValue(path, child, fields)
+		}
+	case []interface{}:
+		if !isTableArray(v) {
+			fields[prefix] = v
+			return
+		}
+		for i, child := range v {
+			flattenValue(fmt.Sprintf("%s[%d]", prefix, i), child, fields)
+		}
+	default:
+		fields[prefix] = v
+	}
+}
+
+// isTableArray 判断数组是否为表数组
+func isTableArray(values []interface{}) bool {
+	if len(values) == 0 {
+		return false
+	}
+	for _, v := range values {
+		if _, ok := v.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	base := loadTemplateConfig(t)
+
+	tests := []struct {
+		name string
+		edit func(*Config)
+		want []string
+	}{
+		{name: "unchanged", edit: func(c *Config) {}},
+		{name: "nil and empty array", edit: func(c *Config) { c.Jito.IPAddresses = nil }},
+		{name: "scalar", edit: func(c *Config) { c.Bot.ComputeUnitLimit++ }, want: []string{"bot.compute_unit_limit"}},
+		{name: "nested and array", edit: func(c *Config) {
+			c.Jito.TipConfig.From++
+			c.Jito.BlockEngineURLs = append(c.Jito.BlockEngineURLs, "https://x")
+		}, want: []string{"jito.block_engine_urls", "jito.tip_config.from"}},
+		{name: "table array element", edit: func(c *Config) {
+			c.Routing.MintConfigList[0].ProcessDelay++
+		}, want: []string{"routing.mint_config_list[0].process_delay"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated := base.Copy()
+			tt.edit(updated)
+			changes, err := DiffConfigs(base, updated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var paths []string
+			for _, change := range changes {
+				paths = append(paths, change.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.want) {
+				t.Errorf("差异路径 %v，期望 %v", paths, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigHistoryRecordListAndPrune(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "history")
+	history, err := NewConfigHistory(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := secretTestConfig(t)
+	for i := 1; i <= 5; i++ {
+		config.Bot.ComputeUnitLimit = 100000 + i
+		revision, err := history.Record(config, ChangeInfo{Author: "test", Reason: "修改"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revision != int64(i) {
+			t.Fatalf("版本号 %d，期望 %d", revision, i)
+		}
+	}
+
+	list, err := history.List(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var revisions []int64
+	for _, rev := range list {
+		revisions = append(revisions, rev.Revision)
+		if rev.Config != nil || rev.Author != "test" {
+			t.Errorf("版本 %d 元信息不符合预期: %+v", rev.Revision, rev)
+		}
+	}
+	if !reflect.DeepEqual(revisions, []int64{5, 4, 3}) {
+		t.Errorf("版本列表 %v，期望 [5 4 3]", revisions)
+	}
+	if list, _ := history.List(1); len(list) != 1 || list[0].Revision != 5 {
+		t.Errorf("limit=1 返回 %+v", list)
+	}
+
+	if _, err := history.Get(1); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("被清理的版本返回 %v，期望 ErrRevisionNotFound", err)
+	}
+	rev, err := history.Get(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rev.Config.Bot.ComputeUnitLimit != 100004 {
+		t.Errorf("版本4内容 %d", rev.Config.Bot.ComputeUnitLimit)
+	}
+
+	// 重新打开后从最新版本继续编号
+	reopened, err := NewConfigHistory(dir, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Latest() != 5 {
+		t.Errorf("重新打开后最新版本 %d，期望 5", reopened.Latest())
+	}
+}
+
+// 版本文件中不出现任何敏感值，目录和文件只有所有者可访问
+func TestConfigHistoryStoresRedactedSnapshots(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "history")
+	history, err := NewConfigHistory(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := secretTestConfig(t)
+	if _, err := history.Record(config, ChangeInfo{Author: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if config.Wallet.Private != "wallet-private" {
+		t.Error("Record修改了传入的配置")
+	}
+
+	data, err := os.ReadFile(history.revisionPath(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoSecrets(t, string(data))
+
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("配置历史目录权限 %v %v，期望 700", info.Mode().Perm(), err)
+	}
+	if info, err := os.Stat(history.revisionPath(1)); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("版本文件权限 %v %v，期望 600", info.Mode().Perm(), err)
+	}
+}
+
+// 旧版本写入的明文版本文件在打开时被改写
+func TestConfigHistoryRedactsExistingPlaintext(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "history")
+	history, err := NewConfigHistory(dir, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := history.write(&ConfigRevision{Revision: 1, Author: "agent", Config: secretTestConfig(t)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewConfigHistory(dir, 10); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(history.revisionPath(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoSecrets(t, string(data))
+	if !strings.Contains(string(data), `"author": "agent"`) {
+		t.Error("改写后丢失了版本元信息")
+	}
+}
+
+// 回滚到脱敏的版本时，敏感字段保持当前配置中的值
+func TestRollbackConfigKeepsLiveSecrets(t *testing.T) {
+	agent := newTestAgent(t)
+	auth, err := NewAuthenticator(agent.agentConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agent.ws = NewWebSocketServer(agent.agentConfig.Server, auth, agent)
+	agent.setManuallyStopped(true)
+
+	first := agent.store.Revision()
+	original := agent.store.Current()
+
+	updated := agent.store.Current()
+	updated.Bot.ComputeUnitLimit = 500000
+	updated.RPC.URL = "https://rotated.example.com/?api-key=new"
+	updated.Wallet.Private = "new-private"
+	updated.Spam.SendingRPCURLs = []string{"https://send1.example.com", "https://send2.example.com"}
+	if err := agent.UpdateConfig(updated, ChangeInfo{Author: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := agent.RollbackConfig(first, ChangeInfo{Author: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	current := agent.store.Current()
+	if current.Bot.ComputeUnitLimit != original.Bot.ComputeUnitLimit {
+		t.Errorf("回滚后 compute_unit_limit %d，期望 %d", current.Bot.ComputeUnitLimit, original.Bot.ComputeUnitLimit)
+	}
+	if current.RPC.URL != "https://rotated.example.com/?api-key=new" || current.Wallet.Private != "new-private" {
+		t.Errorf("回滚后敏感字段 %q %q，期望保持当前值", current.RPC.URL, current.Wallet.Private)
+	}
+	if !reflect.DeepEqual(current.Spam.SendingRPCURLs, []string{"https://send1.example.com", "https://send2.example.com"}) {
+		t.Errorf("回滚后 sending_rpc_urls %v", current.Spam.SendingRPCURLs)
+	}
+	data, err := os.ReadFile(agent.store.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), RedactedPlaceholder) {
+		t.Error("占位符被写入了配置文件")
+	}
+
+	// 回滚版本记录为新版本
+	if agent.store.Revision() != first+2 {
+		t.Errorf("当前版本 %d，期望 %d", agent.store.Revision(), first+2)
+	}
+	if err := agent.RollbackConfig(999, ChangeInfo{Author: "test"}); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("回滚不存在的版本返回 %v", err)
+	}
+}
+
+// assertNoSecrets 检查文本中不包含secretTestConfig中的敏感值
+func assertNoSecrets(t *testing.T, text string) {
+	t.Helper()
+	for _, secret := range []string{"api-key=k1", "k2", "k3", "k4", "jito-uuid", "wallet-private"} {
+		if strings.Contains(text, secret) {
+			t.Errorf("版本文件中包含敏感值 %q", secret)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ConfigStore 持有MEV Bot的TOML配置文件，是配置文件的唯一写入者
+// WebSocket处理程序和热门代币跟踪器都通过它读取和保存配置
+type ConfigStore struct {
+	mu       sync.RWMutex
+	path     string
+	current  *Config
+	history  *ConfigHistory
+	revision int64         // 当前配置对应的版本号
+	document *tomlDocument // 配置文件的原始文本，保存时只改写变化的键
+}
+
+// NewConfigStore 从指定路径加载配置并创建配置存储
+// 配置历史为空时，将当前配置记录为第一个版本
+func NewConfigStore(path string, history *ConfigHistory) (*ConfigStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &ConfigStore{
+		path:     path,
+		current:  config,
+		history:  history,
+		revision: history.Latest(),
+		document: parseTOMLDocument(data),
+	}
+
+	// 首次启动或配置文件在代理之外被修改时，记录为新版本
+	// 按字段比较，nil和空数组写入TOML后相同，不能视为修改；版本中的敏感字段已脱敏，
+	// 因此与脱敏后的当前配置比较，只修改敏感字段的值不会产生新版本
+	info := ChangeInfo{Author: "agent", Reason: "初始配置"}
+	if store.revision > 0 {
+		latest, err := history.Get(store.revision)
+		if err == nil {
+			if changes, err := DiffConfigs(latest.Config, RedactConfig(config)); err == nil && len(changes) == 0 {
+				return store, nil
+			}
+		}
+		info = ChangeInfo{Author: "external", Reason: "配置文件在代理之外被修改"}
+	}
+
+	revision, err := history.Record(config, info)
+	if err != nil {
+		return nil, err
+	}
+	store.revision = revision
+
+	return store, nil
+}
+
+// Path 返回配置文件路径
+func (s *ConfigStore) Path() string {
+	return s.path
+}
+
+// Current 返回当前配置的副本，调用方可以自由修改
+func (s *ConfigStore) Current() *Config {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.Copy()
+}
+
+// Revision 返回当前配置的版本号
+func (s *ConfigStore) Revision() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.revision
+}
+
+// History 返回配置版本历史
+func (s *ConfigStore) History() *ConfigHistory {
+	return s.history
+}
+
+// Save 校验并原子地写入新配置，将旧版本保留为 .bak 备份，并记录为新的配置版本
+// 只改写发生变化的键，注释、键的顺序以及Config中未建模的键都保持原样
+// 敏感字段为占位符时保留原有的值；校验失败时返回ValidationErrors，配置文件保持不变
+func (s *ConfigStore) Save(config *Config, info ChangeInfo) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	config = config.Copy()
+	if err := RestoreSecrets(config, s.current); err != nil {
+		return 0, err
+	}
+	if err := config.Validate(); err != nil {
+		var errs, previous ValidationErrors
+		if errors.As(err, &errs) && errors.As(s.current.Validate(), &previous) {
+			return 0, markPreexisting(errs, previous)
+		}
+		return 0, err
+	}
+
+	// 在副本上修改，写入失败时保持原文档不变
+	document := s.document.clone()
+	data, err := document.Apply(s.current, config)
+	if err != nil {
+		return 0, fmt.Errorf("序列化配置失败: %w", err)
+	}
+
+	// 确认改写后的文本与目标配置一致；完整重写会丢失注释和未建模的键，因此不一致时拒绝保存
+	parsed, err := ParseConfig(data)
+	if err != nil {
+		return 0, fmt.Errorf("改写后的配置文件无法解析: %w", err)
+	}
+	changes, err := DiffConfigs(parsed, config)
+	if err != nil {
+		return 0, fmt.Errorf("校验改写后的配置文件失败: %w", err)
+	}
+	if len(changes) > 0 {
+		return 0, fmt.Errorf("改写后的配置文件有%d个字段与目标配置不一致（首个: %s），未保存", len(changes), changes[0].Path)
+	}
+
+	if err := backupFile(s.path); err != nil {
+		return 0, fmt.Errorf("备份配置文件失败: %w", err)
+	}
+	if err := writeFileAtomic(s.path, data, 0644); err != nil {
+		return 0, fmt.Errorf("写入配置文件失败: %w", err)
+	}
+	s.current = config.Copy()
+	s.document = document
+
+	// 配置文件已写入，版本记录失败只记日志，不影响本次更新
+	revision, err := s.history.Record(s.current, info)
+	if err != nil {
+		log.Printf("记录配置版本失败: %v", err)
+		return s.revision, nil
+	}
+	s.revision = revision
+	return revision, nil
+}
+
+// backupFile 将现有文件复制为 .bak 备份，文件不存在时不做任何操作
+func backupFile(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path+".bak", data, info.Mode().Perm())
+}
+
+// writeFileAtomic 先写入同目录下的临时文件并fsync，再重命名替换目标文件
+// 写入过程中断时目标文件保持原样，不会出现半截配置
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	// 任何一步失败都清理临时文件
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	// 同步目录项，确保重命名在断电后仍然生效；部分平台不支持对目录fsync，忽略错误
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package agent
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Solana单笔交易允许的最大计算单元
+const maxComputeUnitLimit = 1400000
+
+// smb-onchain 支持的价格/小费生成策略
+var knownStrategies = map[string]bool{
+	"Random":      true,
+	"Linear":      true,
+	"Exponential": true,
+}
+
+// FieldError 表示一个配置字段的校验错误
+type FieldError struct {
+	Field   string `json:"field"`   // TOML路径，如 jito.tip_config.from
+	Message string `json:"message"` // 错误说明
+}
+
+// ValidationErrors 汇总配置的全部校验错误
+type ValidationErrors []FieldError
+
+// Error 实现error接口
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, fe := range e {
+		messages = append(messages, fe.Field+": "+fe.Message)
+	}
+	return "配置校验失败: " + strings.Join(messages, "; ")
+}
+
+// validator 收集校验错误
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// url 校验 http(s)/ws(s) 地址
+func (v *validator) url(field, value string) {
+	if value == "" {
+		v.add(field, "不能为空")
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		v.add(field, "不是合法的URL")
+		return
+	}
+	switch u.Scheme {
+	case "http", "https", "ws", "wss":
+	default:
+		v.add(field, "不支持的协议 %q", u.Scheme)
+	}
+}
+
+// pubkey 校验Solana公钥
+func (v *validator) pubkey(field, value string) {
+	if !isValidPublicKey(value) {
+		v.add(field, "不是合法的Base58公钥: %q", value)
+	}
+}
+
+// pubkeys 校验公钥列表
+func (v *validator) pubkeys(field string, values []string) {
+	for i, value := range values {
+		v.pubkey(fmt.Sprintf("%s[%d]", field, i), value)
+	}
+}
+
+// rangeConfig 校验 strategy/from/to/count 形式的取值区间
+func (v *validator) rangeConfig(field, strategy string, from, to, count int) {
+	if !knownStrategies[strategy] {
+		v.add(field+".strategy", "未知的策略 %q", strategy)
+	}
+	if from < 0 {
+		v.add(field+".from", "不能为负数")
+	}
+	if from > to {
+		v.add(field+".from", "不能大于to (%d > %d)", from, to)
+	}
+	if count <= 0 {
+		v.add(field+".count", "必须大于0")
+	}
+}
+
+// Validate 对配置做语义校验，一次返回全部字段错误
+// 没有错误时返回nil，否则返回ValidationErrors
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.url("rpc.url", c.RPC.URL)
+
+	// 未启用的功能不校验其下的字段，模板中的占位值不影响保存
+	if c.Spam.Enabled {
+		if len(c.Spam.SendingRPCURLs) == 0 {
+			v.add("spam.sending_rpc_urls", "启用spam时至少需要一个发送节点")
+		}
+		for i, u := range c.Spam.SendingRPCURLs {
+			v.url(fmt.Sprintf("spam.sending_rpc_urls[%d]", i), u)
+		}
+		v.rangeConfig("spam.compute_unit_price", c.Spam.ComputeUnitPrice.Strategy,
+			c.Spam.ComputeUnitPrice.From, c.Spam.ComputeUnitPrice.To, c.Spam.ComputeUnitPrice.Count)
+	}
+	if c.Spam.MaxRetries < 0 {
+		v.add("spam.max_retries", "不能为负数")
+	}
+
+	if c.Jito.Enabled {
+		if len(c.Jito.BlockEngineURLs) == 0 {
+			v.add("jito.block_engine_urls", "启用jito时至少需要一个Block Engine地址")
+		}
+		for i, u := range c.Jito.BlockEngineURLs {
+			v.url(fmt.Sprintf("jito.block_engine_urls[%d]", i), u)
+		}
+		v.rangeConfig("jito.tip_config", c.Jito.TipConfig.Strategy,
+			c.Jito.TipConfig.From, c.Jito.TipConfig.To, c.Jito.TipConfig.Count)
+	}
+
+	if c.Bot.ComputeUnitLimit <= 0 {
+		v.add("bot.compute_unit_limit", "必须大于0")
+	} else if c.Bot.ComputeUnitLimit > maxComputeUnitLimit {
+		v.add("bot.compute_unit_limit", "不能超过%d", maxComputeUnitLimit)
+	}
+
+	seen := make(map[string]int)
+	for i, mint := range c.Routing.MintConfigList {
+		field := fmt.Sprintf("routing.mint_config_list[%d]", i)
+		v.pubkey(field+".mint", mint.Mint)
+		if prev, ok := seen[mint.Mint]; ok {
+			v.add(field+".mint", "与 routing.mint_config_list[%d] 重复", prev)
+		} else {
+			seen[mint.Mint] = i
+		}
+
+		v.pubkeys(field+".pump_pool_list", mint.PumpPoolList)
+		v.pubkeys(field+".raydium_pool_list", mint.RaydiumPoolList)
+		v.pubkeys(field+".raydium_cp_pool_list", mint.RaydiumCPPoolList)
+		v.pubkeys(field+".meteora_dlmm_pool_list", mint.MeteoraPoolList)
+		v.pubkeys(field+".lookup_table_accounts", mint.LookupTableAccounts)
+
+		poolCount := len(mint.PumpPoolList) + len(mint.RaydiumPoolList) +
+			len(mint.RaydiumCPPoolList) + len(mint.MeteoraPoolList)
+		if poolCount == 0 {
+			v.add(field, "至少需要一个池子")
+		}
+		if mint.ProcessDelay < 0 {
+			v.add(field+".process_delay", "不能为负数")
+		}
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// markPreexisting 标记在修改前的配置中就已存在的错误，说明它们与本次修改无关
+// previous为修改前配置的校验错误
+func markPreexisting(errs, previous ValidationErrors) ValidationErrors {
+	existing := make(map[FieldError]bool, len(previous))
+	for _, fe := range previous {
+		existing[fe] = true
+	}
+	marked := make(ValidationErrors, len(errs))
+	for i, fe := range errs {
+		if existing[fe] {
+			fe.Message += "（修改前的配置中已存在，与本次修改无关，请先修正该字段）"
+		}
+		marked[i] = fe
+	}
+	return marked
+}
@@ -0,0 +1,188 @@
+package agent
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadTemplateConfig 读取仓库中的配置模板
+func loadTemplateConfig(t *testing.T) *Config {
+	t.Helper()
+	data, err := os.ReadFile("../config.template.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config, err := ParseConfig(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config
+}
+
+// validationFields 返回校验错误中的字段列表
+func validationFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("期望ValidationErrors，得到 %T: %v", err, err)
+	}
+	var fields []string
+	for _, fe := range errs {
+		if fe.Message == "" {
+			t.Errorf("%s 没有错误说明", fe.Field)
+		}
+		fields = append(fields, fe.Field)
+	}
+	return fields
+}
+
+func TestTemplateConfigValidates(t *testing.T) {
+	if err := loadTemplateConfig(t).Validate(); err != nil {
+		t.Fatalf("配置模板未通过校验: %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(*Config)
+		want []string
+	}{
+		{
+			name: "valid",
+			edit: func(c *Config) {},
+		},
+		{
+			name: "empty rpc url",
+			edit: func(c *Config) { c.RPC.URL = "" },
+			want: []string{"rpc.url"},
+		},
+		{
+			name: "bad rpc url",
+			edit: func(c *Config) { c.RPC.URL = "ftp://rpc.example.com" },
+			want: []string{"rpc.url"},
+		},
+		{
+			name: "spam enabled without urls",
+			edit: func(c *Config) { c.Spam.Enabled = true },
+			want: []string{"spam.sending_rpc_urls"},
+		},
+		{
+			name: "spam empty sending url",
+			edit: func(c *Config) {
+				c.Spam.Enabled = true
+				c.Spam.SendingRPCURLs = []string{"https://send.example.com", ""}
+			},
+			want: []string{"spam.sending_rpc_urls[1]"},
+		},
+		{
+			name: "from greater than to",
+			edit: func(c *Config) {
+				c.Jito.TipConfig.From = 200000
+			},
+			want: []string{"jito.tip_config.from"},
+		},
+		{
+			name: "unknown strategy",
+			edit: func(c *Config) {
+				c.Spam.Enabled = true
+				c.Spam.SendingRPCURLs = []string{"https://send.example.com"}
+				c.Spam.ComputeUnitPrice.Strategy = "Fixed"
+			},
+			want: []string{"spam.compute_unit_price.strategy"},
+		},
+		{
+			name: "disabled sections are not range checked",
+			edit: func(c *Config) {
+				c.Spam.ComputeUnitPrice.Strategy = "Fixed"
+				c.Spam.ComputeUnitPrice.From = 10
+				c.Spam.ComputeUnitPrice.To = 1
+				c.Jito.Enabled = false
+				c.Jito.BlockEngineURLs = nil
+				c.Jito.TipConfig.Strategy = ""
+				c.Jito.TipConfig.Count = 0
+			},
+		},
+		{
+			name: "bad base58",
+			edit: func(c *Config) {
+				c.Routing.MintConfigList[0].Mint = "0OIl-not-base58"
+				c.Routing.MintConfigList[0].PumpPoolList[1] = "tooShort"
+			},
+			want: []string{"routing.mint_config_list[0].mint", "routing.mint_config_list[0].pump_pool_list[1]"},
+		},
+		{
+			name: "zero compute unit limit",
+			edit: func(c *Config) { c.Bot.ComputeUnitLimit = 0 },
+			want: []string{"bot.compute_unit_limit"},
+		},
+		{
+			name: "compute unit limit too large",
+			edit: func(c *Config) { c.Bot.ComputeUnitLimit = maxComputeUnitLimit + 1 },
+			want: []string{"bot.compute_unit_limit"},
+		},
+		{
+			name: "duplicate mint and no pools",
+			edit: func(c *Config) {
+				dup := c.Routing.MintConfigList[0]
+				dup.PumpPoolList, dup.RaydiumPoolList, dup.RaydiumCPPoolList, dup.MeteoraPoolList = nil, nil, nil, nil
+				c.Routing.MintConfigList = append(c.Routing.MintConfigList, dup)
+			},
+			want: []string{"routing.mint_config_list[1].mint", "routing.mint_config_list[1]"},
+		},
+		{
+			name: "multiple errors at once",
+			edit: func(c *Config) {
+				c.RPC.URL = ""
+				c.Bot.ComputeUnitLimit = 0
+				c.Jito.TipConfig.Strategy = "Unknown"
+				c.Jito.TipConfig.From = -1
+				c.Jito.TipConfig.To = -2
+				c.Jito.TipConfig.Count = 0
+				c.Spam.MaxRetries = -1
+				c.Routing.MintConfigList[0].ProcessDelay = -5
+			},
+			want: []string{
+				"rpc.url",
+				"spam.max_retries",
+				"jito.tip_config.strategy", "jito.tip_config.from", "jito.tip_config.from", "jito.tip_config.count",
+				"bot.compute_unit_limit",
+				"routing.mint_config_list[0].process_delay",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := loadTemplateConfig(t)
+			tt.edit(config)
+			if got := validationFields(t, config.Validate()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("错误字段 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkPreexisting(t *testing.T) {
+	previous := ValidationErrors{{Field: "rpc.url", Message: "不能为空"}}
+	errs := ValidationErrors{
+		{Field: "rpc.url", Message: "不能为空"},
+		{Field: "bot.compute_unit_limit", Message: "必须大于0"},
+	}
+	marked := markPreexisting(errs, previous)
+	if !strings.Contains(marked[0].Message, "与本次修改无关") {
+		t.Errorf("已有的错误没有标记: %q", marked[0].Message)
+	}
+	if marked[1].Message != "必须大于0" {
+		t.Errorf("新的错误被标记: %q", marked[1].Message)
+	}
+	if errs[0].Message != "不能为空" {
+		t.Error("markPreexisting修改了输入")
+	}
+}
@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// 健康检查接口的路径
+const (
+	HealthzPath = "/healthz"
+	ReadyzPath  = "/readyz"
+)
+
+// loopStaleAfter 监控循环超过该时间没有运行则判定为不健康，循环每秒运行一次
+const loopStaleAfter = 5 * time.Second
+
+// 健康检查的总体状态
+const (
+	HealthStatusOK   = "ok"
+	HealthStatusFail = "fail"
+)
+
+// HealthCheck 单项检查的结果
+type HealthCheck struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+}
+
+// HealthReport 是 /healthz 和 /readyz 的响应
+type HealthReport struct {
+	Status string                 `json:"status"`
+	Time   time.Time              `json:"time"`
+	Checks map[string]HealthCheck `json:"checks"`
+}
+
+// newHealthReport 根据各项检查结果生成报告，任一项失败则总体失败
+func newHealthReport(checks map[string]HealthCheck) HealthReport {
+	report := HealthReport{Status: HealthStatusOK, Time: time.Now(), Checks: checks}
+	for _, check := range checks {
+		if !check.OK {
+			report.Status = HealthStatusFail
+		}
+	}
+	return report
+}
+
+// markLoopBeat 记录监控循环的一次运行
+func (a *Agent) markLoopBeat(now time.Time) {
+	a.healthMu.Lock()
+	defer a.healthMu.Unlock()
+	a.loopBeat = now
+}
+
+// markHotTokenRefresh 记录一次热门代币刷新的结果
+func (a *Agent) markHotTokenRefresh(err error) {
+	a.healthMu.Lock()
+	defer a.healthMu.Unlock()
+
+	if err != nil {
+		a.hotTokenErr = err.Error()
+		return
+	}
+	a.hotTokenRefresh = time.Now()
+	a.hotTokenErr = ""
+}
+
+// loopCheck 检查监控循环是否仍在运行
+func (a *Agent) loopCheck(now time.Time) HealthCheck {
+	a.healthMu.RLock()
+	beat := a.loopBeat
+	a.healthMu.RUnlock()
+
+	if beat.IsZero() {
+		return HealthCheck{OK: false, Message: "监控循环尚未启动"}
+	}
+	if since := now.Sub(beat); since > loopStaleAfter {
+		return HealthCheck{OK: false, Message: fmt.Sprintf("监控循环已 %.0f 秒没有运行", since.Seconds())}
+	}
+	return HealthCheck{OK: true}
+}
+
+// Liveness 返回代理自身是否存活，用于 /healthz
+func (a *Agent) Liveness() HealthReport {
+	return newHealthReport(map[string]HealthCheck{
+		"monitor_loop": a.loopCheck(time.Now()),
+	})
+}
+
+// Readiness 返回代理是否就绪，用于 /readyz
+// 要求监控循环存活、MEV Bot进程运行中、当前配置通过校验，且热门代币刷新没有过期
+func (a *Agent) Readiness() HealthReport {
+	now := time.Now()
+	checks := map[string]HealthCheck{
+		"monitor_loop": a.loopCheck(now),
+	}
+
+	state := a.State()
+	if a.proc.IsRunning() {
+		checks["bot"] = HealthCheck{OK: true, Message: string(state)}
+	} else {
+		checks["bot"] = HealthCheck{OK: false, Message: fmt.Sprintf("MEV Bot未运行 (%s)", state)}
+	}
+
+	if err := a.store.Current().Validate(); err != nil {
+		checks["config"] = HealthCheck{OK: false, Message: err.Error()}
+	} else {
+		checks["config"] = HealthCheck{OK: true, Message: fmt.Sprintf("版本 %d", a.store.Revision())}
+	}
+
+	checks["hot_tokens"] = a.hotTokenCheck(now)
+	return newHealthReport(checks)
+}
+
+// hotTokenCheck 检查最近一次成功的热门代币刷新是否过期
+// 首次刷新完成前，以代理启动时间计算
+func (a *Agent) hotTokenCheck(now time.Time) HealthCheck {
+	a.healthMu.RLock()
+	refreshed, lastErr, started := a.hotTokenRefresh, a.hotTokenErr, a.startedAt
+	a.healthMu.RUnlock()
+
+	staleAfter := time.Duration(a.agentConfig.Health.HotTokenStaleAfter) * time.Second
+	check := HealthCheck{OK: true}
+	if refreshed.IsZero() {
+		check.Message = "等待首次刷新"
+		if now.Sub(started) > staleAfter {
+			check = HealthCheck{OK: false, Message: fmt.Sprintf("启动后 %v 内没有成功刷新", staleAfter)}
+		}
+	} else {
+		check.Message = "上次成功刷新: " + refreshed.Format(time.RFC3339)
+		if since := now.Sub(refreshed); since > staleAfter {
+			check = HealthCheck{OK: false, Message: fmt.Sprintf("已 %.0f 秒没有成功刷新（阈值 %v）", since.Seconds(), staleAfter)}
+		}
+	}
+	if lastErr != "" {
+		check.Message += "，最近一次失败: " + lastErr
+	}
+	return check
+}
+
+// notifySystemd 在监控循环中向systemd发送就绪通知和看门狗心跳
+func (a *Agent) notifySystemd(now time.Time) {
+	if !a.sdReady {
+		a.sdReady = true
+		if ok, err := sdNotify(sdNotifyReady); err != nil {
+			log.Printf("发送systemd就绪通知失败: %v", err)
+		} else if ok {
+			log.Println("已通知systemd代理就绪")
+		}
+	}
+
+	// 按看门狗超时时间的一半发送心跳
+	if a.sdWatchdog <= 0 || now.Sub(a.sdWatchdogSent) < a.sdWatchdog/2 {
+		return
+	}
+	a.sdWatchdogSent = now
+	if _, err := sdNotify(sdNotifyWatchdog); err != nil {
+		log.Printf("发送systemd看门狗心跳失败: %v", err)
+	}
+}
+
+// handleHealthz 处理存活探针，无需认证
+func (ws *WebSocketServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, ws.agent.Liveness())
+}
+
+// handleReadyz 处理就绪探针，无需认证
+func (ws *WebSocketServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, ws.agent.Readiness())
+}
+
+// writeHealthReport 以JSON写出健康检查报告，失败时返回503
+func writeHealthReport(w http.ResponseWriter, report HealthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != HealthStatusOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("写入健康检查响应失败: %v", err)
+	}
+}
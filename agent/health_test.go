@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHotTokenCheck(t *testing.T) {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	const staleAfter = 30 * time.Minute
+
+	tests := []struct {
+		name      string
+		refreshed time.Duration // 相对启动时间，0表示从未成功刷新
+		lastErr   string
+		now       time.Duration // 相对启动时间
+		wantOK    bool
+		wantMsg   string
+	}{
+		{name: "waiting for first refresh", now: 10 * time.Minute, wantOK: true, wantMsg: "等待首次刷新"},
+		{name: "first refresh at threshold", now: staleAfter, wantOK: true, wantMsg: "等待首次刷新"},
+		{name: "never refreshed", now: staleAfter + time.Second, wantOK: false, wantMsg: "启动后 30m0s 内没有成功刷新"},
+		{name: "never refreshed with error", now: time.Hour, lastErr: "ave: 429", wantOK: false, wantMsg: "最近一次失败: ave: 429"},
+		{name: "fresh", refreshed: time.Hour, now: time.Hour + time.Minute, wantOK: true, wantMsg: "上次成功刷新"},
+		{name: "fresh with later failure", refreshed: time.Hour, lastErr: "timeout", now: time.Hour + time.Minute,
+			wantOK: true, wantMsg: "最近一次失败: timeout"},
+		{name: "at threshold", refreshed: time.Hour, now: time.Hour + staleAfter, wantOK: true},
+		{name: "stale", refreshed: time.Hour, now: time.Hour + staleAfter + time.Second, wantOK: false, wantMsg: "已 1801 秒没有成功刷新"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := newTestAgent(t)
+			agent.agentConfig.Health.HotTokenStaleAfter = int(staleAfter / time.Second)
+			agent.startedAt = started
+			if tt.refreshed > 0 {
+				agent.hotTokenRefresh = started.Add(tt.refreshed)
+			}
+			agent.hotTokenErr = tt.lastErr
+
+			check := agent.hotTokenCheck(started.Add(tt.now))
+			if check.OK != tt.wantOK || !strings.Contains(check.Message, tt.wantMsg) {
+				t.Errorf("检查结果 %+v，期望 ok=%v 且包含 %q", check, tt.wantOK, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestMarkHotTokenRefresh(t *testing.T) {
+	agent := newTestAgent(t)
+	agent.agentConfig.Health.HotTokenStaleAfter = 60
+	agent.startedAt = time.Now()
+
+	agent.markHotTokenRefresh(nil)
+	agent.markHotTokenRefresh(errors.New("solscan: 500"))
+	refreshed := agent.hotTokenRefresh
+
+	// 失败不更新成功时间，只记录错误；之后的成功清除错误
+	if check := agent.hotTokenCheck(refreshed.Add(61 * time.Second)); check.OK || !strings.Contains(check.Message, "solscan: 500") {
+		t.Errorf("刷新失败后过期的检查结果 %+v", check)
+	}
+	agent.markHotTokenRefresh(nil)
+	if check := agent.hotTokenCheck(time.Now()); !check.OK || strings.Contains(check.Message, "失败") {
+		t.Errorf("成功刷新后的检查结果 %+v", check)
+	}
+}
+
+func TestLoopCheck(t *testing.T) {
+	agent := newTestAgent(t)
+	now := time.Now()
+	if check := agent.loopCheck(now); check.OK {
+		t.Error("监控循环未启动时应失败")
+	}
+	agent.markLoopBeat(now)
+	if check := agent.loopCheck(now.Add(loopStaleAfter)); !check.OK {
+		t.Errorf("阈值内的检查结果 %+v", check)
+	}
+	if check := agent.loopCheck(now.Add(loopStaleAfter + time.Second)); check.OK {
+		t.Error("监控循环停止后应失败")
+	}
+}
+
+// readHealth 调用健康检查处理程序，返回状态码和报告
+func readHealth(t *testing.T, handler http.HandlerFunc, path string) (int, HealthReport) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	var report HealthReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("解析健康检查响应失败: %v: %s", err, rec.Body.String())
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Errorf("Cache-Control %q", cc)
+	}
+	return rec.Code, report
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	ws := newTestWebSocketServer(t, testTokens...)
+	agent := ws.agent
+	agent.proc = NewProcessManager("MEV Bot", "sleep", "30")
+	agent.proc.SetLogOutput(nil, nil)
+	agent.startedAt = time.Now()
+	agent.markLoopBeat(time.Now())
+
+	// 健康检查无需令牌；MEV Bot未运行时存活但未就绪
+	if code, report := readHealth(t, ws.handleHealthz, HealthzPath); code != http.StatusOK || report.Status != HealthStatusOK {
+		t.Errorf("/healthz %d %+v", code, report)
+	}
+	code, report := readHealth(t, ws.handleReadyz, ReadyzPath)
+	if code != http.StatusServiceUnavailable || report.Status != HealthStatusFail || report.Checks["bot"].OK {
+		t.Errorf("MEV Bot未运行时 /readyz %d %+v", code, report)
+	}
+
+	if err := agent.proc.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { agent.proc.Stop(StopCauseManual) })
+	agent.markHotTokenRefresh(nil)
+	code, report = readHealth(t, ws.handleReadyz, ReadyzPath)
+	if code != http.StatusOK || report.Status != HealthStatusOK {
+		t.Fatalf("/readyz %d %+v", code, report)
+	}
+	for _, name := range []string{"monitor_loop", "bot", "config", "hot_tokens"} {
+		if !report.Checks[name].OK {
+			t.Errorf("检查项 %s 失败: %+v", name, report.Checks[name])
+		}
+	}
+
+	// 热门代币刷新过期后未就绪
+	agent.healthMu.Lock()
+	agent.hotTokenRefresh = time.Now().Add(-time.Duration(agent.agentConfig.Health.HotTokenStaleAfter+1) * time.Second)
+	agent.healthMu.Unlock()
+	code, report = readHealth(t, ws.handleReadyz, ReadyzPath)
+	if code != http.StatusServiceUnavailable || report.Checks["hot_tokens"].OK {
+		t.Errorf("热门代币过期后 /readyz %d %+v", code, report.Checks["hot_tokens"])
+	}
+
+	// 监控循环停止后存活检查失败
+	agent.markLoopBeat(time.Now().Add(-time.Minute))
+	if code, report := readHealth(t, ws.handleHealthz, HealthzPath); code != http.StatusServiceUnavailable || report.Checks["monitor_loop"].OK {
+		t.Errorf("监控循环停止后 /healthz %d %+v", code, report)
+	}
+}
@@ -0,0 +1,448 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 热门代币数据源类型
+const (
+	HotTokenSourceAve         = "ave"
+	HotTokenSourceDexScreener = "dexscreener"
+	HotTokenSourceBirdeye     = "birdeye"
+	HotTokenSourceFile        = "file"
+)
+
+// 各数据源的默认地址
+const (
+	defaultAveURL     = "https://febweb002.com/v1api/v4/tokens/treasure/list"
+	defaultBirdeyeURL = "https://public-api.birdeye.so/defi/token_trending?sort_by=rank&sort_type=asc&offset=0&limit=20"
+)
+
+// wrappedSOLMint Wrapped SOL的铸币地址
+const wrappedSOLMint = "So11111111111111111111111111111111111111112"
+
+// HotTokenSource 是热门代币的数据源
+type HotTokenSource interface {
+	// Name 返回数据源名称，用于日志和指标
+	Name() string
+	// FetchHotTokens 获取当前的热门代币列表
+	FetchHotTokens() ([]HotToken, error)
+}
+
+// HotTokenSourceConfig 表示一个热门代币数据源的配置
+type HotTokenSourceConfig struct {
+	Type    string `yaml:"type"`    // ave、dexscreener、birdeye 或 file
+	Name    string `yaml:"name"`    // 数据源名称，为空时使用类型
+	URL     string `yaml:"url"`     // 接口地址，为空时使用该类型的默认地址（dexscreener必须指定）
+	APIKey  string `yaml:"api_key"` // 接口密钥：ave为空时使用 ave.token，birdeye作为 X-API-KEY 头
+	Path    string `yaml:"path"`    // file类型的JSON文件路径
+	Chain   string `yaml:"chain"`   // 链名称，默认solana
+	Timeout int    `yaml:"timeout"` // 请求超时，秒
+}
+
+// NewHotTokenSources 根据配置创建热门代币数据源，未配置时只使用Ave
+func NewHotTokenSources(agentConfig *FlashAgentConfig) ([]HotTokenSource, error) {
+	configs := agentConfig.HotTokenConfig.Sources
+	if len(configs) == 0 {
+		configs = []HotTokenSourceConfig{{Type: HotTokenSourceAve}}
+	}
+
+	var sources []HotTokenSource
+	names := make(map[string]bool)
+	for i, config := range configs {
+		if config.Name == "" {
+			config.Name = config.Type
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("hottoken.sources[%d]: 数据源名称 %q 重复", i, config.Name)
+		}
+		names[config.Name] = true
+
+		if config.Chain == "" {
+			config.Chain = "solana"
+		}
+		timeout := 15 * time.Second
+		if config.Timeout > 0 {
+			timeout = time.Duration(config.Timeout) * time.Second
+		}
+		client := &http.Client{Timeout: timeout}
+
+		switch config.Type {
+		case HotTokenSourceAve:
+			if config.APIKey == "" {
+				config.APIKey = agentConfig.Ave.Token
+			}
+			sources = append(sources, NewAveSource(config, client))
+		case HotTokenSourceDexScreener:
+			if config.URL == "" {
+				return nil, fmt.Errorf("hottoken.sources[%d]: dexscreener数据源必须指定url", i)
+			}
+			sources = append(sources, NewDexScreenerSource(config, client))
+		case HotTokenSourceBirdeye:
+			sources = append(sources, NewBirdeyeSource(config, client))
+		case HotTokenSourceFile:
+			if config.Path == "" {
+				return nil, fmt.Errorf("hottoken.sources[%d]: file数据源必须指定path", i)
+			}
+			sources = append(sources, NewFileSource(config))
+		default:
+			return nil, fmt.Errorf("hottoken.sources[%d]: 未知的数据源类型 %q", i, config.Type)
+		}
+	}
+	return sources, nil
+}
+
+// AveSource 从Ave热门列表获取热门代币
+type AveSource struct {
+	config HotTokenSourceConfig
+	client *http.Client
+}
+
+// NewAveSource 创建Ave数据源，client为nil时使用默认客户端
+func NewAveSource(config HotTokenSourceConfig, client *http.Client) *AveSource {
+	if config.URL == "" {
+		config.URL = defaultAveURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &AveSource{config: config, client: client}
+}
+
+// Name 返回数据源名称
+func (s *AveSource) Name() string {
+	return s.config.Name
+}
+
+// FetchHotTokens 获取Ave热门列表
+func (s *AveSource) FetchHotTokens() ([]HotToken, error) {
+	// 获取更多数据然后由跟踪器按15分钟交易量排序
+	url := fmt.Sprintf("%s?chain=%s&pageNO=1&pageSize=50&category=hot&refresh_total=0", s.config.URL, s.config.Chain)
+
+	var apiResp APIResponse
+	err := getJSON(s.client, url, map[string]string{"X-Auth": s.config.APIKey}, &apiResp)
+	if err != nil {
+		return nil, err
+	}
+
+	// 验证响应
+	if apiResp.Status != 1 || len(apiResp.Data.Data) == 0 {
+		return nil, fmt.Errorf("API响应格式无效")
+	}
+	return apiResp.Data.Data, nil
+}
+
+// dexScreenerResponse DexScreener风格的交易对列表
+type dexScreenerResponse struct {
+	Pairs []struct {
+		ChainID     string `json:"chainId"`
+		DexID       string `json:"dexId"`
+		PairAddress string `json:"pairAddress"`
+		BaseToken   struct {
+			Address string `json:"address"`
+			Symbol  string `json:"symbol"`
+		} `json:"baseToken"`
+		QuoteToken struct {
+			Address string `json:"address"`
+			Symbol  string `json:"symbol"`
+		} `json:"quoteToken"`
+		Volume struct {
+			H24 float64 `json:"h24"`
+		} `json:"volume"`
+	} `json:"pairs"`
+}
+
+// DexScreenerSource 从DexScreener风格的交易对JSON获取热门代币
+// 这类接口没有15分钟的买入量，只提供24小时交易量
+type DexScreenerSource struct {
+	config HotTokenSourceConfig
+	client *http.Client
+}
+
+// NewDexScreenerSource 创建DexScreener风格的数据源，client为nil时使用默认客户端
+func NewDexScreenerSource(config HotTokenSourceConfig, client *http.Client) *DexScreenerSource {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &DexScreenerSource{config: config, client: client}
+}
+
+// Name 返回数据源名称
+func (s *DexScreenerSource) Name() string {
+	return s.config.Name
+}
+
+// FetchHotTokens 获取交易对列表，以非SOL一侧的代币作为热门代币
+func (s *DexScreenerSource) FetchHotTokens() ([]HotToken, error) {
+	var resp dexScreenerResponse
+	if err := getJSON(s.client, s.config.URL, nil, &resp); err != nil {
+		return nil, err
+	}
+
+	var tokens []HotToken
+	for _, pair := range resp.Pairs {
+		if !strings.EqualFold(pair.ChainID, s.config.Chain) {
+			continue
+		}
+		token := HotToken{
+			Pair:         pair.PairAddress,
+			Chain:        pair.ChainID,
+			Amm:          pair.DexID,
+			TargetToken:  pair.BaseToken.Address,
+			TokenSymbol:  pair.BaseToken.Symbol,
+			VolumeUSD24h: pair.Volume.H24,
+		}
+		if token.TargetToken == wrappedSOLMint {
+			token.TargetToken = pair.QuoteToken.Address
+			token.TokenSymbol = pair.QuoteToken.Symbol
+		}
+		if token.TargetToken != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// birdeyeResponse Birdeye风格的热门代币响应
+type birdeyeResponse struct {
+	Success bool `json:"success"`
+	Data    struct {
+		Tokens []struct {
+			Address      string  `json:"address"`
+			Symbol       string  `json:"symbol"`
+			Volume24hUSD float64 `json:"volume24hUSD"`
+		} `json:"tokens"`
+	} `json:"data"`
+}
+
+// BirdeyeSource 从Birdeye风格的热门代币接口获取热门代币
+// 这类接口没有15分钟的买入量，只提供24小时交易量
+type BirdeyeSource struct {
+	config HotTokenSourceConfig
+	client *http.Client
+}
+
+// NewBirdeyeSource 创建Birdeye风格的数据源，client为nil时使用默认客户端
+func NewBirdeyeSource(config HotTokenSourceConfig, client *http.Client) *BirdeyeSource {
+	if config.URL == "" {
+		config.URL = defaultBirdeyeURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &BirdeyeSource{config: config, client: client}
+}
+
+// Name 返回数据源名称
+func (s *BirdeyeSource) Name() string {
+	return s.config.Name
+}
+
+// FetchHotTokens 获取热门代币列表
+func (s *BirdeyeSource) FetchHotTokens() ([]HotToken, error) {
+	headers := map[string]string{"X-API-KEY": s.config.APIKey, "x-chain": s.config.Chain}
+	var resp birdeyeResponse
+	if err := getJSON(s.client, s.config.URL, headers, &resp); err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("API响应未成功")
+	}
+
+	tokens := make([]HotToken, 0, len(resp.Data.Tokens))
+	for _, item := range resp.Data.Tokens {
+		if item.Address == "" {
+			continue
+		}
+		tokens = append(tokens, HotToken{
+			Chain:        s.config.Chain,
+			TargetToken:  item.Address,
+			TokenSymbol:  item.Symbol,
+			VolumeUSD24h: item.Volume24hUSD,
+		})
+	}
+	return tokens, nil
+}
+
+// FileSource 从本地JSON文件读取热门代币，文件内容为与Ave响应字段相同的HotToken数组
+// 每次获取都会重新读取文件，便于手动维护固定的代币列表
+type FileSource struct {
+	config HotTokenSourceConfig
+}
+
+// NewFileSource 创建本地文件数据源
+func NewFileSource(config HotTokenSourceConfig) *FileSource {
+	return &FileSource{config: config}
+}
+
+// Name 返回数据源名称
+func (s *FileSource) Name() string {
+	return s.config.Name
+}
+
+// FetchHotTokens 读取文件中的热门代币
+func (s *FileSource) FetchHotTokens() ([]HotToken, error) {
+	data, err := os.ReadFile(s.config.Path)
+	if err != nil {
+		return nil, fmt.Errorf("读取热门代币文件失败: %w", err)
+	}
+
+	var tokens []HotToken
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("解析热门代币文件 %s 失败: %w", s.config.Path, err)
+	}
+	for i, token := range tokens {
+		if token.TargetToken == "" {
+			return nil, fmt.Errorf("热门代币文件 %s 第%d项缺少 target_token", s.config.Path, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+// getJSON 发送GET请求并解析JSON响应
+func getJSON(client *http.Client, url string, headers map[string]string, v interface{}) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	for key, value := range headers {
+		if value != "" {
+			req.Header.Set(key, value)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求API失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API请求失败，状态码: %d", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("解析JSON失败: %w", err)
+	}
+	return nil
+}
+
+// sourceResult 单个数据源的获取结果
+type sourceResult struct {
+	tokens []HotToken
+	err    error
+}
+
+// fetchFromSources 并发地从所有数据源获取热门代币，合并并按铸币地址去重
+// 只要有一个数据源成功就返回合并结果，全部失败时返回错误；observe 在各数据源的goroutine中并发调用
+func fetchFromSources(sources []HotTokenSource, observe func(name string, elapsed time.Duration, err error)) ([]HotToken, error) {
+	results := make([]sourceResult, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source HotTokenSource) {
+			defer wg.Done()
+			start := time.Now()
+			tokens, err := source.FetchHotTokens()
+			if observe != nil {
+				observe(source.Name(), time.Since(start), err)
+			}
+			results[i] = sourceResult{tokens: tokens, err: err}
+		}(i, source)
+	}
+	wg.Wait()
+
+	var lists [][]HotToken
+	var errs []string
+	for i, result := range results {
+		name := sources[i].Name()
+		if result.err != nil {
+			log.Printf("热门代币数据源 %s 获取失败: %v", name, result.err)
+			errs = append(errs, name+": "+result.err.Error())
+			continue
+		}
+		for j := range result.tokens {
+			result.tokens[j].Sources = []string{name}
+		}
+		log.Printf("热门代币数据源 %s 返回 %d 个代币", name, len(result.tokens))
+		lists = append(lists, result.tokens)
+	}
+	if len(lists) == 0 {
+		return nil, errors.New("所有热门代币数据源均获取失败: " + strings.Join(errs, "; "))
+	}
+	return mergeHotTokens(lists...), nil
+}
+
+// mergeHotTokens 按铸币地址合并多个数据源的热门代币，保持首次出现的顺序
+// 交易量取各数据源中的最大值，缺失的描述字段由后续数据源补全
+func mergeHotTokens(lists ...[]HotToken) []HotToken {
+	var merged []HotToken
+	index := make(map[string]int)
+	for _, tokens := range lists {
+		for _, token := range tokens {
+			i, exists := index[token.TargetToken]
+			if !exists {
+				index[token.TargetToken] = len(merged)
+				token.Sources = append([]string{}, token.Sources...)
+				merged = append(merged, token)
+				continue
+			}
+
+			existing := &merged[i]
+			if existing.Pair == "" {
+				existing.Pair = token.Pair
+			}
+			if existing.Chain == "" {
+				existing.Chain = token.Chain
+			}
+			if existing.Amm == "" {
+				existing.Amm = token.Amm
+			}
+			if existing.TokenSymbol == "" {
+				existing.TokenSymbol = token.TokenSymbol
+			}
+			existing.Volume15m = maxFloat(existing.Volume15m, token.Volume15m)
+			existing.VolumeUSD24h = maxFloat(existing.VolumeUSD24h, token.VolumeUSD24h)
+			existing.BuyVolumeUSD15m = maxFloat(existing.BuyVolumeUSD15m, token.BuyVolumeUSD15m)
+			existing.BuyVolumeUSD5m = maxFloat(existing.BuyVolumeUSD5m, token.BuyVolumeUSD5m)
+			for _, source := range token.Sources {
+				if !containsString(existing.Sources, source) {
+					existing.Sources = append(existing.Sources, source)
+				}
+			}
+		}
+	}
+	return merged
+}
+
+// maxFloat 返回较大的值
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// containsString 判断列表中是否包含s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testBonk = "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263"
+
+// newFixtureServer 启动返回固定JSON的测试服务器，并记录最后一次请求
+func newFixtureServer(t *testing.T, status int, body string) (*httptest.Server, *http.Request) {
+	t.Helper()
+	last := &http.Request{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*last = *r.Clone(r.Context())
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, last
+}
+
+const aveFixture = `{
+  "status": 1,
+  "msg": "SUCCESS",
+  "data": {
+    "total": 2,
+    "pageNO": 1,
+    "pageSize": 50,
+    "data": [
+      {"pair": "pairA", "chain": "solana", "amm": "pump_amm", "target_token": "` + testMintA + `",
+       "token0_symbol": "AAA", "volume_u_15m": 1500.5, "volume_u_24h": 90000,
+       "buy_volume_u_15m": 800.25, "buy_volume_u_5m": 300, "extra": "ignored"},
+      {"pair": "pairB", "chain": "solana", "amm": "raydium", "target_token": "` + testBonk + `",
+       "token0_symbol": "BONK", "volume_u_15m": 100, "volume_u_24h": 5000000,
+       "buy_volume_u_15m": 50, "buy_volume_u_5m": 10}
+    ]
+  }
+}`
+
+func TestAveSource(t *testing.T) {
+	srv, last := newFixtureServer(t, http.StatusOK, aveFixture)
+	source := NewAveSource(HotTokenSourceConfig{Name: "ave", URL: srv.URL, APIKey: "secret", Chain: "solana"}, nil)
+
+	tokens, err := source.FetchHotTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HotToken{
+		{Pair: "pairA", Chain: "solana", Amm: "pump_amm", TargetToken: testMintA, TokenSymbol: "AAA",
+			Volume15m: 1500.5, VolumeUSD24h: 90000, BuyVolumeUSD15m: 800.25, BuyVolumeUSD5m: 300},
+		{Pair: "pairB", Chain: "solana", Amm: "raydium", TargetToken: testBonk, TokenSymbol: "BONK",
+			Volume15m: 100, VolumeUSD24h: 5000000, BuyVolumeUSD15m: 50, BuyVolumeUSD5m: 10},
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("解析结果\n%+v\n期望\n%+v", tokens, want)
+	}
+	if got := last.Header.Get("X-Auth"); got != "secret" {
+		t.Errorf("X-Auth %q，期望 secret", got)
+	}
+	if got := last.URL.Query().Get("chain"); got != "solana" {
+		t.Errorf("chain参数 %q，期望 solana", got)
+	}
+}
+
+func TestAveSourceErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "status not 1", status: http.StatusOK, body: `{"status": 0, "data": {"data": [{"target_token": "x"}]}}`},
+		{name: "empty list", status: http.StatusOK, body: `{"status": 1, "data": {"data": []}}`},
+		{name: "http error", status: http.StatusTooManyRequests, body: `{}`},
+		{name: "invalid json", status: http.StatusOK, body: `<html>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := newFixtureServer(t, tt.status, tt.body)
+			source := NewAveSource(HotTokenSourceConfig{Name: "ave", URL: srv.URL, Chain: "solana"}, nil)
+			if tokens, err := source.FetchHotTokens(); err == nil {
+				t.Errorf("期望错误，得到 %+v", tokens)
+			}
+		})
+	}
+}
+
+const dexScreenerFixture = `{
+  "schemaVersion": "1.0.0",
+  "pairs": [
+    {"chainId": "solana", "dexId": "raydium", "pairAddress": "pair1",
+     "baseToken": {"address": "` + testBonk + `", "symbol": "BONK"},
+     "quoteToken": {"address": "` + wrappedSOLMint + `", "symbol": "SOL"},
+     "volume": {"h24": 123456.7, "h6": 1000}},
+    {"chainId": "solana", "dexId": "meteora", "pairAddress": "pair2",
+     "baseToken": {"address": "` + wrappedSOLMint + `", "symbol": "SOL"},
+     "quoteToken": {"address": "` + testMintA + `", "symbol": "AAA"},
+     "volume": {"h24": 999}},
+    {"chainId": "ethereum", "dexId": "uniswap", "pairAddress": "pair3",
+     "baseToken": {"address": "0xabc", "symbol": "ETHX"},
+     "quoteToken": {"address": "0xdef", "symbol": "WETH"},
+     "volume": {"h24": 1e9}},
+    {"chainId": "solana", "dexId": "orca", "pairAddress": "pair4",
+     "baseToken": {"address": "", "symbol": ""},
+     "quoteToken": {"address": "", "symbol": ""},
+     "volume": {"h24": 1}}
+  ]
+}`
+
+func TestDexScreenerSource(t *testing.T) {
+	srv, _ := newFixtureServer(t, http.StatusOK, dexScreenerFixture)
+	source := NewDexScreenerSource(HotTokenSourceConfig{Name: "dex", URL: srv.URL, Chain: "solana"}, nil)
+
+	tokens, err := source.FetchHotTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HotToken{
+		{Pair: "pair1", Chain: "solana", Amm: "raydium", TargetToken: testBonk, TokenSymbol: "BONK", VolumeUSD24h: 123456.7},
+		{Pair: "pair2", Chain: "solana", Amm: "meteora", TargetToken: testMintA, TokenSymbol: "AAA", VolumeUSD24h: 999},
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("解析结果\n%+v\n期望\n%+v", tokens, want)
+	}
+}
+
+const birdeyeFixture = `{
+  "success": true,
+  "data": {
+    "updateUnixTime": 1700000000,
+    "tokens": [
+      {"address": "` + testMintA + `", "symbol": "AAA", "rank": 1, "volume24hUSD": 4321.5},
+      {"address": "", "symbol": "EMPTY", "rank": 2, "volume24hUSD": 1},
+      {"address": "` + testBonk + `", "symbol": "BONK", "rank": 3, "volume24hUSD": 1000000}
+    ]
+  }
+}`
+
+func TestBirdeyeSource(t *testing.T) {
+	srv, last := newFixtureServer(t, http.StatusOK, birdeyeFixture)
+	source := NewBirdeyeSource(HotTokenSourceConfig{Name: "birdeye", URL: srv.URL, APIKey: "key", Chain: "solana"}, nil)
+
+	tokens, err := source.FetchHotTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HotToken{
+		{Chain: "solana", TargetToken: testMintA, TokenSymbol: "AAA", VolumeUSD24h: 4321.5},
+		{Chain: "solana", TargetToken: testBonk, TokenSymbol: "BONK", VolumeUSD24h: 1000000},
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("解析结果\n%+v\n期望\n%+v", tokens, want)
+	}
+	if got := last.Header.Get("X-API-KEY"); got != "key" {
+		t.Errorf("X-API-KEY %q，期望 key", got)
+	}
+	if got := last.Header.Get("x-chain"); got != "solana" {
+		t.Errorf("x-chain %q，期望 solana", got)
+	}
+
+	failed, _ := newFixtureServer(t, http.StatusOK, `{"success": false, "message": "Unauthorized"}`)
+	source = NewBirdeyeSource(HotTokenSourceConfig{Name: "birdeye", URL: failed.URL, Chain: "solana"}, nil)
+	if _, err := source.FetchHotTokens(); err == nil {
+		t.Error("success为false时期望错误")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := write("tokens.json", `[
+  {"target_token": "`+testMintA+`", "token0_symbol": "AAA", "buy_volume_u_15m": 10},
+  {"target_token": "`+testBonk+`", "volume_u_24h": 20}
+]`)
+	tokens, err := NewFileSource(HotTokenSourceConfig{Name: "file", Path: path}).FetchHotTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HotToken{
+		{TargetToken: testMintA, TokenSymbol: "AAA", BuyVolumeUSD15m: 10},
+		{TargetToken: testBonk, VolumeUSD24h: 20},
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("解析结果\n%+v\n期望\n%+v", tokens, want)
+	}
+
+	tests := map[string]string{
+		"missing":        filepath.Join(dir, "missing.json"),
+		"invalid json":   write("invalid.json", `{"target_token": "x"}`),
+		"missing target": write("no_target.json", `[{"token0_symbol": "AAA"}]`),
+	}
+	for name, path := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewFileSource(HotTokenSourceConfig{Name: "file", Path: path}).FetchHotTokens(); err == nil {
+				t.Error("期望错误")
+			}
+		})
+	}
+}
+
+func TestMergeHotTokens(t *testing.T) {
+	ave := []HotToken{
+		{Pair: "pairA", Chain: "solana", Amm: "pump_amm", TargetToken: testMintA, TokenSymbol: "AAA",
+			Volume15m: 100, VolumeUSD24h: 1000, BuyVolumeUSD15m: 50, BuyVolumeUSD5m: 20, Sources: []string{"ave"}},
+		{TargetToken: testBonk, VolumeUSD24h: 10, Sources: []string{"ave"}},
+	}
+	dex := []HotToken{
+		{Pair: "pair9", Chain: "solana", Amm: "raydium", TargetToken: testBonk, TokenSymbol: "BONK",
+			VolumeUSD24h: 5000, Sources: []string{"dex"}},
+		{TargetToken: testMintA, TokenSymbol: "OTHER", VolumeUSD24h: 3000, Sources: []string{"dex"}},
+		{TargetToken: testMintB, VolumeUSD24h: 1, Sources: []string{"dex"}},
+	}
+	birdeye := []HotToken{
+		{TargetToken: testMintA, VolumeUSD24h: 2000, Sources: []string{"birdeye"}},
+		{TargetToken: testMintA, BuyVolumeUSD15m: 70, Sources: []string{"birdeye"}},
+	}
+
+	got := mergeHotTokens(ave, dex, birdeye)
+	want := []HotToken{
+		{Pair: "pairA", Chain: "solana", Amm: "pump_amm", TargetToken: testMintA, TokenSymbol: "AAA",
+			Volume15m: 100, VolumeUSD24h: 3000, BuyVolumeUSD15m: 70, BuyVolumeUSD5m: 20,
+			Sources: []string{"ave", "dex", "birdeye"}},
+		{Pair: "pair9", Chain: "solana", Amm: "raydium", TargetToken: testBonk, TokenSymbol: "BONK",
+			VolumeUSD24h: 5000, Sources: []string{"ave", "dex"}},
+		{TargetToken: testMintB, VolumeUSD24h: 1, Sources: []string{"dex"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("合并结果\n%+v\n期望\n%+v", got, want)
+	}
+	if !reflect.DeepEqual(ave[0].Sources, []string{"ave"}) {
+		t.Errorf("合并修改了输入: %v", ave[0].Sources)
+	}
+}
+
+// staticSource 返回固定结果的测试数据源
+type staticSource struct {
+	name   string
+	tokens []HotToken
+	err    error
+}
+
+func (s *staticSource) Name() string { return s.name }
+
+func (s *staticSource) FetchHotTokens() ([]HotToken, error) {
+	return append([]HotToken(nil), s.tokens...), s.err
+}
+
+func TestFetchFromSources(t *testing.T) {
+	ave := &staticSource{name: "ave", tokens: []HotToken{
+		{TargetToken: testMintA, BuyVolumeUSD15m: 10},
+		{TargetToken: testBonk, BuyVolumeUSD15m: 5},
+	}}
+	dex := &staticSource{name: "dex", tokens: []HotToken{
+		{TargetToken: testBonk, VolumeUSD24h: 100},
+	}}
+	broken := &staticSource{name: "broken", err: errors.New("连接超时")}
+
+	var mu sync.Mutex
+	observed := make(map[string]error)
+	tokens, err := fetchFromSources([]HotTokenSource{broken, ave, dex}, func(name string, _ time.Duration, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		observed[name] = err
+	})
+	if err != nil {
+		t.Fatalf("一个数据源失败时不应返回错误: %v", err)
+	}
+	want := []HotToken{
+		{TargetToken: testMintA, BuyVolumeUSD15m: 10, Sources: []string{"ave"}},
+		{TargetToken: testBonk, BuyVolumeUSD15m: 5, VolumeUSD24h: 100, Sources: []string{"ave", "dex"}},
+	}
+	if !reflect.DeepEqual(tokens, want) {
+		t.Errorf("合并结果\n%+v\n期望\n%+v", tokens, want)
+	}
+	if len(observed) != 3 || observed["broken"] == nil || observed["ave"] != nil {
+		t.Errorf("观测结果不符合预期: %v", observed)
+	}
+
+	_, err = fetchFromSources([]HotTokenSource{broken, &staticSource{name: "down", err: errors.New("503")}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "broken: 连接超时") || !strings.Contains(err.Error(), "down: 503") {
+		t.Errorf("全部失败时的错误不符合预期: %v", err)
+	}
+}
@@ -3,11 +3,8 @@ package agent
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
-	"net/http"
-	"sort"
-	"strings"
+	"reflect"
 	"time"
 )
 
@@ -22,7 +19,8 @@ type HotToken struct {
 	VolumeUSD24h    float64 `json:"volume_u_24h"`
 	BuyVolumeUSD15m float64 `json:"buy_volume_u_15m"`
 	BuyVolumeUSD5m  float64 `json:"buy_volume_u_5m"`
-	FlashAgent      Agent
+
+	Sources []string `json:"sources,omitempty"` // 返回该代币的数据源
 }
 
 // APIResponse API响应结构
@@ -70,97 +68,114 @@ type TokenPoolsInfo struct {
 	MeteoraLists    []string
 	RaydiumPools    []string
 	RaydiumCPPools  []string
+
+	UnsupportedPools []DiscoveredPool // MEV Bot不支持的类型或未知程序的池，只用于报告
 }
 
+// poolList 返回池类型对应的池列表，MintConfig中没有对应列表的类型返回nil
+func (info *TokenPoolsInfo) poolList(kind PoolKind) *[]string {
+	switch kind {
+	case PoolKindPump:
+		return &info.PumpPools
+	case PoolKindRaydium:
+		return &info.RaydiumPools
+	case PoolKindRaydiumCP:
+		return &info.RaydiumCPPools
+	case PoolKindMeteoraDLMM:
+		return &info.MeteoraLists
+	}
+	return nil
+}
+
+// addRankedPools 按评分从高到低把池加入对应的列表，每种类型最多maxPools个
+// 没有对应池列表的类型记为不支持的池，不会放入任何列表
+func (info *TokenPoolsInfo) addRankedPools(ranked []DiscoveredPool, maxPools int) {
+	for _, pool := range ranked {
+		list := info.poolList(pool.Kind)
+		if list == nil {
+			log.Printf("跳过池子 %s: MintConfig中没有 %s 池的列表", pool.Address, pool.Kind)
+			info.UnsupportedPools = append(info.UnsupportedPools, pool)
+			continue
+		}
+		if containsString(*list, pool.Address) {
+			continue
+		}
+		if len(*list) >= maxPools {
+			log.Printf("跳过池子 %s: %s 池已达到最大数量 (%d)", pool.Address, pool.Kind, maxPools)
+			continue
+		}
+		*list = append(*list, pool.Address)
+		log.Printf("添加 %s 池: %s (评分 %.3f, SOL储备 %.2f, 24小时交易量 $%.0f, 成交 %d 笔, 费率 %.0f 基点)",
+			pool.Kind, pool.Address, pool.Score, pool.LiquiditySOL, pool.Volume24h, pool.Trades24h, pool.FeeBps)
+	}
+}
+
+// defaultHotTokenInterval 未配置 hottoken.interval 时的刷新间隔
+const defaultHotTokenInterval = 10 * time.Minute
+
 // HotTokensTracker 热门代币跟踪器
 type HotTokensTracker struct {
-	APIURL          string
+	Sources         []HotTokenSource   // 热门代币数据源，结果按铸币地址合并
+	Discoverers     []PoolDiscoverer   // 池发现提供方，按顺序尝试
+	Reserves        *PoolReserveReader // 读取池的SOL储备，用于评分
 	PollInterval    time.Duration
 	HotTokens       []HotToken
-	MevConfig       *Config
 	AgentConfig     *FlashAgentConfig
 	TokenPoolsInfos []TokenPoolsInfo // 存储所有代币的池信息
 	Agent           *Agent
 }
 
-// NewHotTokensTracker 创建新的热门代币跟踪器
-func NewHotTokensTracker(mevConfig *Config, agentConfig *FlashAgentConfig, agent *Agent) *HotTokensTracker {
-	return &HotTokensTracker{
-		APIURL:       "https://febweb002.com/v1api/v4/tokens/treasure/list",
-		PollInterval: time.Duration(agentConfig.HotTokenConfig.Interval) * time.Minute,
-		HotTokens:    []HotToken{},
-		MevConfig:    mevConfig,
-		AgentConfig:  agentConfig,
-		Agent:        agent,
+// NewHotTokensTracker 创建新的热门代币跟踪器，数据源配置无效时返回错误
+func NewHotTokensTracker(agentConfig *FlashAgentConfig, agent *Agent) (*HotTokensTracker, error) {
+	if err := agentConfig.HotTokenConfig.Selection.Validate(); err != nil {
+		return nil, err
 	}
-}
-
-// FetchHotTokens 获取15分钟内交易量最大的热门代币
-func (h *HotTokensTracker) FetchHotTokens() error {
-	// 构建请求URL和参数 - 获取更多数据然后按15分钟交易量排序
-	url := fmt.Sprintf("%s?chain=solana&pageNO=1&pageSize=50&category=hot&refresh_total=0", h.APIURL)
-
-	// 创建请求
-	req, err := http.NewRequest("GET", url, nil)
+	sources, err := NewHotTokenSources(agentConfig)
 	if err != nil {
-		log.Printf("创建请求失败: %v", err)
-		return err
+		return nil, err
 	}
-
-	// 添加认证Token到请求头
-	req.Header.Set("X-Auth", h.AgentConfig.Ave.Token)
-	req.Header.Set("Accept", "application/json")
-
-	// 发送请求
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	rpcURL := func() string {
+		return agent.store.Current().RPC.URL
+	}
+	discoverers, err := NewPoolDiscoverers(agentConfig, rpcURL)
 	if err != nil {
-		log.Printf("请求API失败: %v", err)
-		return err
+		return nil, err
 	}
-	defer resp.Body.Close()
-
-	// 检查响应状态
-	if resp.StatusCode != 200 {
-		log.Printf("API请求失败，状态码: %d", resp.StatusCode)
-		return fmt.Errorf("API请求失败，状态码: %d", resp.StatusCode)
+	pollInterval := time.Duration(agentConfig.HotTokenConfig.Interval) * time.Minute
+	if pollInterval <= 0 {
+		pollInterval = defaultHotTokenInterval // time.NewTicker 不接受0
 	}
+	return &HotTokensTracker{
+		Sources:      sources,
+		Discoverers:  discoverers,
+		Reserves:     NewPoolReserveReader(agentConfig, rpcURL),
+		PollInterval: pollInterval,
+		HotTokens:    []HotToken{},
+		AgentConfig:  agentConfig,
+		Agent:        agent,
+	}, nil
+}
 
-	// 解析响应
-	body, err := ioutil.ReadAll(resp.Body)
+// FetchHotTokens 按选择策略（selection.sort_by）获取热门代币及其池信息
+func (h *HotTokensTracker) FetchHotTokens() error {
+	tokens, err := fetchFromSources(h.Sources, h.Agent.metrics.ObserveFetch)
 	if err != nil {
-		log.Printf("读取响应失败: %v", err)
-		return err
-	}
-
-	var apiResp APIResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		log.Printf("解析JSON失败: %v", err)
 		return err
 	}
 
-	// 验证响应
-	if apiResp.Status != 1 || len(apiResp.Data.Data) == 0 {
-		log.Printf("API响应格式无效")
-		return fmt.Errorf("API响应格式无效")
-	}
-
-	// 根据15分钟购买量排序
-	tokens := apiResp.Data.Data
-	sort.Slice(tokens, func(i, j int) bool {
-		return tokens[i].BuyVolumeUSD15m > tokens[j].BuyVolumeUSD15m
-	})
-
-	// 仅保留前10个代币
-	if len(tokens) > 10 {
-		tokens = tokens[:10]
+	// 按选择策略过滤、排序并截取
+	policy := h.AgentConfig.HotTokenConfig.Selection
+	tokens, rejected := policy.SelectHotTokens(tokens)
+	for mint, reason := range rejected {
+		log.Printf("过滤掉代币 %s: %s", mint, reason)
 	}
-	log.Printf("获取到30分钟内交易量最大的热门代币: %d个, 分别是 %+v", len(tokens), tokens)
+	log.Printf("按 %s 选出热门代币: %d个, 分别是 %+v", policy.SortBy, len(tokens), tokens)
 
 	// 保存热门代币
 	h.HotTokens = tokens
 
-	// 为每个热门代币创建初始池信息结构
+	// 为每个热门代币创建初始池信息结构，每次刷新重新收集
+	h.TokenPoolsInfos = make([]TokenPoolsInfo, 0, len(h.HotTokens))
 	for _, token := range h.HotTokens {
 		info := TokenPoolsInfo{
 			TokenAddress:    token.TargetToken,
@@ -191,154 +206,70 @@ func (h *HotTokensTracker) FetchHotTokens() error {
 
 // FetchPoolsForToken 获取指定代币的池信息
 func (h *HotTokensTracker) FetchPoolsForToken(tokenInfo *TokenPoolsInfo, tokenAddress string) {
-	// 构建Solscan API URL
-	url := fmt.Sprintf("https://api-v2.solscan.io/v2/token/pools?page=1&page_size=40&token[]=%s", tokenAddress)
-
-	// 创建请求
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		log.Printf("创建Solscan请求失败: %v", err)
-		return
-	}
-
-	// 添加Solscan认证头
-	req.Header.Set("x-sol-auth", h.AgentConfig.SolScan.SolAuth)
-	req.Header.Set("authorization", h.AgentConfig.SolScan.Token)
-	req.Header.Set("cookie", h.AgentConfig.SolScan.Cookie)
-	req.Header.Set("origin", h.AgentConfig.SolScan.Origin)
-	req.Header.Set("referer", h.AgentConfig.SolScan.Referer)
-	req.Header.Set("Accept", "application/json")
-
-	// 发送请求
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		log.Printf("请求Solscan API失败: %v", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	// 检查响应状态
-	if resp.StatusCode != 200 {
-		log.Printf("Solscan API请求失败，状态码: %d", resp.StatusCode)
-		return
-	}
-
-	// 解析响应
-	body, err := ioutil.ReadAll(resp.Body)
+	pools, provider, err := discoverPools(h.Discoverers, tokenAddress, h.Agent.metrics.ObserveFetch)
 	if err != nil {
-		log.Printf("读取Solscan响应失败: %v", err)
-		return
-	}
-
-	var poolResp SolscanPoolResponse
-	if err := json.Unmarshal(body, &poolResp); err != nil {
-		log.Printf("解析Solscan JSON失败: %v", err)
+		log.Printf("获取代币 %s 的池信息失败: %v", tokenAddress, err)
 		return
 	}
 
-	if !poolResp.Success || len(poolResp.Data) == 0 {
-		log.Printf("Solscan没有返回有效数据")
+	if len(pools) == 0 {
+		log.Printf("%s没有返回有效数据", provider)
 		return
 	}
 
-	log.Printf("代币 %s (%s) 的池信息: %d个", tokenInfo.TokenSymbol, tokenInfo.TokenAddress, len(poolResp.Data))
-	log.Printf("池信息: %+v", poolResp.Data)
-
-	// 添加池子计数器
-	addedPoolCount := 0
-	maxPoolsPerToken := 2 // 每个代币最多添加2个池子
-
-	// 遍历data中的实际池，确保只处理与当前代币相关的池
-	for _, pool := range poolResp.Data {
-		// 检查是否已达到最大池子数量
-		if addedPoolCount >= maxPoolsPerToken {
-			log.Printf("代币 %s (%s) 已达到最大池子数量 (%d)，跳过剩余池子",
-				tokenInfo.TokenSymbol, tokenInfo.TokenAddress, maxPoolsPerToken)
-			break // 停止添加更多池子
-		}
+	log.Printf("代币 %s (%s) 的池信息 (%s): %d个", tokenInfo.TokenSymbol, tokenInfo.TokenAddress, provider, len(pools))
+	log.Printf("池信息: %+v", pools)
 
+	// 只保留包含目标代币和SOL且MEV Bot支持的池
+	var candidates []DiscoveredPool
+	for _, pool := range pools {
 		// 验证: 确认池子包含我们的目标代币
-		containsToken := false
-		containsSOL := false // 新增: 检查是否包含SOL
-		for _, tokInfo := range pool.TokensInfo {
-			if strings.EqualFold(tokInfo.Token, tokenAddress) {
-				containsToken = true
-			}
-			if strings.EqualFold(tokInfo.Token, "So11111111111111111111111111111111111111112") {
-				containsSOL = true
-			}
-		}
-
-		if !containsToken {
-			log.Printf("跳过池子 %s: 不包含目标代币 %s", pool.PoolID, tokenAddress)
+		if !pool.HasMint(tokenAddress) {
+			log.Printf("跳过池子 %s: 不包含目标代币 %s", pool.Address, tokenAddress)
 			continue // 跳过此池子
 		}
 
 		// 必须包含SOL才能继续
-		if !containsSOL {
-			log.Printf("跳过池子 %s: 不包含SOL交易对", pool.PoolID)
+		if !pool.HasMint(wrappedSOLMint) {
+			log.Printf("跳过池子 %s: 不包含SOL交易对", pool.Address)
 			continue
 		}
 
-		// 现在我们确信这个池子包含我们的目标代币，继续处理
-		poolAccount, hasPoolAccount := poolResp.Metadata.Accounts[pool.PoolID]
-		if hasPoolAccount {
-			// 检查是否为Pump池
-			if strings.Contains(strings.ToLower(poolAccount.AccountLabel), "pump") &&
-				!strings.Contains(strings.ToLower(poolAccount.AccountLabel), "bonding curve") {
-				// 检查是否已经添加过
-				alreadyAdded := false
-				for _, existingPool := range tokenInfo.PumpPools {
-					if existingPool == pool.PoolID {
-						alreadyAdded = true
-						break
-					}
-				}
-				if !alreadyAdded {
-					tokenInfo.PumpPools = append(tokenInfo.PumpPools, pool.PoolID)
-					log.Printf("添加Pump池: %s, 标签: %v, 账户标签: %s",
-						pool.PoolID, poolAccount.AccountTags, poolAccount.AccountLabel)
-					addedPoolCount++ // 增加计数器
-				}
+		if program, ok := poolProgramByID(pool.ProgramID); !ok || !program.Supported() {
+			// 不支持的池不能放入任何池列表，只记录下来
+			tokenInfo.UnsupportedPools = append(tokenInfo.UnsupportedPools, pool)
+			if ok {
+				log.Printf("跳过池子 %s: MEV Bot不支持 %s 池", pool.Address, program.Name)
+			} else {
+				log.Printf("跳过池子 %s: 未知的程序 %s", pool.Address, pool.ProgramID)
 			}
+			continue
 		}
+		candidates = append(candidates, pool)
+	}
 
-		// 获取程序ID对应的账户信息
-		progAccount, hasProgAccount := poolResp.Metadata.Accounts[pool.ProgramID]
-		if hasProgAccount {
-			// 检查Raydium程序
-			if strings.Contains(strings.ToLower(progAccount.AccountLabel), "raydium") &&
-				progAccount.AccountType == "program" {
-				// 区分普通池和集中流动性池
-				if strings.Contains(strings.ToLower(progAccount.AccountLabel), "concentrated") ||
-					strings.Contains(strings.ToLower(progAccount.AccountLabel), "clmm") {
-					// 这是 Raydium CP 池
-					tokenInfo.RaydiumCPPools = append(tokenInfo.RaydiumCPPools, pool.PoolID)
-					log.Printf("添加 Raydium CP 池: %s", pool.PoolID)
-				} else {
-					// 这是普通 Raydium 池
-					tokenInfo.RaydiumPools = append(tokenInfo.RaydiumPools, pool.PoolID)
-					log.Printf("添加 Raydium 普通池: %s", pool.PoolID)
-				}
-			}
+	// 读取SOL储备，失败时只按交易量、成交笔数和费率评分
+	start := time.Now()
+	err = h.Reserves.LoadReserves(candidates)
+	h.Agent.metrics.ObserveFetch(PoolReservesUpstream, time.Since(start), err)
+	if err != nil {
+		log.Printf("读取代币 %s 的池储备失败，不按储备过滤: %v", tokenAddress, err)
+	}
 
-			// 检查Meteora程序
-			if strings.Contains(strings.ToLower(progAccount.AccountLabel), "meteora") &&
-				progAccount.AccountType == "program" {
-				// 检查是否为 Meteora DLMM 池
-				if strings.Contains(strings.ToLower(progAccount.AccountLabel), "dlmm") {
-					tokenInfo.MeteoraLists = append(tokenInfo.MeteoraLists, pool.PoolID)
-					log.Printf("添加 Meteora DLMM 池: %s, 标签: %s", pool.PoolID, progAccount.AccountLabel)
-				}
-			}
-		}
+	ranking := h.AgentConfig.HotTokenConfig.PoolRanking
+	maxPools := h.AgentConfig.HotTokenConfig.Selection.MaxPoolsPerKind
+	ranked, rejected := rankPools(candidates, ranking)
+	for _, r := range rejected {
+		log.Printf("跳过池子 %s (%s): %s", r.Pool.Address, r.Pool.Kind, r.Reason)
 	}
 
-	log.Printf("代币 %s (%s) 的池信息: Pump池: %d, Meteora池: %d, Raydium池: %d, RaydiumCP池: %d",
+	// 按评分从高到低加入对应的列表，每种类型最多 max_pools_per_kind 个
+	tokenInfo.addRankedPools(ranked, maxPools)
+
+	log.Printf("代币 %s (%s) 的池信息: Pump池: %d, Meteora池: %d, Raydium池: %d, RaydiumCP池: %d, 不支持的池: %d",
 		tokenInfo.TokenSymbol, tokenInfo.TokenAddress,
 		len(tokenInfo.PumpPools), len(tokenInfo.MeteoraLists),
-		len(tokenInfo.RaydiumPools), len(tokenInfo.RaydiumCPPools))
+		len(tokenInfo.RaydiumPools), len(tokenInfo.RaydiumCPPools), len(tokenInfo.UnsupportedPools))
 }
 
 // UpdateConfig 根据搜集到的所有代币池信息更新配置
@@ -349,57 +280,30 @@ func (h *HotTokensTracker) UpdateConfig() {
 		return
 	}
 
-	// 根据交易量排序
-	sort.Slice(h.TokenPoolsInfos, func(i, j int) bool {
-		return h.TokenPoolsInfos[i].BuyVolumeUSD15m > h.TokenPoolsInfos[j].BuyVolumeUSD15m
-	})
-
-	// 只保留交易量最大且有至少两种类型池子的代币
-	var validTokenInfos []TokenPoolsInfo
+	// 按选择策略保留池类型足够的代币，TokenPoolsInfos 已按排序键排好
+	policy := h.AgentConfig.HotTokenConfig.Selection
+	validTokenInfos := policy.SelectTokenPools(h.TokenPoolsInfos)
 	for _, info := range h.TokenPoolsInfos {
-		if !strings.Contains(info.TokenAddress, "pump") {
-			continue // 过滤掉不含有Pump的代币，当前只交易pump
-		}
-		// 计算有多少种类型的池子
-		poolTypeCount := 0
-		if len(info.PumpPools) > 0 {
-			poolTypeCount++
-		}
-		if len(info.MeteoraLists) > 0 {
-			poolTypeCount++
-		}
-		if len(info.RaydiumPools) > 0 {
-			poolTypeCount++
-		}
-		if len(info.RaydiumCPPools) > 0 {
-			poolTypeCount++
-		}
-
-		// 只保留有至少两种类型池子的代币
-		if poolTypeCount >= 2 {
-			validTokenInfos = append(validTokenInfos, info)
-			log.Printf("保留代币 %s (%s): 具有 %d 种类型的池子 (Pump: %d, Meteora: %d, Raydium: %d, RaydiumCP: %d)",
-				info.TokenSymbol, info.TokenAddress, poolTypeCount,
-				len(info.PumpPools), len(info.MeteoraLists),
-				len(info.RaydiumPools), len(info.RaydiumCPPools))
-		} else {
+		if count := info.poolTypeCount(); count < policy.MinPoolTypes {
 			log.Printf("过滤掉代币 %s (%s): 只有 %d 种类型的池子",
-				info.TokenSymbol, info.TokenAddress, poolTypeCount)
+				info.TokenSymbol, info.TokenAddress, count)
 		}
 	}
+	for _, info := range validTokenInfos {
+		log.Printf("保留代币 %s (%s): 具有 %d 种类型的池子 (Pump: %d, Meteora: %d, Raydium: %d, RaydiumCP: %d)",
+			info.TokenSymbol, info.TokenAddress, info.poolTypeCount(),
+			len(info.PumpPools), len(info.MeteoraLists),
+			len(info.RaydiumPools), len(info.RaydiumCPPools))
+	}
 	// 如果没有有效代币，不进行更新
 	if len(validTokenInfos) == 0 {
-		log.Printf("没有找到有池信息的代币")
+		log.Printf("没有找到池类型不少于 %d 种的代币", policy.MinPoolTypes)
 		return
 	}
 
-	// 最多保留前两个
-	if len(validTokenInfos) > 2 {
-		validTokenInfos = validTokenInfos[:2]
-	}
-
-	// 创建MevConfig的副本
-	newMevConfig := h.MevConfig.Copy()
+	// 获取当前配置的副本
+	currentConfig := h.Agent.store.Current()
+	newMevConfig := currentConfig.Copy()
 
 	// 构建新的mint配置列表
 	newMintConfigs := []MintConfig{}
@@ -427,21 +331,6 @@ func (h *HotTokensTracker) UpdateConfig() {
 			}
 		}
 
-		// 截断池子列表，确保不超过最大数量
-		maxPools := 2
-		if len(info.PumpPools) > maxPools {
-			info.PumpPools = info.PumpPools[:maxPools]
-		}
-		if len(info.MeteoraLists) > maxPools {
-			info.MeteoraLists = info.MeteoraLists[:maxPools]
-		}
-		if len(info.RaydiumPools) > maxPools {
-			info.RaydiumPools = info.RaydiumPools[:maxPools]
-		}
-		if len(info.RaydiumCPPools) > maxPools {
-			info.RaydiumCPPools = info.RaydiumCPPools[:maxPools]
-		}
-
 		// 更新池列表
 		if len(info.PumpPools) > 0 {
 			mintConfig.PumpPoolList = info.PumpPools
@@ -462,34 +351,69 @@ func (h *HotTokensTracker) UpdateConfig() {
 	// 更新配置
 	newMevConfig.Routing.MintConfigList = newMintConfigs
 
-	// 保存配置文件
-	if err := newMevConfig.SaveToFile("config.toml"); err != nil {
-		log.Printf("保存配置文件失败: %v", err)
+	// 配置没有变化时不重启MEV Bot
+	if reflect.DeepEqual(currentConfig, newMevConfig) {
+		log.Printf("热门代币池信息没有变化，无需更新配置")
 		return
 	}
 
-	// 更新内存中的配置
-	*h.MevConfig = *newMevConfig
+	// 通过代理保存配置文件并重启MEV Bot
+	info := ChangeInfo{
+		Author: "hot-token-tracker",
+		Reason: fmt.Sprintf("热门代币刷新: %d个代币", len(newMintConfigs)),
+	}
+	mints := make([]string, 0, len(newMintConfigs))
+	for _, mintConfig := range newMintConfigs {
+		mints = append(mints, mintConfig.Mint)
+	}
+	payload, _ := json.Marshal(map[string]interface{}{"mints": mints})
+	entry := AuditEntry{
+		Actor:   info.Author,
+		Command: "config/update",
+		Payload: payload,
+		Result:  AuditResultOK,
+	}
+
+	err := h.Agent.UpdateConfig(newMevConfig, info)
+	entry.Revision = h.Agent.store.Revision()
+	if err != nil {
+		entry.Result = AuditResultError
+		entry.Error = err.Error()
+	}
+	h.Agent.audit.Record(entry)
+	if err != nil {
+		log.Printf("保存配置文件失败: %v", err)
+		return
+	}
 
 	log.Printf("成功更新配置文件，添加/更新了%d个热门代币的池信息", len(newMintConfigs))
 }
 
-// StartTracking 启动跟踪协程
+// StartTracking 启动跟踪协程，代理停止（Agent.ctx取消）时返回
 func (h *HotTokensTracker) StartTracking() {
-	log.Println("启动热门代币跟踪器 - 按15分钟交易量排序")
+	log.Printf("启动热门代币跟踪器 - 按 %s 排序，刷新间隔 %v", h.AgentConfig.HotTokenConfig.Selection.SortBy, h.PollInterval)
 
 	// 立即执行一次
-	if err := h.FetchHotTokens(); err != nil {
+	err := h.FetchHotTokens()
+	h.Agent.markHotTokenRefresh(err)
+	if err != nil {
 		log.Printf("首次获取热门代币失败: %v", err)
 	}
-	h.Agent.RestartMEVBot()
 
 	// 创建定时器
 	ticker := time.NewTicker(h.PollInterval)
-	for range ticker.C {
-		if err := h.FetchHotTokens(); err != nil {
-			log.Printf("获取热门代币失败: %v", err)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.Agent.ctx.Done():
+			log.Println("热门代币跟踪器已停止")
+			return
+		case <-ticker.C:
+			err := h.FetchHotTokens()
+			h.Agent.markHotTokenRefresh(err)
+			if err != nil {
+				log.Printf("获取热门代币失败: %v", err)
+			}
 		}
-		h.Agent.RestartMEVBot()
 	}
 }
@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAddRankedPools(t *testing.T) {
+	ranked := []DiscoveredPool{
+		{Address: "pump1", Kind: PoolKindPump},
+		{Address: "future1", Kind: PoolKind("future_amm")}, // 注册表中新增但MintConfig没有对应列表的类型
+		{Address: "pump2", Kind: PoolKindPump},
+		{Address: "pump1", Kind: PoolKindPump},
+		{Address: "dlmm1", Kind: PoolKindMeteoraDLMM},
+		{Address: "pump3", Kind: PoolKindPump},
+		{Address: "cp1", Kind: PoolKindRaydiumCP},
+		{Address: "ray1", Kind: PoolKindRaydium},
+		{Address: "clmm1", Kind: PoolKindRaydiumCLMM},
+	}
+
+	info := TokenPoolsInfo{PumpPools: []string{}, MeteoraLists: []string{}, RaydiumPools: []string{}, RaydiumCPPools: []string{}}
+	info.addRankedPools(ranked, 2)
+
+	want := TokenPoolsInfo{
+		PumpPools:      []string{"pump1", "pump2"},
+		MeteoraLists:   []string{"dlmm1"},
+		RaydiumPools:   []string{"ray1"},
+		RaydiumCPPools: []string{"cp1"},
+		UnsupportedPools: []DiscoveredPool{
+			{Address: "future1", Kind: PoolKind("future_amm")},
+			{Address: "clmm1", Kind: PoolKindRaydiumCLMM},
+		},
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("池列表\n%+v\n期望\n%+v", info, want)
+	}
+}
+
+// countingSource 记录调用次数并返回错误的数据源
+type countingSource struct {
+	calls int32
+}
+
+func (s *countingSource) Name() string { return "counting" }
+
+func (s *countingSource) FetchHotTokens() ([]HotToken, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return nil, errors.New("上游不可用")
+}
+
+// 代理停止后跟踪协程退出，定时器被释放
+func TestStartTrackingStopsWithAgent(t *testing.T) {
+	agent := newTestAgent(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agent.ctx = ctx
+
+	source := &countingSource{}
+	tracker := &HotTokensTracker{
+		Sources:      []HotTokenSource{source},
+		PollInterval: 10 * time.Millisecond,
+		AgentConfig:  agent.agentConfig,
+		Agent:        agent,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		tracker.StartTracking()
+		close(done)
+	}()
+	waitFor(t, 5*time.Second, "定时刷新", func() bool { return atomic.LoadInt32(&source.calls) >= 3 })
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("代理停止后跟踪协程没有退出")
+	}
+	calls := atomic.LoadInt32(&source.calls)
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&source.calls) != calls {
+		t.Error("跟踪协程退出后仍在刷新")
+	}
+}
@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// LivenessConfig 表示MEV Bot存活检测的配置
+// 任一检测失败都会以 unhealthy 原因停止MEV Bot，并像崩溃一样按重启策略重启
+type LivenessConfig struct {
+	LogSilence    int         `yaml:"log_silence"`    // 超过该秒数没有任何输出则判定为卡死，0表示不检测
+	FatalPatterns []string    `yaml:"fatal_patterns"` // 输出（stdout和stderr）匹配任一正则表达式即判定为失败
+	StartupGrace  int         `yaml:"startup_grace"`  // 进程启动后多少秒内不做静默检测和探针检测
+	Probe         ProbeConfig `yaml:"probe"`          // 可选的HTTP/TCP探针
+}
+
+// ProbeConfig 表示MEV Bot的HTTP/TCP存活探针
+type ProbeConfig struct {
+	HTTP             string `yaml:"http"`              // HTTP探针地址，返回2xx或3xx视为成功
+	TCP              string `yaml:"tcp"`               // TCP探针地址 host:port，能建立连接视为成功
+	Interval         int    `yaml:"interval"`          // 探测间隔，秒
+	Timeout          int    `yaml:"timeout"`           // 单次探测超时，秒
+	FailureThreshold int    `yaml:"failure_threshold"` // 连续失败多少次判定为失败
+}
+
+// Enabled 判断是否配置了探针
+func (c ProbeConfig) Enabled() bool {
+	return c.HTTP != "" || c.TCP != ""
+}
+
+// LivenessMonitor 在监控循环中检测MEV Bot是否仍在正常工作
+type LivenessMonitor struct {
+	config   LivenessConfig
+	patterns []*regexp.Regexp
+	client   *http.Client
+
+	// 以下字段只在监控循环中访问
+	pid        int       // 当前检测的进程
+	started    time.Time // 当前进程的启动时间
+	lastSeq    uint64    // 已检查过的最后一行日志
+	lastOutput time.Time // 最近一次输出的时间
+	nextProbe  time.Time // 下一次探测的时间
+
+	mu            sync.Mutex // 保护后台探测的结果
+	probing       bool
+	probePID      int
+	probeFailures int
+	probeErr      error
+}
+
+// NewLivenessMonitor 创建存活检测器，fatal_patterns中的正则表达式无效时返回错误
+func NewLivenessMonitor(config LivenessConfig) (*LivenessMonitor, error) {
+	m := &LivenessMonitor{
+		config: config,
+		client: &http.Client{Timeout: time.Duration(config.Probe.Timeout) * time.Second},
+	}
+	for _, pattern := range config.FatalPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("fatal_patterns %q 无效: %w", pattern, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	return m, nil
+}
+
+// Check 检查正在运行的进程，返回失败原因，检测通过时返回空字符串
+// pid和started标识当前进程，进程变化时重置检测状态
+func (m *LivenessMonitor) Check(now time.Time, pid int, started time.Time, logs *LogBuffer) string {
+	if pid != m.pid {
+		m.reset(pid, started)
+	}
+
+	// 检查新输出的日志
+	for _, line := range logs.Since(m.lastSeq, 0) {
+		m.lastSeq = line.Seq
+		if line.Time.Before(m.started) {
+			continue // 上一个进程的输出
+		}
+		m.lastOutput = line.Time
+		for _, re := range m.patterns {
+			if re.MatchString(line.Text) {
+				return fmt.Sprintf("输出匹配致命模式 %q: %s", re.String(), line.Text)
+			}
+		}
+	}
+
+	// 启动宽限期内不做静默检测和探针检测
+	if now.Sub(m.started) < time.Duration(m.config.StartupGrace)*time.Second {
+		return ""
+	}
+
+	if silence := time.Duration(m.config.LogSilence) * time.Second; silence > 0 && now.Sub(m.lastOutput) > silence {
+		return fmt.Sprintf("已 %.0f 秒没有任何输出", now.Sub(m.lastOutput).Seconds())
+	}
+
+	if !m.config.Probe.Enabled() {
+		return ""
+	}
+	if !now.Before(m.nextProbe) {
+		m.nextProbe = now.Add(time.Duration(m.config.Probe.Interval) * time.Second)
+		m.startProbe(pid)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.probePID == pid && m.probeFailures >= m.config.Probe.FailureThreshold {
+		return fmt.Sprintf("存活探针连续失败 %d 次: %v", m.probeFailures, m.probeErr)
+	}
+	return ""
+}
+
+// reset 开始检测新的进程
+func (m *LivenessMonitor) reset(pid int, started time.Time) {
+	m.pid = pid
+	m.started = started
+	m.lastSeq = 0
+	m.lastOutput = started
+	m.nextProbe = started.Add(time.Duration(m.config.StartupGrace) * time.Second)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.probePID = pid
+	m.probeFailures = 0
+	m.probeErr = nil
+}
+
+// startProbe 在后台执行一次探测，上一次探测尚未结束时跳过
+func (m *LivenessMonitor) startProbe(pid int) {
+	m.mu.Lock()
+	if m.probing {
+		m.mu.Unlock()
+		return
+	}
+	m.probing = true
+	m.mu.Unlock()
+
+	go func() {
+		err := m.probe()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.probing = false
+		if m.probePID != pid {
+			return // 进程已经变化，丢弃结果
+		}
+		if err != nil {
+			m.probeFailures++
+			m.probeErr = err
+		} else {
+			m.probeFailures = 0
+			m.probeErr = nil
+		}
+	}()
+}
+
+// probe 执行一次HTTP或TCP探测
+func (m *LivenessMonitor) probe() error {
+	probe := m.config.Probe
+	if probe.TCP != "" {
+		conn, err := net.DialTimeout("tcp", probe.TCP, time.Duration(probe.Timeout)*time.Second)
+		if err != nil {
+			return fmt.Errorf("TCP探针 %s 失败: %w", probe.TCP, err)
+		}
+		conn.Close()
+	}
+
+	if probe.HTTP != "" {
+		resp, err := m.client.Get(probe.HTTP)
+		if err != nil {
+			return fmt.Errorf("HTTP探针 %s 失败: %w", probe.HTTP, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("HTTP探针 %s 返回状态码 %d", probe.HTTP, resp.StatusCode)
+		}
+	}
+	return nil
+}
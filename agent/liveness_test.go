@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewLivenessMonitorRejectsInvalidPattern(t *testing.T) {
+	if _, err := NewLivenessMonitor(LivenessConfig{FatalPatterns: []string{"panic", "("}}); err == nil {
+		t.Error("无效的正则表达式应返回错误")
+	}
+}
+
+func TestLivenessFatalPattern(t *testing.T) {
+	m, err := NewLivenessMonitor(LivenessConfig{FatalPatterns: []string{`(?i)panic`, `RPC.*429`}, StartupGrace: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := NewLogBuffer(100)
+
+	// 上一个进程的输出不参与检测
+	logs.Append("stderr", "panic: 上一个进程")
+	time.Sleep(10 * time.Millisecond)
+	started := time.Now()
+	if reason := m.Check(started, 100, started, logs); reason != "" {
+		t.Fatalf("上一个进程的输出触发了检测: %s", reason)
+	}
+
+	logs.Append("stdout", "正在连接RPC")
+	if reason := m.Check(time.Now(), 100, started, logs); reason != "" {
+		t.Fatalf("正常输出触发了检测: %s", reason)
+	}
+
+	// 宽限期内致命模式同样生效
+	logs.Append("stderr", "RPC返回 429 Too Many Requests")
+	reason := m.Check(time.Now(), 100, started, logs)
+	if !strings.Contains(reason, `"RPC.*429"`) || !strings.Contains(reason, "RPC返回 429 Too Many Requests") {
+		t.Errorf("失败原因 %q，期望包含模式和输出行", reason)
+	}
+
+	// 已检查过的行不会重复触发
+	if reason := m.Check(time.Now(), 100, started, logs); reason != "" {
+		t.Errorf("同一行重复触发: %s", reason)
+	}
+}
+
+func TestLivenessLogSilence(t *testing.T) {
+	m, err := NewLivenessMonitor(LivenessConfig{LogSilence: 60, StartupGrace: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := NewLogBuffer(100)
+	started := time.Now()
+
+	tests := []struct {
+		name    string
+		after   time.Duration
+		pid     int
+		started time.Time
+		want    string
+	}{
+		{name: "within grace", after: 20 * time.Second, pid: 1, started: started},
+		{name: "within silence", after: 60 * time.Second, pid: 1, started: started},
+		{name: "silent", after: 61 * time.Second, pid: 1, started: started, want: "已 61 秒没有任何输出"},
+		// 新进程重新计算静默时间和宽限期
+		{name: "new process", after: 62 * time.Second, pid: 2, started: started.Add(61 * time.Second)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reason := m.Check(started.Add(tt.after), tt.pid, tt.started, logs); reason != tt.want {
+				t.Errorf("失败原因 %q，期望 %q", reason, tt.want)
+			}
+		})
+	}
+}
+
+func TestLivenessProbeFailureThreshold(t *testing.T) {
+	var healthy atomic.Value
+	healthy.Store(false)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load().(bool) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	m, err := NewLivenessMonitor(LivenessConfig{
+		Probe: ProbeConfig{HTTP: srv.URL, Interval: 1, Timeout: 1, FailureThreshold: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logs := NewLogBuffer(10)
+	started := time.Now()
+	now := started
+
+	// 每次调用前进一个探测间隔，等待后台探测完成
+	probeOnce := func() string {
+		now = now.Add(time.Second)
+		m.Check(now, 1, started, logs)
+		waitFor(t, 5*time.Second, "探测完成", func() bool {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			return !m.probing
+		})
+		return m.Check(now, 1, started, logs)
+	}
+
+	if reason := probeOnce(); reason != "" {
+		t.Fatalf("失败一次就判定为失败: %s", reason)
+	}
+	reason := probeOnce()
+	if !strings.Contains(reason, "存活探针连续失败 2 次") || !strings.Contains(reason, "返回状态码 503") {
+		t.Fatalf("失败原因 %q", reason)
+	}
+
+	// 探测成功后清零失败次数
+	healthy.Store(true)
+	if reason := probeOnce(); reason != "" {
+		t.Errorf("探测恢复后仍失败: %s", reason)
+	}
+}
+
+// 存活检测失败时，运行历史记录stop_cause为unhealthy，reason为具体原因
+func TestLivenessFailureRecordsReason(t *testing.T) {
+	a := newLifecycleTestAgent(t)
+	a.proc = NewProcessManager("MEV Bot", "sh", "-c", "echo 'panic: RPC节点不可用'; exec sleep 30")
+	a.proc.SetLogOutput(nil, nil)
+	a.proc.SetRunHistory(a.history, 10)
+	t.Cleanup(func() { a.proc.Stop(StopCauseShutdown) })
+
+	var err error
+	a.liveness, err = NewLivenessMonitor(LivenessConfig{FatalPatterns: []string{"panic"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.StartMEVBot(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, 5*time.Second, "MEV Bot输出", func() bool { return len(a.proc.Logs().Tail(1)) > 0 })
+
+	a.superviseBot(time.Now())
+	waitFor(t, 10*time.Second, "MEV Bot被停止", func() bool {
+		record, ok := a.history.Last()
+		return ok && record.StopCause == StopCauseUnhealthy
+	})
+
+	record, _ := a.history.Last()
+	if !strings.Contains(record.Reason, `致命模式 "panic"`) || !strings.Contains(record.Reason, "panic: RPC节点不可用") {
+		t.Errorf("运行记录的原因 %q", record.Reason)
+	}
+	if a.proc.IsRunning() {
+		t.Error("存活检测失败后MEV Bot仍在运行")
+	}
+}
@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+)
+
+// 默认在内存中保留的Bot日志行数
+const defaultLogBufferLines = 2000
+
+// LogLine 表示MEV Bot输出的一行日志
+type LogLine struct {
+	Seq    uint64    `json:"seq"`    // 递增序号，便于客户端去重
+	Time   time.Time `json:"time"`   // 捕获时间
+	Stream string    `json:"stream"` // stdout 或 stderr
+	Text   string    `json:"text"`   // 日志内容（不含换行符）
+}
+
+// LogBuffer 保存最近N行日志的环形缓冲区，并支持实时订阅
+type LogBuffer struct {
+	mu          sync.RWMutex
+	lines       []LogLine
+	next        int // 下一次写入的位置
+	count       int // 当前保存的行数
+	seq         uint64
+	subscribers map[chan LogLine]struct{}
+}
+
+// NewLogBuffer 创建指定容量的日志环形缓冲区
+func NewLogBuffer(capacity int) *LogBuffer {
+	if capacity <= 0 {
+		capacity = defaultLogBufferLines
+	}
+	return &LogBuffer{
+		lines:       make([]LogLine, capacity),
+		subscribers: make(map[chan LogLine]struct{}),
+	}
+}
+
+// Append 追加一行日志，并推送给所有订阅者
+func (b *LogBuffer) Append(stream, text string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	line := LogLine{
+		Seq:    b.seq,
+		Time:   time.Now(),
+		Stream: stream,
+		Text:   text,
+	}
+
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.count < len(b.lines) {
+		b.count++
+	}
+
+	// 推送给订阅者，订阅者处理不过来时丢弃，避免阻塞进程输出
+	for ch := range b.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// Tail 返回最近的n行日志，按时间先后排列
+func (b *LogBuffer) Tail(n int) []LogLine {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if n <= 0 || n > b.count {
+		n = b.count
+	}
+
+	result := make([]LogLine, 0, n)
+	start := (b.next - n + len(b.lines)) % len(b.lines)
+	for i := 0; i < n; i++ {
+		result = append(result, b.lines[(start+i)%len(b.lines)])
+	}
+	return result
+}
+
+// Since 返回序号大于seq的日志中最近的n行
+func (b *LogBuffer) Since(seq uint64, n int) []LogLine {
+	lines := b.Tail(0)
+
+	start := len(lines)
+	for start > 0 && lines[start-1].Seq > seq {
+		start--
+	}
+	lines = lines[start:]
+
+	if n > 0 && len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// LastSeq 返回最近一行日志的序号
+func (b *LogBuffer) LastSeq() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.seq
+}
+
+// Subscribe 订阅新的日志行，返回日志通道和取消订阅函数
+func (b *LogBuffer) Subscribe(bufSize int) (<-chan LogLine, func()) {
+	if bufSize <= 0 {
+		bufSize = 100
+	}
+	ch := make(chan LogLine, bufSize)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
+
+// lineWriter 将进程输出按行切分写入LogBuffer，同时原样写入可选的输出目标
+type lineWriter struct {
+	mu      sync.Mutex
+	stream  string
+	logs    *LogBuffer
+	out     io.Writer
+	pending []byte
+}
+
+// newLineWriter 创建按行捕获输出的写入器
+func newLineWriter(stream string, logs *LogBuffer, out io.Writer) *lineWriter {
+	return &lineWriter{
+		stream: stream,
+		logs:   logs,
+		out:    out,
+	}
+}
+
+// Write 实现io.Writer接口
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.out != nil {
+		// 输出目标写入失败不影响日志捕获
+		w.out.Write(p)
+	}
+
+	w.pending = append(w.pending, p...)
+	for {
+		idx := bytes.IndexByte(w.pending, '\n')
+		if idx < 0 {
+			break
+		}
+		w.logs.Append(w.stream, string(bytes.TrimRight(w.pending[:idx], "\r")))
+		w.pending = w.pending[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush 将未以换行结尾的剩余内容作为一行写入
+func (w *lineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.pending) > 0 {
+		w.logs.Append(w.stream, string(bytes.TrimRight(w.pending, "\r")))
+		w.pending = nil
+	}
+}
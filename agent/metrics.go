@@ -0,0 +1,354 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MetricsPath Prometheus指标的路径
+const MetricsPath = "/metrics"
+
+// 指标名称前缀
+const metricsPrefix = "flash_agent_"
+
+// latencyBuckets 命令和上游请求耗时直方图的分桶（秒）
+// 配置更新会重启MEV Bot，耗时可能达到优雅关闭的等待时间
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// clockTicks /proc/<pid>/stat 中CPU时间的单位（USER_HZ），Linux上固定为100
+const clockTicks = 100
+
+// histogram 是一个固定分桶的Prometheus直方图
+type histogram struct {
+	counts []uint64 // 与latencyBuckets一一对应，不含+Inf
+	sum    float64
+	count  uint64
+}
+
+// newHistogram 创建空的直方图
+func newHistogram() *histogram {
+	return &histogram{counts: make([]uint64, len(latencyBuckets))}
+}
+
+// observe 记录一次观测值
+func (h *histogram) observe(value float64) {
+	for i, bound := range latencyBuckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// commandMetric 单个命令的调用统计
+type commandMetric struct {
+	results map[string]uint64 // 按结果（ok、error）计数
+	latency *histogram
+}
+
+// fetchMetric 单个上游的请求统计
+type fetchMetric struct {
+	requests uint64
+	failures uint64
+	duration *histogram
+}
+
+// Metrics 收集代理的计数器和直方图，瞬时值在导出时从Agent读取
+type Metrics struct {
+	mu       sync.Mutex
+	restarts map[StopCause]uint64
+	commands map[string]*commandMetric // 键为 type/action
+	fetches  map[string]*fetchMetric   // 键为上游名称
+}
+
+// NewMetrics 创建指标收集器
+// 常见的重启原因预先导出为0，便于在第一次重启前配置告警
+func NewMetrics() *Metrics {
+	return &Metrics{
+		restarts: map[StopCause]uint64{
+			StopCauseExited:    0,
+			StopCauseUnhealthy: 0,
+			StopCauseManual:    0,
+			StopCauseConfig:    0,
+		},
+		commands: make(map[string]*commandMetric),
+		fetches:  make(map[string]*fetchMetric),
+	}
+}
+
+// ObserveRestart 记录一次MEV Bot重启，cause为触发重启的原因
+func (m *Metrics) ObserveRestart(cause StopCause) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.restarts[cause]++
+}
+
+// ObserveCommand 记录一次命令执行的结果和耗时
+func (m *Metrics) ObserveCommand(command string, ok bool, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metric, exists := m.commands[command]
+	if !exists {
+		metric = &commandMetric{results: make(map[string]uint64), latency: newHistogram()}
+		m.commands[command] = metric
+	}
+	result := AuditResultOK
+	if !ok {
+		result = AuditResultError
+	}
+	metric.results[result]++
+	metric.latency.observe(elapsed.Seconds())
+}
+
+// ObserveFetch 记录一次上游请求（如ave、solscan）的耗时和是否失败
+func (m *Metrics) ObserveFetch(upstream string, elapsed time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metric, exists := m.fetches[upstream]
+	if !exists {
+		metric = &fetchMetric{duration: newHistogram()}
+		m.fetches[upstream] = metric
+	}
+	metric.requests++
+	if err != nil {
+		metric.failures++
+	}
+	metric.duration.observe(elapsed.Seconds())
+}
+
+// writeTo 以Prometheus文本格式写出计数器和直方图
+func (m *Metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeMetricHeader(w, "bot_restarts_total", "counter", "MEV Bot重启次数，按触发原因区分")
+	causes := make([]string, 0, len(m.restarts))
+	for cause := range m.restarts {
+		causes = append(causes, string(cause))
+	}
+	sort.Strings(causes)
+	for _, cause := range causes {
+		writeSample(w, "bot_restarts_total", labels("cause", cause), float64(m.restarts[StopCause(cause)]))
+	}
+
+	commands := make([]string, 0, len(m.commands))
+	for command := range m.commands {
+		commands = append(commands, command)
+	}
+	sort.Strings(commands)
+
+	writeMetricHeader(w, "commands_total", "counter", "执行的控制命令数，按命令和结果区分")
+	for _, command := range commands {
+		results := m.commands[command].results
+		for _, result := range []string{AuditResultOK, AuditResultError} {
+			writeSample(w, "commands_total", append(commandLabels(command), "result", result), float64(results[result]))
+		}
+	}
+	writeMetricHeader(w, "command_duration_seconds", "histogram", "控制命令的执行耗时")
+	for _, command := range commands {
+		writeHistogram(w, "command_duration_seconds", commandLabels(command), m.commands[command].latency)
+	}
+
+	upstreams := make([]string, 0, len(m.fetches))
+	for upstream := range m.fetches {
+		upstreams = append(upstreams, upstream)
+	}
+	sort.Strings(upstreams)
+
+	writeMetricHeader(w, "hot_token_fetches_total", "counter", "热门代币跟踪器的上游请求数")
+	for _, upstream := range upstreams {
+		writeSample(w, "hot_token_fetches_total", labels("upstream", upstream), float64(m.fetches[upstream].requests))
+	}
+	writeMetricHeader(w, "hot_token_fetch_failures_total", "counter", "热门代币跟踪器失败的上游请求数")
+	for _, upstream := range upstreams {
+		writeSample(w, "hot_token_fetch_failures_total", labels("upstream", upstream), float64(m.fetches[upstream].failures))
+	}
+	writeMetricHeader(w, "hot_token_fetch_duration_seconds", "histogram", "热门代币跟踪器的上游请求耗时")
+	for _, upstream := range upstreams {
+		writeHistogram(w, "hot_token_fetch_duration_seconds", labels("upstream", upstream), m.fetches[upstream].duration)
+	}
+}
+
+// handleMetrics 以Prometheus文本格式导出指标，需要viewer及以上角色的令牌
+func (ws *WebSocketServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if _, ok := ws.auth.Authenticate(r); !ok {
+		log.Printf("指标接口验证失败: 无效的token (%s)", r.RemoteAddr)
+		http.Error(w, "未提供有效的令牌", http.StatusUnauthorized)
+		return
+	}
+
+	var buf bytes.Buffer
+	ws.writeMetrics(&buf)
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// writeMetrics 写出所有指标，瞬时值在此时读取
+func (ws *WebSocketServer) writeMetrics(w io.Writer) {
+	agent := ws.agent
+	status := agent.Status()
+
+	up := 0.0
+	if status.PID != 0 {
+		up = 1
+	}
+	writeMetricHeader(w, "bot_up", "gauge", "MEV Bot进程是否在运行")
+	writeSample(w, "bot_up", nil, up)
+
+	writeMetricHeader(w, "bot_state", "gauge", "MEV Bot的生命周期状态，当前状态为1")
+	for _, state := range []BotState{BotStateStopped, BotStateStarting, BotStateRunning, BotStateStopping, BotStateCrashLooping, BotStateUpdatingConfig} {
+		value := 0.0
+		if state == status.State {
+			value = 1
+		}
+		writeSample(w, "bot_state", labels("state", string(state)), value)
+	}
+
+	writeMetricHeader(w, "bot_uptime_seconds", "gauge", "当前MEV Bot进程已运行的秒数")
+	writeSample(w, "bot_uptime_seconds", nil, status.Uptime)
+
+	writeMetricHeader(w, "bot_consecutive_failures", "gauge", "MEV Bot连续异常退出的次数")
+	writeSample(w, "bot_consecutive_failures", nil, float64(status.RestartPolicy.ConsecutiveFailures))
+
+	if status.PID != 0 {
+		cpu, rss, err := readProcStats(status.PID)
+		if err == nil {
+			writeMetricHeader(w, "bot_cpu_seconds_total", "counter", "MEV Bot进程消耗的用户态和内核态CPU时间")
+			writeSample(w, "bot_cpu_seconds_total", nil, cpu)
+			writeMetricHeader(w, "bot_resident_memory_bytes", "gauge", "MEV Bot进程的常驻内存")
+			writeSample(w, "bot_resident_memory_bytes", nil, float64(rss))
+		}
+	}
+
+	ws.mu.Lock()
+	clients := len(ws.clients)
+	ws.mu.Unlock()
+	writeMetricHeader(w, "websocket_clients", "gauge", "当前连接的WebSocket客户端数")
+	writeSample(w, "websocket_clients", nil, float64(clients))
+
+	writeMetricHeader(w, "config_revision", "gauge", "MEV Bot配置的当前版本号")
+	writeSample(w, "config_revision", nil, float64(status.ConfigRevision))
+
+	agent.metrics.writeTo(w)
+}
+
+// readProcStats 从/proc读取进程的CPU时间（秒）和常驻内存（字节），仅支持Linux
+func readProcStats(pid int) (float64, int64, error) {
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	// 进程名可能包含空格和括号，从最后一个右括号之后开始解析
+	end := bytes.LastIndexByte(stat, ')')
+	if end < 0 {
+		return 0, 0, fmt.Errorf("无法解析 /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(string(stat[end+1:]))
+	// 右括号后的第一个字段是state（第3个字段），utime和stime是第14、15个字段
+	if len(fields) < 13 {
+		return 0, 0, fmt.Errorf("无法解析 /proc/%d/stat", pid)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("解析utime失败: %w", err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("解析stime失败: %w", err)
+	}
+
+	statm, err := os.ReadFile(fmt.Sprintf("/proc/%d/statm", pid))
+	if err != nil {
+		return 0, 0, err
+	}
+	pages := strings.Fields(string(statm))
+	if len(pages) < 2 {
+		return 0, 0, fmt.Errorf("无法解析 /proc/%d/statm", pid)
+	}
+	resident, err := strconv.ParseInt(pages[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("解析常驻内存失败: %w", err)
+	}
+
+	return float64(utime+stime) / clockTicks, resident * int64(os.Getpagesize()), nil
+}
+
+// labels 将成对的名称和值组成标签列表
+func labels(pairs ...string) []string {
+	return pairs
+}
+
+// commandLabels 将 type/action 拆分为标签
+func commandLabels(command string) []string {
+	typ, action := command, ""
+	if i := strings.Index(command, "/"); i >= 0 {
+		typ, action = command[:i], command[i+1:]
+	}
+	return labels("type", typ, "action", action)
+}
+
+// writeMetricHeader 写出指标的HELP和TYPE行
+func writeMetricHeader(w io.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s%s %s\n", metricsPrefix, name, help)
+	fmt.Fprintf(w, "# TYPE %s%s %s\n", metricsPrefix, name, typ)
+}
+
+// writeSample 写出一个样本
+func writeSample(w io.Writer, name string, pairs []string, value float64) {
+	fmt.Fprintf(w, "%s%s%s %s\n", metricsPrefix, name, formatLabels(pairs), strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+// writeHistogram 写出直方图的分桶、总和与计数
+func writeHistogram(w io.Writer, name string, pairs []string, h *histogram) {
+	for i, bound := range latencyBuckets {
+		le := strconv.FormatFloat(bound, 'g', -1, 64)
+		writeSample(w, name+"_bucket", append(append([]string{}, pairs...), "le", le), float64(h.counts[i]))
+	}
+	writeSample(w, name+"_bucket", append(append([]string{}, pairs...), "le", "+Inf"), float64(h.count))
+	writeSample(w, name+"_sum", pairs, h.sum)
+	writeSample(w, name+"_count", pairs, float64(h.count))
+}
+
+// formatLabels 将标签格式化为 {name="value",...}
+func formatLabels(pairs []string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(labelEscaper.Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelEscaper 转义标签值中的反斜杠、双引号和换行
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,227 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sampleLine Prometheus文本格式的样本行：名称、可选的标签和数值
+var sampleLine = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(\{(?:[a-zA-Z_][a-zA-Z0-9_]*="(?:[^"\\]|\\.)*",?)*\})? (\S+)$`)
+
+// exposition 解析后的指标输出
+type exposition struct {
+	types   map[string]string  // 指标族 -> 类型
+	samples map[string]float64 // 名称加标签 -> 数值
+}
+
+// parseExposition 按Prometheus文本格式（0.0.4）解析并检查输出：
+// 每个指标族先有HELP和TYPE，且只声明一次；样本属于最近声明的指标族；数值可以解析
+func parseExposition(t *testing.T, text string) *exposition {
+	t.Helper()
+	exp := &exposition{types: make(map[string]string), samples: make(map[string]float64)}
+	helped := make(map[string]bool)
+	current := ""
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "# HELP "):
+			fields := strings.SplitN(strings.TrimPrefix(text, "# HELP "), " ", 2)
+			if helped[fields[0]] {
+				t.Errorf("第%d行: %s 的HELP重复", line, fields[0])
+			}
+			helped[fields[0]] = true
+		case strings.HasPrefix(text, "# TYPE "):
+			fields := strings.Fields(strings.TrimPrefix(text, "# TYPE "))
+			if len(fields) != 2 {
+				t.Fatalf("第%d行: TYPE格式错误: %q", line, text)
+			}
+			if _, ok := exp.types[fields[0]]; ok {
+				t.Errorf("第%d行: %s 的TYPE重复", line, fields[0])
+			}
+			if !helped[fields[0]] {
+				t.Errorf("第%d行: %s 的TYPE之前没有HELP", line, fields[0])
+			}
+			switch fields[1] {
+			case "counter", "gauge", "histogram":
+			default:
+				t.Errorf("第%d行: 未知类型 %q", line, fields[1])
+			}
+			exp.types[fields[0]] = fields[1]
+			current = fields[0]
+		default:
+			m := sampleLine.FindStringSubmatch(text)
+			if m == nil {
+				t.Fatalf("第%d行不是有效的样本: %q", line, text)
+			}
+			name := m[1]
+			family := name
+			if exp.types[current] == "histogram" {
+				family = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "_bucket"), "_sum"), "_count")
+			}
+			if family != current {
+				t.Errorf("第%d行: 样本 %s 不属于最近声明的指标族 %s", line, name, current)
+			}
+			if exp.types[current] == "counter" && !strings.HasSuffix(name, "_total") {
+				t.Errorf("第%d行: 计数器 %s 应以 _total 结尾", line, name)
+			}
+			value, err := strconv.ParseFloat(m[3], 64)
+			if err != nil {
+				t.Errorf("第%d行: 无法解析数值 %q", line, m[3])
+			}
+			key := name + m[2]
+			if _, ok := exp.samples[key]; ok {
+				t.Errorf("第%d行: 样本 %s 重复", line, key)
+			}
+			exp.samples[key] = value
+		}
+	}
+	return exp
+}
+
+// value 返回样本的值，样本不存在时测试失败
+func (e *exposition) value(t *testing.T, key string) float64 {
+	t.Helper()
+	value, ok := e.samples[key]
+	if !ok {
+		t.Fatalf("缺少样本 %s", key)
+	}
+	return value
+}
+
+// checkHistogram 检查直方图的分桶单调不减，且+Inf分桶等于样本数
+func (e *exposition) checkHistogram(t *testing.T, name, labels string) {
+	t.Helper()
+	prefix := ""
+	if labels != "" {
+		prefix = labels + ","
+	}
+	previous := 0.0
+	for _, bound := range latencyBuckets {
+		le := strconv.FormatFloat(bound, 'g', -1, 64)
+		count := e.value(t, name+`_bucket{`+prefix+`le="`+le+`"}`)
+		if count < previous {
+			t.Errorf("%s 分桶 le=%s 的值 %v 小于前一个分桶 %v", name, le, count, previous)
+		}
+		previous = count
+	}
+	inf := e.value(t, name+`_bucket{`+prefix+`le="+Inf"}`)
+	suffix := ""
+	if labels != "" {
+		suffix = "{" + labels + "}"
+	}
+	if count := e.value(t, name+"_count"+suffix); inf != count || inf < previous {
+		t.Errorf("%s +Inf分桶 %v，样本数 %v，最后一个分桶 %v", name, inf, count, previous)
+	}
+}
+
+func TestMetricsExposition(t *testing.T) {
+	ws := newTestWebSocketServer(t, testTokens...)
+	metrics := ws.agent.metrics
+	metrics.ObserveRestart(StopCauseUnhealthy)
+	metrics.ObserveRestart(StopCauseUnhealthy)
+	metrics.ObserveCommand("config/get", true, 3*time.Millisecond)
+	metrics.ObserveCommand("config/get", false, 2*time.Second)
+	metrics.ObserveCommand("config/get", true, time.Minute)
+	metrics.ObserveFetch("ave", 200*time.Millisecond, nil)
+	metrics.ObserveFetch("ave", 40*time.Millisecond, errors.New("timeout"))
+	metrics.ObserveFetch(`odd"name\`, time.Millisecond, nil)
+
+	// 未注册的命令统一计入 unknown/unknown
+	ws.dispatch(&commandRequest{cmd: &Command{Type: "no", Action: "such"}, principal: &Principal{Name: "t", Role: RoleAdmin}})
+
+	var buf bytes.Buffer
+	ws.writeMetrics(&buf)
+	exp := parseExposition(t, buf.String())
+
+	for name, typ := range map[string]string{
+		"flash_agent_bot_up":                           "gauge",
+		"flash_agent_bot_state":                        "gauge",
+		"flash_agent_bot_restarts_total":               "counter",
+		"flash_agent_commands_total":                   "counter",
+		"flash_agent_command_duration_seconds":         "histogram",
+		"flash_agent_hot_token_fetches_total":          "counter",
+		"flash_agent_hot_token_fetch_failures_total":   "counter",
+		"flash_agent_hot_token_fetch_duration_seconds": "histogram",
+		"flash_agent_config_revision":                  "gauge",
+		"flash_agent_websocket_clients":                "gauge",
+	} {
+		if exp.types[name] != typ {
+			t.Errorf("%s 类型 %q，期望 %q", name, exp.types[name], typ)
+		}
+	}
+
+	checks := map[string]float64{
+		`flash_agent_bot_up`:                                                                 0,
+		`flash_agent_bot_state{state="stopped"}`:                                             1,
+		`flash_agent_bot_state{state="running"}`:                                             0,
+		`flash_agent_bot_restarts_total{cause="unhealthy"}`:                                  2,
+		`flash_agent_bot_restarts_total{cause="manual"}`:                                     0,
+		`flash_agent_commands_total{type="config",action="get",result="ok"}`:                 2,
+		`flash_agent_commands_total{type="config",action="get",result="error"}`:              1,
+		`flash_agent_commands_total{type="unknown",action="unknown",result="error"}`:         1,
+		`flash_agent_command_duration_seconds_bucket{type="config",action="get",le="0.005"}`: 1,
+		`flash_agent_command_duration_seconds_bucket{type="config",action="get",le="2.5"}`:   2,
+		`flash_agent_command_duration_seconds_bucket{type="config",action="get",le="30"}`:    2,
+		`flash_agent_command_duration_seconds_bucket{type="config",action="get",le="+Inf"}`:  3,
+		`flash_agent_hot_token_fetches_total{upstream="ave"}`:                                2,
+		`flash_agent_hot_token_fetch_failures_total{upstream="ave"}`:                         1,
+		`flash_agent_hot_token_fetches_total{upstream="odd\"name\\"}`:                        1,
+		`flash_agent_config_revision`:                                                        float64(ws.agent.store.Revision()),
+	}
+	for key, want := range checks {
+		if got := exp.value(t, key); got != want {
+			t.Errorf("%s = %v，期望 %v", key, got, want)
+		}
+	}
+	if sum := exp.value(t, `flash_agent_command_duration_seconds_sum{type="config",action="get"}`); sum < 62 || sum > 62.01 {
+		t.Errorf("耗时总和 %v，期望约62.003", sum)
+	}
+
+	exp.checkHistogram(t, "flash_agent_command_duration_seconds", `type="config",action="get"`)
+	exp.checkHistogram(t, "flash_agent_command_duration_seconds", `type="unknown",action="unknown"`)
+	exp.checkHistogram(t, "flash_agent_hot_token_fetch_duration_seconds", `upstream="ave"`)
+}
+
+func TestFormatLabelsEscaping(t *testing.T) {
+	got := formatLabels(labels("path", "a\"b\\c\nd", "empty", ""))
+	want := `{path="a\"b\\c\nd",empty=""}`
+	if got != want {
+		t.Errorf("标签 %s，期望 %s", got, want)
+	}
+	if formatLabels(nil) != "" {
+		t.Error("没有标签时应为空")
+	}
+}
+
+// /metrics 需要令牌，返回Prometheus文本格式
+func TestMetricsEndpoint(t *testing.T) {
+	ws := newTestWebSocketServer(t, testTokens...)
+
+	rec := httptest.NewRecorder()
+	ws.handleMetrics(rec, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("没有令牌时状态码 %d，期望401", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, MetricsPath, nil)
+	req.Header.Set("Authorization", "Bearer viewer-token")
+	rec = httptest.NewRecorder()
+	ws.handleMetrics(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("状态码 %d，期望200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type %q", ct)
+	}
+	parseExposition(t, rec.Body.String())
+}
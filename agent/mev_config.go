@@ -9,16 +9,17 @@ import (
 )
 
 // Config 代表MEV Bot的整体配置
+// 带有 secret:"true" 标签的字段在返回给客户端和写入日志时会被脱敏
 type Config struct {
 	Routing struct {
 		MintConfigList []MintConfig `toml:"mint_config_list"`
 	} `toml:"routing"`
 	RPC struct {
-		URL string `toml:"url"`
+		URL string `toml:"url" secret:"true"`
 	} `toml:"rpc"`
 	Spam struct {
 		Enabled          bool     `toml:"enabled"`
-		SendingRPCURLs   []string `toml:"sending_rpc_urls"`
+		SendingRPCURLs   []string `toml:"sending_rpc_urls" secret:"true"`
 		ComputeUnitPrice struct {
 			Strategy string `toml:"strategy"`
 			From     int    `toml:"from"`
@@ -31,7 +32,7 @@ type Config struct {
 	Jito struct {
 		Enabled         bool     `toml:"enabled"`
 		BlockEngineURLs []string `toml:"block_engine_urls"`
-		UUID            string   `toml:"uuid"`
+		UUID            string   `toml:"uuid" secret:"true"`
 		IPAddresses     []string `toml:"ip_addresses"`
 		TipConfig       struct {
 			Strategy string `toml:"strategy"`
@@ -48,6 +49,7 @@ type Config struct {
 		MergeMints       bool `toml:"merge_mints"`
 	} `toml:"bot"`
 	Wallet struct {
+		Private string `toml:"private" secret:"true"`
 	} `toml:"wallet"`
 }
 
@@ -68,7 +70,11 @@ func LoadConfig(configPath string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	return ParseConfig(data)
+}
 
+// ParseConfig 从TOML文本解析配置
+func ParseConfig(data []byte) (*Config, error) {
 	var config Config
 	if err := toml.Unmarshal(data, &config); err != nil {
 		return nil, err
@@ -85,7 +91,7 @@ func (c *Config) SaveToFile(configPath string) error {
 	}
 
 	// 写入文件
-	return os.WriteFile(configPath, data, 0644)
+	return writeFileAtomic(configPath, data, 0644)
 }
 
 // Copy 创建配置的深度副本
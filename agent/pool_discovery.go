@@ -0,0 +1,328 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 池发现提供方
+const (
+	PoolProviderSolscan = "solscan"
+	PoolProviderRPC     = "rpc"
+)
+
+// defaultSolscanPoolsURL Solscan内部的代币池列表接口
+const defaultSolscanPoolsURL = "https://api-v2.solscan.io/v2/token/pools"
+
+// DiscoveredPool 表示发现的一个池
+type DiscoveredPool struct {
+	Address   string   `json:"address"`
+	Kind      PoolKind `json:"kind"` // 未知程序的池为空
+	ProgramID string   `json:"program_id"`
+	MintA     string   `json:"mint_a"`
+	MintB     string   `json:"mint_b"`
+	VaultA    string   `json:"vault_a"`
+	VaultB    string   `json:"vault_b"`
+	Trades24h int64    `json:"trades_24h,omitempty"` // 提供方给出的24小时成交笔数，未知时为0
+	Volume24h float64  `json:"volume_24h,omitempty"` // 提供方给出的24小时交易量，未知时为0
+	HasStats  bool     `json:"-"`                    // 提供方是否给出了24小时统计
+
+	// 以下字段在排序时填充
+	LiquiditySOL float64 `json:"liquidity_sol,omitempty"` // 池中SOL一侧的储备
+	HasLiquidity bool    `json:"-"`                       // 是否成功读取了储备
+	FeeBps       float64 `json:"fee_bps,omitempty"`       // 交易费率，基点，未知时为0
+	Score        float64 `json:"score,omitempty"`         // 综合评分，0到1
+}
+
+// HasMint 判断池中是否包含指定代币
+func (p DiscoveredPool) HasMint(mint string) bool {
+	return strings.EqualFold(p.MintA, mint) || strings.EqualFold(p.MintB, mint)
+}
+
+// SOLVault 返回池中SOL一侧的金库地址，池不包含SOL时返回空字符串
+func (p DiscoveredPool) SOLVault() string {
+	switch {
+	case p.MintA == wrappedSOLMint:
+		return p.VaultA
+	case p.MintB == wrappedSOLMint:
+		return p.VaultB
+	}
+	return ""
+}
+
+// PoolDiscoverer 查找包含指定代币的池
+type PoolDiscoverer interface {
+	// Name 返回提供方名称，用于日志和指标
+	Name() string
+	// DiscoverPools 返回包含mint的池
+	DiscoverPools(mint string) ([]DiscoveredPool, error)
+}
+
+// PoolDiscoveryConfig 表示池发现的配置
+type PoolDiscoveryConfig struct {
+	Providers []string `yaml:"providers"` // 按顺序尝试的提供方（solscan、rpc），第一个成功的结果生效，默认只使用solscan
+	RPCURL    string   `yaml:"rpc_url"`   // rpc提供方使用的Solana RPC地址，为空时使用MEV Bot配置中的 rpc.url
+	Timeout   int      `yaml:"timeout"`   // 请求超时，秒
+}
+
+// NewPoolDiscoverers 根据配置创建池发现提供方
+// rpcURL 在每次请求时调用，以便使用MEV Bot配置中最新的RPC地址
+func NewPoolDiscoverers(agentConfig *FlashAgentConfig, rpcURL func() string) ([]PoolDiscoverer, error) {
+	config := agentConfig.HotTokenConfig.PoolDiscovery
+	endpoint := poolRPCEndpoint(config, rpcURL)
+	providers := config.Providers
+	if len(providers) == 0 {
+		providers = []string{PoolProviderSolscan}
+	}
+	client := &http.Client{Timeout: time.Duration(config.Timeout) * time.Second}
+
+	var discoverers []PoolDiscoverer
+	for i, provider := range providers {
+		switch provider {
+		case PoolProviderSolscan:
+			discoverers = append(discoverers, NewSolscanPoolDiscoverer(agentConfig.SolScan, "", client))
+		case PoolProviderRPC:
+			discoverers = append(discoverers, NewRPCPoolDiscoverer(endpoint, client))
+		default:
+			return nil, fmt.Errorf("hottoken.pool_discovery.providers[%d]: 未知的池发现提供方 %q", i, provider)
+		}
+	}
+	return discoverers, nil
+}
+
+// poolRPCEndpoint 返回池发现和储备查询使用的RPC地址，配置了 pool_discovery.rpc_url 时优先使用
+func poolRPCEndpoint(config PoolDiscoveryConfig, rpcURL func() string) func() string {
+	if config.RPCURL == "" {
+		return rpcURL
+	}
+	url := config.RPCURL
+	return func() string { return url }
+}
+
+// discoverPools 按顺序尝试各提供方，返回第一个成功的结果和提供方名称
+func discoverPools(discoverers []PoolDiscoverer, mint string, observe func(name string, elapsed time.Duration, err error)) ([]DiscoveredPool, string, error) {
+	var errs []string
+	for _, discoverer := range discoverers {
+		start := time.Now()
+		pools, err := discoverer.DiscoverPools(mint)
+		if observe != nil {
+			observe(discoverer.Name(), time.Since(start), err)
+		}
+		if err == nil {
+			return pools, discoverer.Name(), nil
+		}
+		errs = append(errs, discoverer.Name()+": "+err.Error())
+	}
+	return nil, "", errors.New("所有池发现提供方均失败: " + strings.Join(errs, "; "))
+}
+
+// SolscanPoolDiscoverer 通过Solscan内部接口查找池，需要浏览器的Cookie和认证头
+type SolscanPoolDiscoverer struct {
+	config SolScanConfig
+	url    string
+	client *http.Client
+}
+
+// NewSolscanPoolDiscoverer 创建Solscan池发现提供方，url为空时使用Solscan的地址，client为nil时使用默认客户端
+func NewSolscanPoolDiscoverer(config SolScanConfig, url string, client *http.Client) *SolscanPoolDiscoverer {
+	if url == "" {
+		url = defaultSolscanPoolsURL
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SolscanPoolDiscoverer{config: config, url: url, client: client}
+}
+
+// Name 返回提供方名称
+func (d *SolscanPoolDiscoverer) Name() string {
+	return PoolProviderSolscan
+}
+
+// DiscoverPools 查询Solscan上包含mint的池，按程序ID判断池类型
+func (d *SolscanPoolDiscoverer) DiscoverPools(mint string) ([]DiscoveredPool, error) {
+	url := fmt.Sprintf("%s?page=1&page_size=40&token[]=%s", d.url, mint)
+	headers := map[string]string{
+		"x-sol-auth":    d.config.SolAuth,
+		"authorization": d.config.Token,
+		"cookie":        d.config.Cookie,
+		"origin":        d.config.Origin,
+		"referer":       d.config.Referer,
+	}
+
+	var resp SolscanPoolResponse
+	if err := getJSON(d.client, url, headers, &resp); err != nil {
+		return nil, fmt.Errorf("Solscan: %w", err)
+	}
+	if !resp.Success {
+		return nil, fmt.Errorf("Solscan响应未成功")
+	}
+
+	pools := make([]DiscoveredPool, 0, len(resp.Data))
+	for _, item := range resp.Data {
+		pool := DiscoveredPool{
+			Address:   item.PoolID,
+			ProgramID: item.ProgramID,
+			Trades24h: item.TotalTrades24h,
+			Volume24h: float64(item.TotalVolume24h),
+			HasStats:  true,
+		}
+		if program, ok := poolProgramByID(item.ProgramID); ok {
+			pool.Kind = program.Kind
+		}
+		if len(item.TokensInfo) > 0 {
+			pool.MintA, pool.VaultA = item.TokensInfo[0].Token, item.TokensInfo[0].TokenAccount
+		}
+		if len(item.TokensInfo) > 1 {
+			pool.MintB, pool.VaultB = item.TokensInfo[1].Token, item.TokensInfo[1].TokenAccount
+		}
+		pools = append(pools, pool)
+	}
+	return pools, nil
+}
+
+// RPCPoolDiscoverer 通过Solana JSON-RPC getProgramAccounts 直接读取池账户
+// 对每个MEV Bot支持的程序，用memcmp过滤代币和SOL分别位于两侧的池
+type RPCPoolDiscoverer struct {
+	endpoint func() string
+	client   *http.Client
+}
+
+// NewRPCPoolDiscoverer 创建RPC池发现提供方，endpoint返回RPC地址，client为nil时使用默认客户端
+func NewRPCPoolDiscoverer(endpoint func() string, client *http.Client) *RPCPoolDiscoverer {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &RPCPoolDiscoverer{endpoint: endpoint, client: client}
+}
+
+// Name 返回提供方名称
+func (d *RPCPoolDiscoverer) Name() string {
+	return PoolProviderRPC
+}
+
+// DiscoverPools 查询所有支持的程序中代币与SOL组成的池
+func (d *RPCPoolDiscoverer) DiscoverPools(mint string) ([]DiscoveredPool, error) {
+	if !isValidPublicKey(mint) {
+		return nil, fmt.Errorf("无效的代币地址: %s", mint)
+	}
+	url := d.endpoint()
+	if url == "" {
+		return nil, fmt.Errorf("未配置RPC地址")
+	}
+
+	var pools []DiscoveredPool
+	for _, program := range poolPrograms {
+		if !program.Supported() {
+			continue // MEV Bot不支持的池类型无需查询
+		}
+		// 代币可能在池的任意一侧
+		for _, sides := range [][2]string{{mint, wrappedSOLMint}, {wrappedSOLMint, mint}} {
+			found, err := d.programAccounts(url, program, sides[0], sides[1])
+			if err != nil {
+				return nil, fmt.Errorf("查询 %s 池失败: %w", program.Kind, err)
+			}
+			pools = append(pools, found...)
+		}
+	}
+	return pools, nil
+}
+
+// rpcProgramAccountsResponse getProgramAccounts 的响应
+type rpcProgramAccountsResponse struct {
+	Result []struct {
+		Pubkey  string `json:"pubkey"`
+		Account struct {
+			Data []string `json:"data"` // [数据, 编码]
+		} `json:"account"`
+	} `json:"result"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// programAccounts 查询program中mintA和mintB分别位于两侧的池账户
+func (d *RPCPoolDiscoverer) programAccounts(url string, program poolProgram, mintA, mintB string) ([]DiscoveredPool, error) {
+	request := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "getProgramAccounts",
+		"params": []interface{}{
+			program.ProgramID,
+			map[string]interface{}{
+				"encoding":  "base64",
+				"dataSlice": map[string]int{"offset": 0, "length": program.Layout.dataEnd()},
+				"filters": []interface{}{
+					map[string]interface{}{"memcmp": map[string]interface{}{"offset": program.Layout.MintA, "bytes": mintA}},
+					map[string]interface{}{"memcmp": map[string]interface{}{"offset": program.Layout.MintB, "bytes": mintB}},
+				},
+			},
+		},
+	}
+
+	var result rpcProgramAccountsResponse
+	if err := rpcCall(d.client, url, request, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("RPC错误 %d: %s", result.Error.Code, result.Error.Message)
+	}
+
+	pools := make([]DiscoveredPool, 0, len(result.Result))
+	for _, account := range result.Result {
+		if len(account.Account.Data) == 0 {
+			return nil, fmt.Errorf("池账户 %s 没有数据", account.Pubkey)
+		}
+		raw, err := base64.StdEncoding.DecodeString(account.Account.Data[0])
+		if err != nil {
+			return nil, fmt.Errorf("解码池账户 %s 失败: %w", account.Pubkey, err)
+		}
+		if len(raw) < program.Layout.dataEnd() {
+			return nil, fmt.Errorf("池账户 %s 数据长度 %d 不足", account.Pubkey, len(raw))
+		}
+		pools = append(pools, DiscoveredPool{
+			Address:   account.Pubkey,
+			Kind:      program.Kind,
+			ProgramID: program.ProgramID,
+			MintA:     base58Encode(raw[program.Layout.MintA : program.Layout.MintA+32]),
+			MintB:     base58Encode(raw[program.Layout.MintB : program.Layout.MintB+32]),
+			VaultA:    base58Encode(raw[program.Layout.VaultA : program.Layout.VaultA+32]),
+			VaultB:    base58Encode(raw[program.Layout.VaultB : program.Layout.VaultB+32]),
+		})
+	}
+	return pools, nil
+}
+
+// rpcCall 发送一个JSON-RPC请求并把响应解析到result中，RPC层面的错误由调用方检查
+func rpcCall(client *http.Client, url string, request interface{}, result interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("请求RPC失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("RPC请求失败，状态码: %d", resp.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取RPC响应失败: %w", err)
+	}
+	if err := json.Unmarshal(data, result); err != nil {
+		return fmt.Errorf("解析RPC响应失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package agent
+
+// PoolKind 表示池所属的DEX类型
+type PoolKind string
+
+const (
+	PoolKindPump          PoolKind = "pump"           // Pump AMM，对应 pump_pool_list
+	PoolKindRaydium       PoolKind = "raydium"        // Raydium AMM v4，对应 raydium_pool_list
+	PoolKindRaydiumCP     PoolKind = "raydium_cp"     // Raydium CPMM，对应 raydium_cp_pool_list
+	PoolKindMeteoraDLMM   PoolKind = "meteora_dlmm"   // Meteora DLMM，对应 meteora_dlmm_pool_list
+	PoolKindRaydiumCLMM   PoolKind = "raydium_clmm"   // Raydium CLMM（集中流动性），MEV Bot不支持
+	PoolKindMeteoraDAMM   PoolKind = "meteora_damm"   // Meteora 动态AMM，MEV Bot不支持
+	PoolKindOrcaWhirlpool PoolKind = "orca_whirlpool" // Orca Whirlpool，MEV Bot不支持
+)
+
+// poolLayout 描述池账户数据中代币和金库地址的字节偏移量
+type poolLayout struct {
+	MintA  int
+	MintB  int
+	VaultA int
+	VaultB int
+}
+
+// dataEnd 返回解析池账户所需的数据长度
+func (l *poolLayout) dataEnd() int {
+	end := 0
+	for _, offset := range []int{l.MintA, l.MintB, l.VaultA, l.VaultB} {
+		if offset+32 > end {
+			end = offset + 32
+		}
+	}
+	return end
+}
+
+// poolProgram 描述一个已知的DEX程序
+type poolProgram struct {
+	Kind      PoolKind
+	Name      string
+	ProgramID string
+	Layout    *poolLayout // 池账户布局，只有MEV Bot支持的类型才需要
+	FeeBps    float64     // 默认交易费率，基点；0表示费率因池而异，排序时按未知处理
+}
+
+// Supported 判断MEV Bot是否支持该程序的池，即MintConfig中有对应的池列表
+func (p poolProgram) Supported() bool {
+	return p.Layout != nil
+}
+
+// poolPrograms 已知DEX程序的注册表，池的类型只按程序ID判断
+var poolPrograms = []poolProgram{
+	{Kind: PoolKindPump, Name: "Pump AMM", ProgramID: "pAMMBay6oceH9fJKBRHGP5D4bD4sWpmSwMn52FMfXEA",
+		Layout: &poolLayout{MintA: 43, MintB: 75, VaultA: 139, VaultB: 171}, FeeBps: 25},
+	{Kind: PoolKindRaydium, Name: "Raydium AMM v4", ProgramID: "675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8",
+		Layout: &poolLayout{MintA: 400, MintB: 432, VaultA: 336, VaultB: 368}, FeeBps: 25},
+	{Kind: PoolKindRaydiumCP, Name: "Raydium CPMM", ProgramID: "CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C",
+		Layout: &poolLayout{MintA: 168, MintB: 200, VaultA: 72, VaultB: 104}, FeeBps: 25},
+	{Kind: PoolKindMeteoraDLMM, Name: "Meteora DLMM", ProgramID: "LBUZKhRxPF3XUpBCjp4YzTKgLccjZhTSDM9YuVaPwxo",
+		Layout: &poolLayout{MintA: 88, MintB: 120, VaultA: 152, VaultB: 184}},
+	{Kind: PoolKindRaydiumCLMM, Name: "Raydium CLMM", ProgramID: "CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK"},
+	{Kind: PoolKindMeteoraDAMM, Name: "Meteora Dynamic AMM", ProgramID: "Eo7WjKq67rjJQSZxS6z3YkapzY3eMj6Xy8X5EQVn5UaB"},
+	{Kind: PoolKindOrcaWhirlpool, Name: "Orca Whirlpool", ProgramID: "whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc"},
+}
+
+// poolProgramByID 按程序ID查找DEX程序
+func poolProgramByID(programID string) (poolProgram, bool) {
+	for _, program := range poolPrograms {
+		if program.ProgramID == programID {
+			return program, true
+		}
+	}
+	return poolProgram{}, false
+}
@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// PoolReservesUpstream 储备查询在指标中的名称
+const PoolReservesUpstream = "pool_reserves"
+
+// rpcMaxAccounts getMultipleAccounts 单次请求的账户数上限
+const rpcMaxAccounts = 100
+
+// maxScoredFeeBps 费率评分的上限，费率不低于该值（1%）的池费率得分为0
+const maxScoredFeeBps = 100
+
+// PoolRankingConfig 表示热门代币池的评分和过滤配置
+type PoolRankingConfig struct {
+	MinLiquiditySOL float64              `yaml:"min_liquidity_sol"` // SOL一侧储备低于该值的池不写入配置，0表示不过滤；无法读取储备时不过滤
+	MinVolume24h    float64              `yaml:"min_volume_24h"`    // 24小时交易量下限（美元），只对提供方给出统计的池生效
+	MinTrades24h    int64                `yaml:"min_trades_24h"`    // 24小时成交笔数下限，只对提供方给出统计的池生效
+	Weights         PoolScoreWeights     `yaml:"weights"`           // 各项指标在评分中的权重
+	FeeBps          map[PoolKind]float64 `yaml:"fee_bps"`           // 按池类型覆盖默认费率，基点
+}
+
+// PoolScoreWeights 表示池评分中各项指标的权重，全部为0时使用默认权重
+type PoolScoreWeights struct {
+	Liquidity float64 `yaml:"liquidity"` // SOL一侧的储备
+	Volume    float64 `yaml:"volume"`    // 24小时交易量
+	Trades    float64 `yaml:"trades"`    // 24小时成交笔数
+	Fee       float64 `yaml:"fee"`       // 费率，越低得分越高
+}
+
+// rejectedPool 未通过阈值过滤的池及原因
+type rejectedPool struct {
+	Pool   DiscoveredPool
+	Reason string
+}
+
+// rankPools 为池评分并按评分从高到低排序，评分相同时按地址排序，未达到阈值的池单独返回
+// 储备、交易量和成交笔数按候选池中的最大值归一化；费率按 maxScoredFeeBps 线性计分，未知费率记0.5分
+func rankPools(pools []DiscoveredPool, config PoolRankingConfig) ([]DiscoveredPool, []rejectedPool) {
+	var ranked []DiscoveredPool
+	var rejected []rejectedPool
+	for _, pool := range pools {
+		pool.FeeBps = poolFeeBps(pool.Kind, config.FeeBps)
+		if reason := poolThresholdReason(pool, config); reason != "" {
+			rejected = append(rejected, rejectedPool{Pool: pool, Reason: reason})
+			continue
+		}
+		ranked = append(ranked, pool)
+	}
+
+	var maxLiquidity, maxVolume, maxTrades float64
+	for _, pool := range ranked {
+		maxLiquidity = maxFloat(maxLiquidity, pool.LiquiditySOL)
+		maxVolume = maxFloat(maxVolume, pool.Volume24h)
+		maxTrades = maxFloat(maxTrades, float64(pool.Trades24h))
+	}
+
+	weights := config.Weights
+	total := weights.Liquidity + weights.Volume + weights.Trades + weights.Fee
+	for i := range ranked {
+		pool := &ranked[i]
+		feeScore := 0.5
+		if pool.FeeBps > 0 {
+			feeScore = 1 - math.Min(pool.FeeBps, maxScoredFeeBps)/maxScoredFeeBps
+		}
+		score := weights.Liquidity*ratio(pool.LiquiditySOL, maxLiquidity) +
+			weights.Volume*ratio(pool.Volume24h, maxVolume) +
+			weights.Trades*ratio(float64(pool.Trades24h), maxTrades) +
+			weights.Fee*feeScore
+		if total > 0 {
+			pool.Score = score / total
+		}
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Address < ranked[j].Address
+	})
+	return ranked, rejected
+}
+
+// poolThresholdReason 检查池是否达到配置的阈值，返回未达到的原因
+func poolThresholdReason(pool DiscoveredPool, config PoolRankingConfig) string {
+	if pool.HasLiquidity && pool.LiquiditySOL < config.MinLiquiditySOL {
+		return fmt.Sprintf("SOL储备 %.2f 低于 %.2f", pool.LiquiditySOL, config.MinLiquiditySOL)
+	}
+	if pool.HasStats && pool.Volume24h < config.MinVolume24h {
+		return fmt.Sprintf("24小时交易量 $%.0f 低于 $%.0f", pool.Volume24h, config.MinVolume24h)
+	}
+	if pool.HasStats && pool.Trades24h < config.MinTrades24h {
+		return fmt.Sprintf("24小时成交 %d 笔，低于 %d 笔", pool.Trades24h, config.MinTrades24h)
+	}
+	return ""
+}
+
+// poolFeeBps 返回池类型的费率，配置中的覆盖值优先
+func poolFeeBps(kind PoolKind, overrides map[PoolKind]float64) float64 {
+	if fee, ok := overrides[kind]; ok {
+		return fee
+	}
+	for _, program := range poolPrograms {
+		if program.Kind == kind {
+			return program.FeeBps
+		}
+	}
+	return 0
+}
+
+// ratio 返回value相对max的比例，max为0时返回0
+func ratio(value, max float64) float64 {
+	if max <= 0 {
+		return 0
+	}
+	return value / max
+}
+
+// PoolReserveReader 通过Solana JSON-RPC读取池SOL一侧金库的余额
+type PoolReserveReader struct {
+	endpoint func() string
+	client   *http.Client
+}
+
+// NewPoolReserveReader 创建储备读取器，使用与池发现相同的RPC地址和超时
+func NewPoolReserveReader(agentConfig *FlashAgentConfig, rpcURL func() string) *PoolReserveReader {
+	config := agentConfig.HotTokenConfig.PoolDiscovery
+	return &PoolReserveReader{
+		endpoint: poolRPCEndpoint(config, rpcURL),
+		client:   &http.Client{Timeout: time.Duration(config.Timeout) * time.Second},
+	}
+}
+
+// LoadReserves 读取各池SOL一侧的储备，填充 LiquiditySOL 和 HasLiquidity
+// 读取失败时返回错误，池保持未知储备
+func (r *PoolReserveReader) LoadReserves(pools []DiscoveredPool) error {
+	url := r.endpoint()
+	if url == "" {
+		return fmt.Errorf("未配置RPC地址")
+	}
+
+	var vaults []string
+	for _, pool := range pools {
+		if vault := pool.SOLVault(); vault != "" && !containsString(vaults, vault) {
+			vaults = append(vaults, vault)
+		}
+	}
+
+	balances := make(map[string]float64, len(vaults))
+	for start := 0; start < len(vaults); start += rpcMaxAccounts {
+		end := start + rpcMaxAccounts
+		if end > len(vaults) {
+			end = len(vaults)
+		}
+		if err := r.tokenBalances(url, vaults[start:end], balances); err != nil {
+			return err
+		}
+	}
+
+	for i := range pools {
+		if balance, ok := balances[pools[i].SOLVault()]; ok {
+			pools[i].LiquiditySOL = balance
+			pools[i].HasLiquidity = true
+		}
+	}
+	return nil
+}
+
+// rpcTokenAccountsResponse jsonParsed编码的 getMultipleAccounts 响应
+type rpcTokenAccountsResponse struct {
+	Result struct {
+		Value []*struct {
+			Data json.RawMessage `json:"data"` // 无法解析的账户为 [数据, 编码]
+		} `json:"value"`
+	} `json:"result"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// rpcParsedTokenAccount jsonParsed编码的代币账户数据
+type rpcParsedTokenAccount struct {
+	Program string `json:"program"`
+	Parsed  struct {
+		Info struct {
+			TokenAmount struct {
+				Amount   string `json:"amount"`
+				Decimals int    `json:"decimals"`
+			} `json:"tokenAmount"`
+		} `json:"info"`
+	} `json:"parsed"`
+}
+
+// tokenBalances 查询一批代币账户的余额，按地址写入balances
+// 不存在的账户余额为0，不是代币账户的地址不写入
+func (r *PoolReserveReader) tokenBalances(url string, accounts []string, balances map[string]float64) error {
+	request := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "getMultipleAccounts",
+		"params": []interface{}{
+			accounts,
+			map[string]interface{}{"encoding": "jsonParsed"},
+		},
+	}
+
+	var result rpcTokenAccountsResponse
+	if err := rpcCall(r.client, url, request, &result); err != nil {
+		return err
+	}
+	if result.Error != nil {
+		return fmt.Errorf("RPC错误 %d: %s", result.Error.Code, result.Error.Message)
+	}
+	if len(result.Result.Value) != len(accounts) {
+		return fmt.Errorf("RPC返回 %d 个账户，请求了 %d 个", len(result.Result.Value), len(accounts))
+	}
+
+	for i, account := range result.Result.Value {
+		if account == nil {
+			balances[accounts[i]] = 0
+			continue
+		}
+		var data rpcParsedTokenAccount
+		if err := json.Unmarshal(account.Data, &data); err != nil ||
+			(data.Program != "spl-token" && data.Program != "spl-token-2022") {
+			continue
+		}
+		amount := data.Parsed.Info.TokenAmount
+		raw, err := strconv.ParseFloat(amount.Amount, 64)
+		if err != nil {
+			return fmt.Errorf("解析账户 %s 的余额 %q 失败: %w", accounts[i], amount.Amount, err)
+		}
+		balances[accounts[i]] = raw / math.Pow10(amount.Decimals)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+// testRankingWeights 与默认配置相同的评分权重
+var testRankingWeights = PoolScoreWeights{Liquidity: 0.5, Volume: 0.3, Trades: 0.1, Fee: 0.1}
+
+// poolAddresses 返回池的地址列表
+func poolAddresses(pools []DiscoveredPool) []string {
+	var addresses []string
+	for _, pool := range pools {
+		addresses = append(addresses, pool.Address)
+	}
+	return addresses
+}
+
+// rankingFixture 池样例：储备和统计分别为已知或未知
+var rankingFixture = []DiscoveredPool{
+	{Address: "zzUnknownReserves", Kind: PoolKindPump},
+	{Address: "lowLiquidity", Kind: PoolKindPump, LiquiditySOL: 5, HasLiquidity: true},
+	{Address: "lowVolume", Kind: PoolKindRaydium, Volume24h: 500, Trades24h: 100, HasStats: true},
+	{Address: "lowTrades", Kind: PoolKindRaydium, Volume24h: 5000, Trades24h: 10, HasStats: true},
+	{Address: "best", Kind: PoolKindPump, LiquiditySOL: 100, HasLiquidity: true, Volume24h: 10000, Trades24h: 200, HasStats: true},
+	{Address: "aaNoStats", Kind: PoolKindPump},
+}
+
+func TestRankPoolsThresholds(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   PoolRankingConfig
+		want     []string
+		rejected []string
+	}{
+		{
+			name:   "no thresholds",
+			config: PoolRankingConfig{Weights: testRankingWeights},
+			want:   []string{"best", "lowTrades", "lowVolume", "lowLiquidity", "aaNoStats", "zzUnknownReserves"},
+		},
+		{
+			name:     "min liquidity",
+			config:   PoolRankingConfig{MinLiquiditySOL: 10, Weights: testRankingWeights},
+			want:     []string{"best", "lowTrades", "lowVolume", "aaNoStats", "zzUnknownReserves"},
+			rejected: []string{"lowLiquidity"},
+		},
+		{
+			name:     "min volume",
+			config:   PoolRankingConfig{MinVolume24h: 1000, Weights: testRankingWeights},
+			want:     []string{"best", "lowTrades", "lowLiquidity", "aaNoStats", "zzUnknownReserves"},
+			rejected: []string{"lowVolume"},
+		},
+		{
+			name:     "min trades",
+			config:   PoolRankingConfig{MinTrades24h: 50, Weights: testRankingWeights},
+			want:     []string{"best", "lowVolume", "lowLiquidity", "aaNoStats", "zzUnknownReserves"},
+			rejected: []string{"lowTrades"},
+		},
+		{
+			name:     "all thresholds",
+			config:   PoolRankingConfig{MinLiquiditySOL: 10, MinVolume24h: 1000, MinTrades24h: 50, Weights: testRankingWeights},
+			want:     []string{"best", "aaNoStats", "zzUnknownReserves"},
+			rejected: []string{"lowLiquidity", "lowVolume", "lowTrades"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked, rejected := rankPools(rankingFixture, tt.config)
+			if got := poolAddresses(ranked); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("排序 %v，期望 %v", got, tt.want)
+			}
+			var gotRejected []string
+			for _, r := range rejected {
+				if r.Reason == "" {
+					t.Errorf("%s 没有过滤原因", r.Pool.Address)
+				}
+				gotRejected = append(gotRejected, r.Pool.Address)
+			}
+			if !reflect.DeepEqual(gotRejected, tt.rejected) {
+				t.Errorf("过滤 %v，期望 %v", gotRejected, tt.rejected)
+			}
+		})
+	}
+}
+
+func TestRankPoolsScores(t *testing.T) {
+	ranked, _ := rankPools(rankingFixture, PoolRankingConfig{Weights: testRankingWeights})
+	scores := make(map[string]float64)
+	for _, pool := range ranked {
+		scores[pool.Address] = pool.Score
+	}
+
+	// best在三项指标上都是最大值，Pump费率25基点的费率得分为0.75
+	want := map[string]float64{
+		"best":              0.5 + 0.3 + 0.1 + 0.1*0.75,
+		"lowTrades":         0.3*0.5 + 0.1*0.05 + 0.1*0.75,
+		"aaNoStats":         0.1 * 0.75,
+		"zzUnknownReserves": 0.1 * 0.75,
+	}
+	for address, score := range want {
+		if math.Abs(scores[address]-score) > 1e-9 {
+			t.Errorf("%s 评分 %.4f，期望 %.4f", address, scores[address], score)
+		}
+	}
+
+	// 覆盖费率后，未知费率记0.5分
+	ranked, _ = rankPools([]DiscoveredPool{{Address: "p", Kind: PoolKindPump}}, PoolRankingConfig{
+		Weights: PoolScoreWeights{Fee: 1},
+		FeeBps:  map[PoolKind]float64{PoolKindPump: 0},
+	})
+	if ranked[0].Score != 0.5 {
+		t.Errorf("未知费率评分 %.2f，期望 0.5", ranked[0].Score)
+	}
+}
+
+func TestRankPoolsTiesDeterministic(t *testing.T) {
+	pools := []DiscoveredPool{
+		{Address: "cccc", Kind: PoolKindRaydium, Volume24h: 100, HasStats: true},
+		{Address: "aaaa", Kind: PoolKindRaydium, Volume24h: 100, HasStats: true},
+		{Address: "dddd", Kind: PoolKindRaydiumCP, Volume24h: 100, HasStats: true},
+		{Address: "bbbb", Kind: PoolKindRaydium, Volume24h: 100, HasStats: true},
+		{Address: "eeee", Kind: PoolKindRaydium, Volume24h: 200, HasStats: true},
+	}
+	want := []string{"eeee", "aaaa", "bbbb", "cccc", "dddd"}
+
+	// 输入顺序不影响评分相同的池的顺序
+	for shift := 0; shift < len(pools); shift++ {
+		input := append(append([]DiscoveredPool(nil), pools[shift:]...), pools[:shift]...)
+		ranked, _ := rankPools(input, PoolRankingConfig{Weights: testRankingWeights})
+		if got := poolAddresses(ranked); !reflect.DeepEqual(got, want) {
+			t.Errorf("输入偏移 %d: 排序 %v，期望 %v", shift, got, want)
+		}
+	}
+}
@@ -1,22 +1,59 @@
 package agent
 
 import (
-	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"sync"
-	"syscall"
+	"time"
 )
 
+// 默认的优雅关闭等待时间
+const defaultStopTimeout = 15 * time.Second
+
+// 进程退出后继续读取剩余输出的最长时间，超过后关闭输出管道
+// 后台运行的子进程继承了输出管道时，管道不会关闭，不设上限会使进程永远不被视为已退出
+// （与Go 1.20的exec.Cmd.WaitDelay相同，go.mod要求兼容Go 1.18，因此自行实现）
+const outputWaitDelay = 2 * time.Second
+
+// ExitStatus 表示进程的退出状态
+type ExitStatus struct {
+	Code   int    `json:"code"`             // 退出码，被信号终止时为-1
+	Signal string `json:"signal,omitempty"` // 终止进程的信号
+}
+
+// String 返回退出状态的可读描述
+func (s *ExitStatus) String() string {
+	if s.Signal != "" {
+		return "信号 " + s.Signal
+	}
+	return fmt.Sprintf("退出码 %d", s.Code)
+}
+
 // ProcessManager 管理MEV Bot进程
 type ProcessManager struct {
 	name       string
 	executable string
 	args       []string // 新增: 命令行参数
+	dir        string   // 工作目录
+	env        []string // 环境变量，为空时继承代理的环境变量
 	cmd        *exec.Cmd
 	mutex      sync.RWMutex
 	isRunning  bool
+	logs       *LogBuffer // 进程输出的环形缓冲区
+	logOutput  io.Writer  // 进程输出的额外写入目标（控制台、日志文件）
+
+	stopTimeout time.Duration // 发送SIGTERM后等待退出的时间，超时后发送SIGKILL
+	done        chan struct{} // 当前进程退出时关闭
+	exitStatus  *ExitStatus   // 最近一次退出的状态
+
+	history      *RunHistory // 运行历史记录
+	historyLines int         // 每条运行记录保留的退出前日志行数
+	stopCause    StopCause   // 当前停止操作的原因，进程自行退出时为空
+	stopReason   string      // 当前停止操作的具体说明，记录到运行历史
+	startTime    time.Time   // 当前进程的启动时间
 }
 
 // NewProcessManager 创建新的进程管理器
@@ -26,7 +63,62 @@ func NewProcessManager(name, executable string, args ...string) *ProcessManager
 		executable: executable,
 		args:       args, // 保存参数
 		isRunning:  false,
+		logs:       NewLogBuffer(defaultLogBufferLines),
+		logOutput:  os.Stdout,
+
+		stopTimeout: defaultStopTimeout,
+	}
+}
+
+// SetStopTimeout 设置优雅关闭的等待时间
+func (p *ProcessManager) SetStopTimeout(timeout time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if timeout > 0 {
+		p.stopTimeout = timeout
+	}
+}
+
+// SetWorkDir 设置进程的工作目录
+func (p *ProcessManager) SetWorkDir(dir string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.dir = dir
+}
+
+// SetEnv 设置进程的环境变量
+func (p *ProcessManager) SetEnv(env []string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.env = env
+}
+
+// SetRunHistory 设置运行历史记录，lines为每条记录保留的退出前日志行数
+func (p *ProcessManager) SetRunHistory(history *RunHistory, lines int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.history = history
+	p.historyLines = lines
+}
+
+// SetLogOutput 设置进程输出的捕获缓冲区和额外写入目标
+func (p *ProcessManager) SetLogOutput(logs *LogBuffer, out io.Writer) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if logs != nil {
+		p.logs = logs
 	}
+	p.logOutput = out
+}
+
+// Logs 返回进程输出的环形缓冲区
+func (p *ProcessManager) Logs() *LogBuffer {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.logs
 }
 
 // Start 启动进程
@@ -38,34 +130,96 @@ func (p *ProcessManager) Start() error {
 		return nil
 	}
 
-	// 检查可执行文件是否存在
-	if _, err := os.Stat(p.executable); os.IsNotExist(err) {
-		return errors.New("找不到可执行文件: " + p.executable)
+	// 检查可执行文件是否存在，不带目录的名称在PATH中查找
+	if _, err := exec.LookPath(p.executable); err != nil {
+		return fmt.Errorf("找不到可执行文件 %s: %w", p.executable, err)
 	}
 
 	// 创建命令 - 使用参数
 	p.cmd = exec.Command(p.executable, p.args...)
+	p.cmd.Dir = p.dir
+	p.cmd.Env = p.env
+	// 在独立进程组中运行，停止时连同子进程一起终止
+	setProcessGroup(p.cmd)
 
-	// 设置标准输出和错误输出
-	p.cmd.Stdout = os.Stdout
-	p.cmd.Stderr = os.Stderr
+	// 按行捕获标准输出和错误输出
+	stdout := newLineWriter("stdout", p.logs, p.logOutput)
+	stderr := newLineWriter("stderr", p.logs, p.logOutput)
+	stdoutPipe, err := newOutputPipe()
+	if err != nil {
+		return fmt.Errorf("创建输出管道失败: %w", err)
+	}
+	stderrPipe, err := newOutputPipe()
+	if err != nil {
+		stdoutPipe.close()
+		return fmt.Errorf("创建输出管道失败: %w", err)
+	}
+	p.cmd.Stdout = stdoutPipe.w
+	p.cmd.Stderr = stderrPipe.w
 
 	// 启动进程
 	if err := p.cmd.Start(); err != nil {
+		stdoutPipe.close()
+		stderrPipe.close()
 		return err
 	}
+	stdoutPipe.copyTo(stdout)
+	stderrPipe.copyTo(stderr)
 
 	p.isRunning = true
-	log.Printf("%s进程已启动, PID: %d, 命令: %s %v", p.name, p.cmd.Process.Pid, p.executable, p.args)
+	p.exitStatus = nil
+	p.stopCause = ""
+	p.stopReason = ""
+	cmd := p.cmd
+	done := make(chan struct{})
+	p.done = done
+	startTime := time.Now()
+	p.startTime = startTime
+	startSeq := p.logs.LastSeq()
+	logs := p.logs
+	log.Printf("%s进程已启动, PID: %d, 命令: %s %v", p.name, cmd.Process.Pid, p.executable, p.args)
 
 	// 监控进程
 	go func() {
-		err := p.cmd.Wait()
+		err := cmd.Wait()
 
-		p.mutex.Lock()
-		defer p.mutex.Unlock()
+		// 读完剩余输出后写入最后不完整的一行
+		if !waitOutput(outputWaitDelay, stdoutPipe, stderrPipe) {
+			log.Printf("%s进程退出后输出管道在%v内未关闭（可能被后台子进程继承），停止读取", p.name, outputWaitDelay)
+		}
+		stdout.Flush()
+		stderr.Flush()
+
+		status := exitStatusOf(cmd.ProcessState)
 
+		p.mutex.Lock()
 		p.isRunning = false
+		p.exitStatus = status
+		cause, reason := p.stopCause, p.stopReason
+		history := p.history
+		historyLines := p.historyLines
+		p.mutex.Unlock()
+
+		// 记录本次运行
+		if history != nil {
+			if cause == "" {
+				cause = StopCauseExited
+			}
+			record := RunRecord{
+				PID:       cmd.Process.Pid,
+				StartTime: startTime,
+				StopTime:  time.Now(),
+				StopCause: cause,
+				Reason:    reason,
+				LastLines: logs.Since(startSeq, historyLines),
+			}
+			if status != nil {
+				record.ExitCode = status.Code
+				record.Signal = status.Signal
+			}
+			history.Add(record)
+		}
+		close(done)
 
 		if err != nil {
 			log.Printf("%s进程已退出: %v", p.name, err)
@@ -77,27 +231,90 @@ func (p *ProcessManager) Start() error {
 	return nil
 }
 
-// Stop 停止进程
-func (p *ProcessManager) Stop() error {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
+// Stop 停止进程并等待其退出
+// 先向进程组发送SIGTERM，超过优雅关闭等待时间后发送SIGKILL，返回进程的退出状态
+// cause会记录到本次运行的历史记录中
+func (p *ProcessManager) Stop(cause StopCause) (*ExitStatus, error) {
+	return p.StopWithReason(cause, "")
+}
 
+// StopWithReason 与Stop相同，reason作为停止的具体原因一起记录到运行历史中
+func (p *ProcessManager) StopWithReason(cause StopCause, reason string) (*ExitStatus, error) {
+	p.mutex.Lock()
 	if !p.isRunning || p.cmd == nil || p.cmd.Process == nil {
-		return nil
+		status := p.exitStatus
+		p.mutex.Unlock()
+		return status, nil
 	}
+	p.stopCause = cause
+	p.stopReason = reason
+	process := p.cmd.Process
+	done := p.done
+	timeout := p.stopTimeout
+	p.mutex.Unlock()
 
 	// 尝试优雅关闭
-	log.Printf("正在优雅关闭%s进程 (PID: %d)...", p.name, p.cmd.Process.Pid)
-	if err := p.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+	log.Printf("正在优雅关闭%s进程 (PID: %d)...", p.name, process.Pid)
+	if err := terminateProcess(process); err != nil {
 		log.Printf("发送SIGTERM信号失败: %v, 尝试强制终止", err)
-		// 强制终止
-		if err := p.cmd.Process.Kill(); err != nil {
-			return err
+	} else {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+			return p.ExitStatus(), nil
+		case <-timer.C:
+			log.Printf("%s进程在%v内未退出, 发送SIGKILL", p.name, timeout)
 		}
 	}
 
-	// 进程状态将在监控例程中更新
-	return nil
+	// 强制终止
+	if err := killProcess(process); err != nil {
+		select {
+		case <-done:
+			// 进程已在此期间自行退出
+			return p.ExitStatus(), nil
+		default:
+			return nil, err
+		}
+	}
+
+	select {
+	case <-done:
+		return p.ExitStatus(), nil
+	case <-time.After(5 * time.Second):
+		return nil, fmt.Errorf("%s进程 (PID: %d) 在SIGKILL后仍未退出", p.name, process.Pid)
+	}
+}
+
+// ExitStatus 返回最近一次退出的状态，进程运行中或从未运行时返回nil
+func (p *ProcessManager) ExitStatus() *ExitStatus {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.exitStatus
+}
+
+// PID 返回当前进程的PID，进程未运行时返回0
+func (p *ProcessManager) PID() int {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if !p.isRunning || p.cmd == nil || p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+// StartTime 返回当前进程的启动时间，进程未运行时返回零值
+func (p *ProcessManager) StartTime() time.Time {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	if !p.isRunning {
+		return time.Time{}
+	}
+	return p.startTime
 }
 
 // IsRunning 检查进程是否在运行
@@ -106,3 +323,59 @@ func (p *ProcessManager) IsRunning() bool {
 	defer p.mutex.RUnlock()
 	return p.isRunning
 }
+
+// outputPipe 子进程的一路输出，读端在代理中复制到lineWriter
+type outputPipe struct {
+	r, w *os.File
+	done chan struct{} // 读到EOF或读端被关闭后关闭
+}
+
+// newOutputPipe 创建输出管道
+func newOutputPipe() (*outputPipe, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	return &outputPipe{r: r, w: w, done: make(chan struct{})}, nil
+}
+
+// copyTo 关闭代理持有的写端，并在后台将输出复制到dst
+// 写端只由子进程（及其子进程）持有，全部退出后读端读到EOF
+func (o *outputPipe) copyTo(dst io.Writer) {
+	o.w.Close()
+	go func() {
+		defer close(o.done)
+		io.Copy(dst, o.r)
+	}()
+}
+
+// close 关闭管道的两端，用于进程启动失败时
+func (o *outputPipe) close() {
+	o.r.Close()
+	o.w.Close()
+}
+
+// waitOutput 等待所有输出管道读完，超过delay后关闭读端停止读取
+// 输出在delay内读完时返回true
+func waitOutput(delay time.Duration, pipes ...*outputPipe) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	drained := true
+	for _, pipe := range pipes {
+		if drained {
+			select {
+			case <-pipe.done:
+			case <-timer.C:
+				drained = false
+			}
+		}
+		if !drained {
+			// 关闭读端使阻塞的读取返回
+			pipe.r.Close()
+			<-pipe.done
+		}
+		pipe.r.Close()
+	}
+	return drained
+}
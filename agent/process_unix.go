@@ -0,0 +1,47 @@
+//go:build !windows
+
+package agent
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让子进程在独立的进程组中运行
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// terminateProcess 向进程所在的进程组发送SIGTERM
+func terminateProcess(process *os.Process) error {
+	return signalProcessGroup(process, syscall.SIGTERM)
+}
+
+// killProcess 向进程所在的进程组发送SIGKILL
+func killProcess(process *os.Process) error {
+	return signalProcessGroup(process, syscall.SIGKILL)
+}
+
+// signalProcessGroup 向整个进程组发送信号，进程组不存在时退回到只发送给进程本身
+func signalProcessGroup(process *os.Process, sig syscall.Signal) error {
+	err := syscall.Kill(-process.Pid, sig)
+	if err == nil || !errors.Is(err, syscall.ESRCH) {
+		return err
+	}
+	return process.Signal(sig)
+}
+
+// exitStatusOf 从进程状态中解析退出码和终止信号
+func exitStatusOf(state *os.ProcessState) *ExitStatus {
+	if state == nil {
+		return nil
+	}
+
+	status := &ExitStatus{Code: state.ExitCode()}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		status.Signal = ws.Signal().String()
+	}
+	return status
+}
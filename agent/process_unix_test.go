@@ -0,0 +1,175 @@
+//go:build !windows
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// 与 mock_smb.go 一样模拟MEV Bot的辅助进程：测试二进制以 AGENT_HELPER_PROCESS=1 重新执行自身，
+// 按最后一个参数选择行为
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("AGENT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	switch os.Args[len(os.Args)-1] {
+	case "ignore-term":
+		// 忽略SIGTERM，只能被SIGKILL终止
+		signal.Ignore(syscall.SIGTERM)
+		fmt.Println("ready")
+		time.Sleep(time.Minute)
+	case "spawn":
+		// 启动同一进程组中的子进程后等待
+		child := helperCommand("sleep")
+		child.Stdout = os.Stdout
+		if err := child.Start(); err != nil {
+			fmt.Println("error", err)
+			os.Exit(1)
+		}
+		fmt.Println("child", child.Process.Pid)
+		time.Sleep(time.Minute)
+	case "detach":
+		// 启动继承了标准输出的后台子进程（独立会话，不在进程组中），然后立即退出
+		child := helperCommand("sleep")
+		child.Stdout = os.Stdout
+		child.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+		if err := child.Start(); err != nil {
+			fmt.Println("error", err)
+			os.Exit(1)
+		}
+		fmt.Println("child", child.Process.Pid)
+	case "sleep":
+		time.Sleep(time.Minute)
+	}
+}
+
+// helperCommand 返回以指定行为运行辅助进程的命令
+func helperCommand(mode string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], helperArgs(mode)...)
+	cmd.Env = append(os.Environ(), "AGENT_HELPER_PROCESS=1")
+	return cmd
+}
+
+// helperArgs 返回运行辅助进程的命令行参数
+func helperArgs(mode string) []string {
+	return []string{"-test.run=^TestHelperProcess$", "--", mode}
+}
+
+// newHelperProcess 创建运行辅助进程的进程管理器
+func newHelperProcess(t *testing.T, mode string) *ProcessManager {
+	t.Helper()
+	p := NewProcessManager("helper", os.Args[0], helperArgs(mode)...)
+	p.SetEnv(append(os.Environ(), "AGENT_HELPER_PROCESS=1"))
+	p.SetLogOutput(nil, nil)
+	if err := p.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { p.Stop(StopCauseManual) })
+	return p
+}
+
+// waitForLine 等待进程输出以prefix开头的一行，返回该行
+func waitForLine(t *testing.T, p *ProcessManager, prefix string) string {
+	t.Helper()
+	var line string
+	waitFor(t, 10*time.Second, "输出 "+prefix, func() bool {
+		for _, l := range p.Logs().Tail(100) {
+			if strings.HasPrefix(l.Text, prefix) {
+				line = l.Text
+				return true
+			}
+		}
+		return false
+	})
+	return line
+}
+
+// childPID 等待辅助进程输出子进程的PID
+func childPID(t *testing.T, p *ProcessManager) int {
+	t.Helper()
+	pid, err := strconv.Atoi(strings.TrimPrefix(waitForLine(t, p, "child "), "child "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pid
+}
+
+// processAlive 判断进程是否仍在运行，已退出但未被回收的僵尸进程视为已退出
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); errors.Is(err, syscall.ESRCH) {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+	// 格式为 "pid (comm) state ..."
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
+
+// 忽略SIGTERM的进程在优雅关闭超时后被SIGKILL终止
+func TestProcessStopEscalatesToSIGKILL(t *testing.T) {
+	p := newHelperProcess(t, "ignore-term")
+	p.SetStopTimeout(300 * time.Millisecond)
+	waitForLine(t, p, "ready")
+
+	start := time.Now()
+	status, err := p.Stop(StopCauseManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == nil || status.Signal != syscall.SIGKILL.String() {
+		t.Fatalf("退出状态 %+v，期望被SIGKILL终止", status)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("在优雅关闭等待时间之前发送了SIGKILL: %v", elapsed)
+	}
+	if p.IsRunning() {
+		t.Error("进程仍被视为运行中")
+	}
+}
+
+// 停止时向整个进程组发送信号，MEV Bot启动的子进程一起退出
+func TestProcessStopKillsProcessGroup(t *testing.T) {
+	p := newHelperProcess(t, "spawn")
+	child := childPID(t, p)
+	if !processAlive(child) {
+		t.Fatal("子进程没有运行")
+	}
+
+	status, err := p.Stop(StopCauseManual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status == nil || status.Signal != syscall.SIGTERM.String() {
+		t.Errorf("退出状态 %+v，期望被SIGTERM终止", status)
+	}
+	waitFor(t, 5*time.Second, "子进程退出", func() bool { return !processAlive(child) })
+}
+
+// 后台子进程继承了输出管道时，主进程退出后仍在有限时间内被视为已退出
+func TestProcessExitWithInheritedOutput(t *testing.T) {
+	p := newHelperProcess(t, "detach")
+	child := childPID(t, p)
+	t.Cleanup(func() { syscall.Kill(child, syscall.SIGKILL) })
+
+	waitFor(t, outputWaitDelay+5*time.Second, "进程被视为已退出", func() bool { return !p.IsRunning() })
+	if status := p.ExitStatus(); status == nil || status.Code != 0 {
+		t.Errorf("退出状态 %+v，期望退出码0", status)
+	}
+	if !processAlive(child) {
+		t.Error("后台子进程应仍在运行")
+	}
+}
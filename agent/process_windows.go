@@ -0,0 +1,29 @@
+//go:build windows
+
+package agent
+
+import (
+	"os"
+	"os/exec"
+)
+
+// setProcessGroup Windows下不使用进程组
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminateProcess Windows不支持SIGTERM，直接终止进程
+func terminateProcess(process *os.Process) error {
+	return process.Kill()
+}
+
+// killProcess 强制终止进程
+func killProcess(process *os.Process) error {
+	return process.Kill()
+}
+
+// exitStatusOf 从进程状态中解析退出码
+func exitStatusOf(state *os.ProcessState) *ExitStatus {
+	if state == nil {
+		return nil
+	}
+	return &ExitStatus{Code: state.ExitCode()}
+}
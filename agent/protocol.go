@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ProtocolVersion WebSocket命令协议版本，响应格式或命令语义不兼容地变化时递增
+const ProtocolVersion = 1
+
+// 错误码，客户端根据错误码而不是错误信息判断失败原因
+const (
+	ErrCodeBadRequest      = "bad_request"       // 命令格式错误或参数无法解析
+	ErrCodeUnknownCommand  = "unknown_command"   // 未知的命令类型或动作
+	ErrCodeUnauthorized    = "unauthorized"      // 未提供有效的令牌（HTTP接口）
+	ErrCodeForbidden       = "forbidden"         // 令牌的角色无权执行该命令
+	ErrCodeValidation      = "validation_failed" // 配置校验失败，details为逐字段错误
+	ErrCodeNotFound        = "not_found"         // 请求的资源不存在
+	ErrCodeBusy            = "busy"              // MEV Bot正在启停或更新配置，请稍后重试
+	ErrCodeOperationFailed = "operation_failed"  // 命令执行失败
+)
+
+// Response 命令的响应，id与请求中的id相同
+type Response struct {
+	Type     string      `json:"type"` // 固定为 "response"
+	ID       string      `json:"id,omitempty"`
+	Command  string      `json:"command"` // 命令名，如 config/get
+	Action   string      `json:"action"`
+	OK       bool        `json:"ok"`
+	Message  string      `json:"message,omitempty"`
+	Data     interface{} `json:"data,omitempty"`
+	Revision int64       `json:"revision,omitempty"` // 配置类命令成功后的当前配置版本
+	Error    *ErrorInfo  `json:"error,omitempty"`
+}
+
+// ErrorInfo 描述命令失败的原因
+type ErrorInfo struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// Error 实现error接口
+func (e *ErrorInfo) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Event 服务端主动推送的消息，没有id
+type Event struct {
+	Type    string      `json:"type"` // system、notification 或 log
+	Message string      `json:"message,omitempty"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// HelloInfo hello握手的响应数据
+type HelloInfo struct {
+	ProtocolVersion int      `json:"protocol_version"`
+	Server          string   `json:"server"`
+	Role            Role     `json:"role"`     // 当前连接的角色
+	Commands        []string `json:"commands"` // 当前角色可以执行的命令列表，如 config/get
+}
+
+// ErrRevisionNotFound 请求的配置版本不存在
+var ErrRevisionNotFound = errors.New("配置版本不存在")
+
+// commandError 带错误码的命令错误
+type commandError struct {
+	code    string
+	message string
+}
+
+// Error 实现error接口
+func (e *commandError) Error() string {
+	return e.message
+}
+
+// newCommandError 创建带错误码的命令错误
+func newCommandError(code, format string, args ...interface{}) error {
+	return &commandError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// errorInfoOf 将处理程序返回的错误转换为带错误码的ErrorInfo
+func errorInfoOf(err error) *ErrorInfo {
+	var cmdErr *commandError
+	var validationErrs ValidationErrors
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &cmdErr):
+		return &ErrorInfo{Code: cmdErr.code, Message: cmdErr.message}
+	case errors.As(err, &validationErrs):
+		return &ErrorInfo{Code: ErrCodeValidation, Message: "配置校验失败", Details: validationErrs}
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return &ErrorInfo{Code: ErrCodeBadRequest, Message: "参数格式错误: " + err.Error()}
+	case errors.Is(err, ErrRevisionNotFound):
+		return &ErrorInfo{Code: ErrCodeNotFound, Message: err.Error()}
+	default:
+		return &ErrorInfo{Code: ErrCodeOperationFailed, Message: err.Error()}
+	}
+}
@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// RedactedPlaceholder 敏感字段在响应中被替换成的占位符
+// 客户端原样发回占位符时，保存配置会保留原有的值
+const RedactedPlaceholder = "********"
+
+// secretPaths 带有 secret:"true" 标签的字段的TOML路径，如 rpc.url
+var secretPaths = collectSecretPaths(reflect.TypeOf(Config{}), "")
+
+// collectSecretPaths 递归收集结构体中敏感字段的TOML路径
+func collectSecretPaths(t reflect.Type, prefix string) map[string]bool {
+	paths := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		path := tomlFieldName(field)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		if field.Tag.Get("secret") == "true" {
+			paths[path] = true
+		} else if field.Type.Kind() == reflect.Struct {
+			for p := range collectSecretPaths(field.Type, path) {
+				paths[p] = true
+			}
+		}
+	}
+	return paths
+}
+
+// IsSecretPath 判断TOML路径是否为敏感字段或位于敏感字段之下
+func IsSecretPath(path string) bool {
+	for p := range secretPaths {
+		if path == p || strings.HasPrefix(path, p+".") || strings.HasPrefix(path, p+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactConfig 返回配置的副本，其中非空的敏感字段被替换为占位符
+func RedactConfig(config *Config) *Config {
+	redacted := config.Copy()
+	walkSecrets(reflect.ValueOf(redacted).Elem(), reflect.Value{}, "", func(_ string, dst, _ reflect.Value) {
+		for _, item := range secretStrings(dst) {
+			if item.String() != "" {
+				item.SetString(RedactedPlaceholder)
+			}
+		}
+	})
+	return redacted
+}
+
+// RestoreSecrets 将updated中值为占位符的敏感字段恢复为current中的值
+// 数组中的占位符无法判断对应current中的哪个元素，因此数组必须全部是占位符且长度与current相同
+// （整体保持不变），或者不含占位符（整体替换）；删除、重排后再发回占位符会返回校验错误，
+// 而不是按下标恢复成另一个值
+func RestoreSecrets(updated, current *Config) error {
+	var errs ValidationErrors
+	walkSecrets(reflect.ValueOf(updated).Elem(), reflect.ValueOf(current).Elem(), "", func(path string, dst, src reflect.Value) {
+		if dst.Kind() == reflect.String {
+			if dst.String() == RedactedPlaceholder {
+				dst.SetString(src.String())
+			}
+			return
+		}
+
+		placeholders := 0
+		for j := 0; j < dst.Len(); j++ {
+			if dst.Index(j).String() == RedactedPlaceholder {
+				placeholders++
+			}
+		}
+		if placeholders == 0 {
+			return
+		}
+		if placeholders != dst.Len() || dst.Len() != src.Len() {
+			errs = append(errs, FieldError{Field: path, Message: fmt.Sprintf(
+				"数组包含占位符 %s 但与当前配置的%d个元素不能一一对应；修改、删除或重排元素时请提供全部完整的值", RedactedPlaceholder, src.Len())})
+			return
+		}
+		dst.Set(reflect.AppendSlice(reflect.MakeSlice(src.Type(), 0, src.Len()), src))
+	})
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// KeepSecrets 将updated中的全部敏感字段设置为current中的值
+// 配置历史中的版本已脱敏，无法区分空值和被替换的值，回滚时敏感字段一律保持当前配置
+func KeepSecrets(updated, current *Config) {
+	walkSecrets(reflect.ValueOf(updated).Elem(), reflect.ValueOf(current).Elem(), "", func(_ string, dst, src reflect.Value) {
+		if dst.Kind() == reflect.Slice && !src.IsNil() {
+			dst.Set(reflect.AppendSlice(reflect.MakeSlice(src.Type(), 0, src.Len()), src))
+			return
+		}
+		dst.Set(src)
+	})
+}
+
+// RedactChanges 将差异列表中敏感字段的新旧值替换为占位符
+func RedactChanges(changes []FieldChange) []FieldChange {
+	result := make([]FieldChange, len(changes))
+	for i, change := range changes {
+		if IsSecretPath(change.Path) {
+			change.Old = redactValue(change.Old)
+			change.New = redactValue(change.New)
+		}
+		result[i] = change
+	}
+	return result
+}
+
+// redactValue 替换单个值中的非空字符串
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			return RedactedPlaceholder
+		}
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = redactValue(item)
+		}
+		return result
+	}
+	return value
+}
+
+// walkSecrets 遍历dst中所有带有 secret:"true" 标签的字段（字符串或字符串数组），
+// 对每个字段调用fn；path为字段的TOML路径，src为对照结构体中相同位置的值
+func walkSecrets(dst, src reflect.Value, prefix string, fn func(path string, dst, src reflect.Value)) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		d := dst.Field(i)
+		s := reflect.Zero(field.Type)
+		if src.IsValid() {
+			s = src.Field(i)
+		}
+		path := tomlFieldName(field)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+
+		if field.Tag.Get("secret") != "true" {
+			if d.Kind() == reflect.Struct {
+				walkSecrets(d, s, path, fn)
+			}
+			continue
+		}
+		if d.Kind() == reflect.String || (d.Kind() == reflect.Slice && d.Type().Elem().Kind() == reflect.String) {
+			fn(path, d, s)
+		}
+	}
+}
+
+// secretStrings 返回敏感字段中的全部字符串值：字符串字段本身或字符串数组的每个元素
+func secretStrings(v reflect.Value) []reflect.Value {
+	if v.Kind() == reflect.String {
+		return []reflect.Value{v}
+	}
+	items := make([]reflect.Value, v.Len())
+	for j := range items {
+		items[j] = v.Index(j)
+	}
+	return items
+}
+
+// tomlFieldName 返回结构体字段的TOML键名，没有toml标签时为字段名
+func tomlFieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("toml"), ",")[0]; name != "" {
+		return name
+	}
+	return field.Name
+}
@@ -0,0 +1,129 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+// secretTestConfig 返回敏感字段都有值的配置
+func secretTestConfig(t *testing.T) *Config {
+	t.Helper()
+	config := loadTemplateConfig(t)
+	config.RPC.URL = "https://rpc.example.com/?api-key=k1"
+	config.Spam.SendingRPCURLs = []string{"https://a.example.com/k2", "https://b.example.com/k3", "https://c.example.com/k4"}
+	config.Jito.UUID = "jito-uuid"
+	config.Wallet.Private = "wallet-private"
+	return config
+}
+
+func TestRedactConfig(t *testing.T) {
+	config := secretTestConfig(t)
+	config.Jito.UUID = ""
+	redacted := RedactConfig(config)
+
+	if redacted.RPC.URL != RedactedPlaceholder || redacted.Wallet.Private != RedactedPlaceholder {
+		t.Errorf("敏感字段未脱敏: %q %q", redacted.RPC.URL, redacted.Wallet.Private)
+	}
+	if redacted.Jito.UUID != "" {
+		t.Errorf("空的敏感字段应保持为空，实际 %q", redacted.Jito.UUID)
+	}
+	want := []string{RedactedPlaceholder, RedactedPlaceholder, RedactedPlaceholder}
+	if !reflect.DeepEqual(redacted.Spam.SendingRPCURLs, want) {
+		t.Errorf("数组未脱敏: %v", redacted.Spam.SendingRPCURLs)
+	}
+	if config.Wallet.Private != "wallet-private" || config.Spam.SendingRPCURLs[0] != "https://a.example.com/k2" {
+		t.Error("RedactConfig修改了原配置")
+	}
+	if redacted.Bot.ComputeUnitLimit != config.Bot.ComputeUnitLimit {
+		t.Error("非敏感字段被修改")
+	}
+}
+
+func TestRestoreSecretsRoundTrip(t *testing.T) {
+	current := secretTestConfig(t)
+	updated := RedactConfig(current)
+	updated.Bot.ComputeUnitLimit++
+
+	if err := RestoreSecrets(updated, current); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := DiffConfigs(current, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "bot.compute_unit_limit" {
+		t.Errorf("原样发回脱敏配置后只应有一处修改，实际 %+v", changes)
+	}
+
+	// 修改restore后的配置不能影响current
+	updated.Spam.SendingRPCURLs[0] = "https://changed"
+	if current.Spam.SendingRPCURLs[0] != "https://a.example.com/k2" {
+		t.Error("恢复的数组与current共用底层存储")
+	}
+}
+
+func TestRestoreSecretsArrays(t *testing.T) {
+	p := RedactedPlaceholder
+	tests := []struct {
+		name    string
+		urls    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "unchanged", urls: []string{p, p, p},
+			want: []string{"https://a.example.com/k2", "https://b.example.com/k3", "https://c.example.com/k4"}},
+		{name: "replaced", urls: []string{"https://new"}, want: []string{"https://new"}},
+		{name: "cleared", urls: []string{}, want: []string{}},
+		{name: "deleted one", urls: []string{p, p}, wantErr: true},
+		{name: "appended", urls: []string{p, p, p, "https://new"}, wantErr: true},
+		{name: "edited one", urls: []string{"https://new", p, p}, wantErr: true},
+		{name: "reordered with new", urls: []string{p, "https://new", p}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current := secretTestConfig(t)
+			updated := RedactConfig(current)
+			updated.Spam.SendingRPCURLs = tt.urls
+
+			err := RestoreSecrets(updated, current)
+			if tt.wantErr {
+				fields := validationFields(t, err)
+				if !reflect.DeepEqual(fields, []string{"spam.sending_rpc_urls"}) {
+					t.Errorf("错误字段 %v", fields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(updated.Spam.SendingRPCURLs, tt.want) {
+				t.Errorf("恢复结果 %v，期望 %v", updated.Spam.SendingRPCURLs, tt.want)
+			}
+			if updated.Wallet.Private != "wallet-private" {
+				t.Errorf("字符串字段未恢复: %q", updated.Wallet.Private)
+			}
+		})
+	}
+}
+
+// 通过ConfigStore保存时，无法对应的占位符返回校验错误，配置文件不变
+func TestConfigStoreRejectsAmbiguousPlaceholders(t *testing.T) {
+	agent := newTestAgent(t)
+	store := agent.store
+	current := store.Current()
+	current.Spam.SendingRPCURLs = []string{"https://a.example.com/k2", "https://b.example.com/k3"}
+	if _, err := store.Save(current, ChangeInfo{Author: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	revision := store.Revision()
+
+	updated := RedactConfig(store.Current())
+	updated.Spam.SendingRPCURLs = updated.Spam.SendingRPCURLs[1:]
+	_, err := store.Save(updated, ChangeInfo{Author: "test"})
+	if fields := validationFields(t, err); !reflect.DeepEqual(fields, []string{"spam.sending_rpc_urls"}) {
+		t.Errorf("错误字段 %v", fields)
+	}
+	if store.Revision() != revision || len(store.Current().Spam.SendingRPCURLs) != 2 {
+		t.Error("校验失败后配置被修改")
+	}
+}
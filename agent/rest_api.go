@@ -0,0 +1,175 @@
+package agent
+
+import (
+	_ "embed"
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// OpenAPIPath REST接口OpenAPI文档的路径
+const OpenAPIPath = "/api/openapi.json"
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// registerRoutes 在mux上注册REST接口
+// 每个接口都被转换为对应的WebSocket命令，共用处理程序、认证、权限检查、校验和审计
+func (ws *WebSocketServer) registerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(OpenAPIPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPIDocument)
+	})
+	mux.HandleFunc("/api/", ws.handleAPI)
+	mux.HandleFunc(MetricsPath, ws.handleMetrics)
+	mux.HandleFunc(HealthzPath, ws.handleHealthz)
+	mux.HandleFunc(ReadyzPath, ws.handleReadyz)
+}
+
+// handleAPI 处理REST请求
+// 带有不允许的Origin头的请求返回403，防止浏览器中的其他页面跨站调用接口；
+// 带请求体的请求必须使用 application/json，使跨站的简单请求无法提交命令
+func (ws *WebSocketServer) handleAPI(w http.ResponseWriter, r *http.Request) {
+	if !ws.checkOrigin(r) {
+		log.Printf("HTTP接口拒绝来源 %s (%s)", r.Header.Get("Origin"), r.RemoteAddr)
+		writeAPIResponse(w, &Response{
+			Type:  "response",
+			Error: &ErrorInfo{Code: ErrCodeForbidden, Message: "不允许的来源: " + r.Header.Get("Origin")},
+		})
+		return
+	}
+
+	principal, ok := ws.auth.Authenticate(r)
+	if !ok {
+		log.Printf("HTTP接口验证失败: 无效的token (%s)", r.RemoteAddr)
+		writeAPIResponse(w, &Response{
+			Type:  "response",
+			Error: &ErrorInfo{Code: ErrCodeUnauthorized, Message: "未提供有效的令牌"},
+		})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		writeAPIResponse(w, &Response{
+			Type:  "response",
+			Error: &ErrorInfo{Code: ErrCodeBadRequest, Message: "读取请求体失败: " + err.Error()},
+		})
+		return
+	}
+	if len(body) > 0 && !isJSONContentType(r.Header.Get("Content-Type")) {
+		writeAPIResponse(w, &Response{
+			Type:  "response",
+			Error: &ErrorInfo{Code: ErrCodeBadRequest, Message: "请求体必须使用 Content-Type: application/json"},
+		})
+		return
+	}
+
+	cmd := restCommand(r.Method, r.URL.Path, body)
+	if cmd == nil {
+		writeAPIResponse(w, &Response{
+			Type:  "response",
+			Error: &ErrorInfo{Code: ErrCodeUnknownCommand, Message: "未知接口: " + r.Method + " " + r.URL.Path},
+		})
+		return
+	}
+	cmd.ID = r.Header.Get("X-Request-ID")
+	cmd.Reason = r.Header.Get("X-Change-Reason")
+	if cmd.Reason == "" {
+		cmd.Reason = r.URL.Query().Get("reason")
+	}
+	if cmd.Key == "" {
+		cmd.Key = r.URL.Query().Get("key")
+	}
+
+	log.Printf("收到HTTP请求: %s %s (%s)", r.Method, r.URL.Path, principal.Name)
+	writeAPIResponse(w, ws.dispatch(&commandRequest{
+		cmd:       cmd,
+		principal: principal,
+		remote:    r.RemoteAddr,
+	}))
+}
+
+// isJSONContentType 判断Content-Type是否为 application/json，忽略charset等参数
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// restCommand 将REST请求转换为命令，没有对应的接口时返回nil
+//
+//	GET    /api/config                  -> config/get
+//	PUT    /api/config                  -> config/update
+//	PATCH  /api/config/{section}[/{key}] -> config/updateSection
+//	POST   /api/mints                   -> config/addMint
+//	DELETE /api/mints/{mint}            -> config/removeMint
+//	POST   /api/bot/{restart|start|stop} -> bot/restart、bot/start、bot/stop
+//	GET    /api/status                  -> bot/status
+func restCommand(method, path string, body []byte) *Command {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, "/api/"), "/"), "/")
+	value := json.RawMessage(body)
+	if len(body) == 0 {
+		value = nil
+	}
+
+	switch {
+	case segments[0] == "config" && len(segments) == 1 && method == http.MethodGet:
+		return &Command{Type: "config", Action: "get"}
+	case segments[0] == "config" && len(segments) == 1 && method == http.MethodPut:
+		return &Command{Type: "config", Action: "update", Value: value}
+	case segments[0] == "config" && (len(segments) == 2 || len(segments) == 3) && method == http.MethodPatch:
+		cmd := &Command{Type: "config", Action: "updateSection", Section: segments[1], Value: value}
+		if len(segments) == 3 {
+			cmd.Key = segments[2]
+		}
+		return cmd
+	case segments[0] == "mints" && len(segments) == 1 && method == http.MethodPost:
+		return &Command{Type: "config", Action: "addMint", Value: value}
+	case segments[0] == "mints" && len(segments) == 2 && method == http.MethodDelete:
+		mint, _ := json.Marshal(segments[1])
+		return &Command{Type: "config", Action: "removeMint", Value: mint}
+	case segments[0] == "bot" && len(segments) == 2 && method == http.MethodPost:
+		switch segments[1] {
+		case "restart", "start", "stop":
+			return &Command{Type: "bot", Action: segments[1]}
+		}
+	case segments[0] == "status" && len(segments) == 1 && method == http.MethodGet:
+		return &Command{Type: "bot", Action: "status"}
+	}
+	return nil
+}
+
+// httpStatus 根据响应的错误码返回HTTP状态码
+func httpStatus(response *Response) int {
+	if response.Error == nil {
+		return http.StatusOK
+	}
+	switch response.Error.Code {
+	case ErrCodeBadRequest:
+		return http.StatusBadRequest
+	case ErrCodeUnauthorized:
+		return http.StatusUnauthorized
+	case ErrCodeForbidden:
+		return http.StatusForbidden
+	case ErrCodeUnknownCommand, ErrCodeNotFound:
+		return http.StatusNotFound
+	case ErrCodeValidation:
+		return http.StatusUnprocessableEntity
+	case ErrCodeBusy:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeAPIResponse 以JSON写出响应，格式与WebSocket响应相同
+func writeAPIResponse(w http.ResponseWriter, response *Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(response))
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("写入HTTP响应失败: %v", err)
+	}
+}
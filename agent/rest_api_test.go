@@ -0,0 +1,103 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRESTCommandMapping(t *testing.T) {
+	body := []byte(`{"enabled":true}`)
+	mint, _ := json.Marshal(testMintA)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   []byte
+		want   *Command
+	}{
+		{name: "get config", method: http.MethodGet, path: "/api/config", want: &Command{Type: "config", Action: "get"}},
+		{name: "trailing slash", method: http.MethodGet, path: "/api/config/", want: &Command{Type: "config", Action: "get"}},
+		{name: "replace config", method: http.MethodPut, path: "/api/config", body: body,
+			want: &Command{Type: "config", Action: "update", Value: body}},
+		{name: "update section", method: http.MethodPatch, path: "/api/config/spam", body: body,
+			want: &Command{Type: "config", Action: "updateSection", Section: "spam", Value: body}},
+		{name: "update key", method: http.MethodPatch, path: "/api/config/spam/enabled", body: []byte(`false`),
+			want: &Command{Type: "config", Action: "updateSection", Section: "spam", Key: "enabled", Value: []byte(`false`)}},
+		{name: "add mint", method: http.MethodPost, path: "/api/mints", body: body,
+			want: &Command{Type: "config", Action: "addMint", Value: body}},
+		{name: "remove mint", method: http.MethodDelete, path: "/api/mints/" + testMintA,
+			want: &Command{Type: "config", Action: "removeMint", Value: mint}},
+		{name: "restart", method: http.MethodPost, path: "/api/bot/restart", want: &Command{Type: "bot", Action: "restart"}},
+		{name: "start", method: http.MethodPost, path: "/api/bot/start", want: &Command{Type: "bot", Action: "start"}},
+		{name: "stop", method: http.MethodPost, path: "/api/bot/stop", want: &Command{Type: "bot", Action: "stop"}},
+		{name: "status", method: http.MethodGet, path: "/api/status", want: &Command{Type: "bot", Action: "status"}},
+		{name: "wrong method", method: http.MethodDelete, path: "/api/config"},
+		{name: "get bot action", method: http.MethodGet, path: "/api/bot/stop"},
+		{name: "unknown bot action", method: http.MethodPost, path: "/api/bot/kill"},
+		{name: "section too deep", method: http.MethodPatch, path: "/api/config/a/b/c"},
+		{name: "remove without mint", method: http.MethodDelete, path: "/api/mints"},
+		{name: "unknown resource", method: http.MethodGet, path: "/api/nothing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := restCommand(tt.method, tt.path, tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s %s 映射为 %+v，期望 %+v", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRESTOriginAndContentType(t *testing.T) {
+	agent := newTestAgent(t)
+	auth, err := NewAuthenticator(agent.agentConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := agent.agentConfig.Server
+	config.AllowedOrigins = []string{"https://dashboard.example.com"}
+	ws := NewWebSocketServer(config, auth, agent)
+	agent.ws = ws
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		origin      string
+		contentType string
+		body        string
+		want        int
+	}{
+		{name: "no origin", method: http.MethodGet, path: "/api/status", want: http.StatusOK},
+		{name: "same origin", method: http.MethodGet, path: "/api/status", origin: "http://example.com", want: http.StatusOK},
+		{name: "allowed origin", method: http.MethodGet, path: "/api/status", origin: "https://dashboard.example.com", want: http.StatusOK},
+		{name: "cross-site stop", method: http.MethodPost, path: "/api/bot/stop", origin: "https://evil.example.net", want: http.StatusForbidden},
+		{name: "cross-site add mint", method: http.MethodPost, path: "/api/mints", origin: "https://evil.example.net",
+			contentType: "application/json", body: `{"mint":"` + testMintB + `"}`, want: http.StatusForbidden},
+		{name: "text body", method: http.MethodPost, path: "/api/mints",
+			contentType: "text/plain", body: `{"mint":"` + testMintB + `"}`, want: http.StatusBadRequest},
+		{name: "missing content type", method: http.MethodPut, path: "/api/config", body: `{}`, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com"+tt.path, strings.NewReader(tt.body))
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			ws.handleAPI(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("状态码 %d，期望 %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
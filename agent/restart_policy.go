@@ -0,0 +1,186 @@
+package agent
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RestartPolicyStatus 表示自动重启策略的当前状态
+type RestartPolicyStatus struct {
+	CrashLoop           bool      `json:"crash_loop"`           // 是否处于崩溃循环，处于该状态时不再自动重启
+	ConsecutiveFailures int       `json:"consecutive_failures"` // 连续失败次数
+	RestartsInWindow    int       `json:"restarts_in_window"`   // 时间窗口内的自动重启次数
+	TotalRestarts       int       `json:"total_restarts"`       // 代理启动以来的自动重启总次数
+	Pending             bool      `json:"pending"`              // 是否有等待执行的重启
+	NextAttempt         time.Time `json:"next_attempt"`         // 下一次重启时间
+	LastStart           time.Time `json:"last_start"`           // 最近一次启动时间
+}
+
+// RestartPolicy 实现带抖动的指数退避重启和崩溃循环检测
+type RestartPolicy struct {
+	mu            sync.Mutex
+	config        RestartPolicyConfig
+	rnd           *rand.Rand
+	now           func() time.Time // Status使用的时钟，测试中可替换；其他方法由调用方传入当前时间
+	failures      int
+	restarts      []time.Time
+	totalRestarts int
+	pending       bool
+	nextAttempt   time.Time
+	crashLoop     bool
+	lastStart     time.Time
+}
+
+// NewRestartPolicy 根据配置创建重启策略
+func NewRestartPolicy(config RestartPolicyConfig) *RestartPolicy {
+	return &RestartPolicy{
+		config: config,
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:    time.Now,
+	}
+}
+
+// ScheduleRestart 记录一次意外退出并安排下一次重启，返回等待时间
+// 已有等待中的重启或处于崩溃循环时不重复安排
+func (r *RestartPolicy) ScheduleRestart(now time.Time) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.crashLoop {
+		return 0
+	}
+	if r.pending {
+		return r.nextAttempt.Sub(now)
+	}
+
+	r.failures++
+	delay := r.backoff()
+	r.pending = true
+	r.nextAttempt = now.Add(delay)
+	return delay
+}
+
+// backoff 计算当前连续失败次数对应的等待时间
+func (r *RestartPolicy) backoff() time.Duration {
+	base := float64(r.config.InitialBackoff) * math.Pow(r.config.Multiplier, float64(r.failures-1))
+	if base > float64(r.config.MaxBackoff) {
+		base = float64(r.config.MaxBackoff)
+	}
+
+	// 在 [1-jitter, 1+jitter] 范围内随机抖动，避免多个实例同时重启
+	if jitter := r.config.Jitter; jitter != nil && *jitter > 0 {
+		base *= 1 + *jitter*(2*r.rnd.Float64()-1)
+	}
+
+	return time.Duration(base * float64(time.Second))
+}
+
+// Due 判断是否到达计划的重启时间
+func (r *RestartPolicy) Due(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pending && !r.crashLoop && !now.Before(r.nextAttempt)
+}
+
+// AllowRestart 在执行重启前调用，记录本次重启
+// 时间窗口内重启次数超出上限时进入崩溃循环并返回false
+func (r *RestartPolicy) AllowRestart(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneRestarts(now)
+	if len(r.restarts) >= r.config.MaxRestarts {
+		r.crashLoop = true
+		r.pending = false
+		r.nextAttempt = time.Time{}
+		return false
+	}
+
+	r.restarts = append(r.restarts, now)
+	r.totalRestarts++
+	r.pending = false
+	r.nextAttempt = time.Time{}
+	return true
+}
+
+// pruneRestarts 移除时间窗口之外的重启记录
+func (r *RestartPolicy) pruneRestarts(now time.Time) {
+	window := time.Duration(r.config.Window) * time.Second
+	kept := r.restarts[:0]
+	for _, t := range r.restarts {
+		if now.Sub(t) < window {
+			kept = append(kept, t)
+		}
+	}
+	r.restarts = kept
+}
+
+// MarkStarted 记录进程启动成功的时间
+func (r *RestartPolicy) MarkStarted(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastStart = now
+}
+
+// CheckHealthy 进程持续运行超过健康阈值时重置退避和重启计数
+// 返回true表示本次调用完成了重置
+func (r *RestartPolicy) CheckHealthy(now time.Time) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures == 0 && len(r.restarts) == 0 {
+		return false
+	}
+	if r.lastStart.IsZero() || now.Sub(r.lastStart) < time.Duration(r.config.HealthyAfter)*time.Second {
+		return false
+	}
+
+	r.failures = 0
+	r.restarts = nil
+	return true
+}
+
+// Reset 清除退避和崩溃循环状态，用于手动启动或重启
+func (r *RestartPolicy) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures = 0
+	r.restarts = nil
+	r.pending = false
+	r.nextAttempt = time.Time{}
+	r.crashLoop = false
+}
+
+// Pending 判断是否有等待执行的重启
+func (r *RestartPolicy) Pending() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pending
+}
+
+// InCrashLoop 判断是否处于崩溃循环
+func (r *RestartPolicy) InCrashLoop() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.crashLoop
+}
+
+// Status 返回重启策略的当前状态
+func (r *RestartPolicy) Status() RestartPolicyStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.pruneRestarts(r.now())
+	return RestartPolicyStatus{
+		CrashLoop:           r.crashLoop,
+		ConsecutiveFailures: r.failures,
+		RestartsInWindow:    len(r.restarts),
+		TotalRestarts:       r.totalRestarts,
+		Pending:             r.pending,
+		NextAttempt:         r.nextAttempt,
+		LastStart:           r.lastStart,
+	}
+}
@@ -0,0 +1,224 @@
+package agent
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// testClock 测试用的时钟，只在调用Advance时前进
+type testClock struct {
+	now time.Time
+}
+
+// Now 返回当前时间
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+// Advance 将时钟前进d
+func (c *testClock) Advance(d time.Duration) time.Time {
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// newTestRestartPolicy 创建使用测试时钟的重启策略，jitter为抖动比例
+func newTestRestartPolicy(jitter float64) (*RestartPolicy, *testClock) {
+	clock := &testClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	policy := NewRestartPolicy(RestartPolicyConfig{
+		InitialBackoff: 5,
+		MaxBackoff:     60,
+		Multiplier:     2,
+		Jitter:         &jitter,
+		MaxRestarts:    3,
+		Window:         600,
+		HealthyAfter:   120,
+	})
+	policy.rnd = rand.New(rand.NewSource(1))
+	policy.now = clock.Now
+	return policy, clock
+}
+
+// crashAndRestart 模拟一次意外退出，等到计划的重启时间后执行重启，返回等待时间和是否允许重启
+func crashAndRestart(t *testing.T, policy *RestartPolicy, clock *testClock) (time.Duration, bool) {
+	t.Helper()
+	delay := policy.ScheduleRestart(clock.Now())
+	if policy.Due(clock.Now()) && delay > 0 {
+		t.Fatalf("等待时间 %v 未到就可以重启", delay)
+	}
+	now := clock.Advance(delay)
+	if !policy.Due(now) {
+		t.Fatalf("等待 %v 后仍未到重启时间", delay)
+	}
+	allowed := policy.AllowRestart(now)
+	if allowed {
+		policy.MarkStarted(now)
+	}
+	return delay, allowed
+}
+
+func TestRestartPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures int
+		want     time.Duration
+	}{
+		{name: "first", failures: 1, want: 5 * time.Second},
+		{name: "second", failures: 2, want: 10 * time.Second},
+		{name: "fourth", failures: 4, want: 40 * time.Second},
+		{name: "capped", failures: 5, want: 60 * time.Second},
+		{name: "stays capped", failures: 10, want: 60 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, _ := newTestRestartPolicy(0)
+			policy.failures = tt.failures
+			if got := policy.backoff(); got != tt.want {
+				t.Errorf("等待时间 %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestartPolicyJitter(t *testing.T) {
+	policy, _ := newTestRestartPolicy(0.2)
+	policy.failures = 2
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 200; i++ {
+		delay := policy.backoff()
+		if delay < 8*time.Second || delay > 12*time.Second {
+			t.Fatalf("等待时间 %v 超出 10s±20%%", delay)
+		}
+		seen[delay] = true
+	}
+	if len(seen) < 2 {
+		t.Error("抖动没有生效")
+	}
+}
+
+func TestRestartPolicyScheduleAndDue(t *testing.T) {
+	policy, clock := newTestRestartPolicy(0)
+	start := clock.Now()
+
+	if policy.Due(start) {
+		t.Fatal("没有安排重启时不应到期")
+	}
+	if delay := policy.ScheduleRestart(start); delay != 5*time.Second {
+		t.Fatalf("首次等待 %v，期望 5s", delay)
+	}
+
+	// 等待中的重启不会被重复安排，返回剩余时间
+	if delay := policy.ScheduleRestart(clock.Advance(2 * time.Second)); delay != 3*time.Second {
+		t.Errorf("重复安排返回 %v，期望剩余的 3s", delay)
+	}
+	if policy.Status().ConsecutiveFailures != 1 {
+		t.Errorf("重复安排增加了失败次数: %+v", policy.Status())
+	}
+	if policy.Due(clock.Advance(2 * time.Second)) {
+		t.Error("4s时不应到期")
+	}
+	if !policy.Due(clock.Advance(time.Second)) {
+		t.Error("5s时应到期")
+	}
+	if status := policy.Status(); !status.Pending || !status.NextAttempt.Equal(start.Add(5*time.Second)) {
+		t.Errorf("状态不符合预期: %+v", status)
+	}
+}
+
+func TestRestartPolicyCrashLoop(t *testing.T) {
+	policy, clock := newTestRestartPolicy(0)
+
+	var delays []time.Duration
+	for i := 0; i < 3; i++ {
+		delay, allowed := crashAndRestart(t, policy, clock)
+		if !allowed {
+			t.Fatalf("第%d次重启被拒绝", i+1)
+		}
+		delays = append(delays, delay)
+	}
+	if delays[0] != 5*time.Second || delays[1] != 10*time.Second || delays[2] != 20*time.Second {
+		t.Errorf("退避时间 %v，期望 [5s 10s 20s]", delays)
+	}
+
+	// 窗口内第4次重启进入崩溃循环
+	if _, allowed := crashAndRestart(t, policy, clock); allowed {
+		t.Fatal("超过 max_restarts 后仍允许重启")
+	}
+	if !policy.InCrashLoop() || policy.Pending() {
+		t.Fatalf("应处于崩溃循环且没有等待中的重启: %+v", policy.Status())
+	}
+	if delay := policy.ScheduleRestart(clock.Now()); delay != 0 || policy.Due(clock.Advance(time.Hour)) {
+		t.Error("崩溃循环中不应再安排重启")
+	}
+
+	// 手动启动清除崩溃循环
+	policy.Reset()
+	if policy.InCrashLoop() {
+		t.Fatal("Reset后仍处于崩溃循环")
+	}
+	if delay, allowed := crashAndRestart(t, policy, clock); !allowed || delay != 5*time.Second {
+		t.Errorf("Reset后重启 %v %v，期望从初始等待时间开始", delay, allowed)
+	}
+	if status := policy.Status(); status.TotalRestarts != 4 || status.RestartsInWindow != 1 {
+		t.Errorf("重启计数不符合预期: %+v", status)
+	}
+}
+
+// 时间窗口之外的重启不计入崩溃循环判定
+func TestRestartPolicyWindow(t *testing.T) {
+	policy, clock := newTestRestartPolicy(0)
+	policy.config.HealthyAfter = 3600
+
+	for i := 0; i < 3; i++ {
+		if _, allowed := crashAndRestart(t, policy, clock); !allowed {
+			t.Fatalf("第%d次重启被拒绝", i+1)
+		}
+	}
+	// 三次重启分别在5s、15s、35s，615s时前两次已移出600秒窗口
+	clock.Advance(580 * time.Second)
+	if status := policy.Status(); status.RestartsInWindow != 1 {
+		t.Errorf("窗口内重启次数 %d，期望 1（只剩最后一次）", status.RestartsInWindow)
+	}
+	if _, allowed := crashAndRestart(t, policy, clock); !allowed {
+		t.Error("早期重启移出时间窗口后仍被判定为崩溃循环")
+	}
+}
+
+func TestRestartPolicyCheckHealthy(t *testing.T) {
+	tests := []struct {
+		name    string
+		crashes int
+		running time.Duration
+		want    bool
+	}{
+		{name: "no failures", crashes: 0, running: time.Hour, want: false},
+		{name: "not long enough", crashes: 2, running: 119 * time.Second, want: false},
+		{name: "healthy", crashes: 2, running: 120 * time.Second, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, clock := newTestRestartPolicy(0)
+			policy.MarkStarted(clock.Now())
+			for i := 0; i < tt.crashes; i++ {
+				crashAndRestart(t, policy, clock)
+			}
+
+			if got := policy.CheckHealthy(clock.Advance(tt.running)); got != tt.want {
+				t.Fatalf("CheckHealthy 返回 %v，期望 %v", got, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+			if status := policy.Status(); status.ConsecutiveFailures != 0 || status.RestartsInWindow != 0 {
+				t.Errorf("重置后状态 %+v", status)
+			}
+			if policy.CheckHealthy(clock.Advance(time.Second)) {
+				t.Error("已重置后再次返回true")
+			}
+			// 恢复健康后的下一次崩溃从初始等待时间开始
+			if delay, _ := crashAndRestart(t, policy, clock); delay != 5*time.Second {
+				t.Errorf("重置后等待时间 %v，期望 5s", delay)
+			}
+		})
+	}
+}
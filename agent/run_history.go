@@ -0,0 +1,160 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// StopCause 表示进程停止的原因
+type StopCause string
+
+const (
+	StopCauseExited    StopCause = "exited"    // 进程自行退出（崩溃或正常结束）
+	StopCauseManual    StopCause = "manual"    // 客户端手动停止或重启
+	StopCauseConfig    StopCause = "config"    // 配置更新触发的重启
+	StopCauseShutdown  StopCause = "shutdown"  // 代理关闭
+	StopCauseUnhealthy StopCause = "unhealthy" // 存活检测失败（无输出、匹配致命模式或探针失败）
+)
+
+// RunRecord 记录MEV Bot进程的一次运行
+type RunRecord struct {
+	ID        int64     `json:"id"`
+	PID       int       `json:"pid"`
+	StartTime time.Time `json:"start_time"`
+	StopTime  time.Time `json:"stop_time"`
+	ExitCode  int       `json:"exit_code"`
+	Signal    string    `json:"signal,omitempty"`
+	StopCause StopCause `json:"stop_cause"`
+	Reason    string    `json:"reason,omitempty"` // 停止的具体原因，如存活检测失败的说明
+	LastLines []LogLine `json:"last_lines"`       // 退出前的最后若干行输出
+}
+
+// RunHistory 保存最近若干次运行记录，并持久化到磁盘
+type RunHistory struct {
+	mu      sync.RWMutex
+	path    string
+	limit   int
+	nextID  int64
+	records []RunRecord
+}
+
+// NewRunHistory 创建运行历史，path为空时只保存在内存中
+func NewRunHistory(path string, limit int) *RunHistory {
+	if limit <= 0 {
+		limit = 100
+	}
+	h := &RunHistory{
+		path:   path,
+		limit:  limit,
+		nextID: 1,
+	}
+	if err := h.load(); err != nil {
+		log.Printf("加载运行历史失败: %v", err)
+	}
+	return h
+}
+
+// load 从磁盘加载运行历史
+func (h *RunHistory) load() error {
+	if h.path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var records []RunRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return fmt.Errorf("解析运行历史失败: %w", err)
+	}
+
+	if len(records) > h.limit {
+		records = records[len(records)-h.limit:]
+	}
+	h.records = records
+	for _, r := range records {
+		if r.ID >= h.nextID {
+			h.nextID = r.ID + 1
+		}
+	}
+	return nil
+}
+
+// Add 追加一条运行记录并写入磁盘，返回分配的记录ID
+func (h *RunHistory) Add(record RunRecord) int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	record.ID = h.nextID
+	h.nextID++
+
+	h.records = append(h.records, record)
+	if len(h.records) > h.limit {
+		h.records = h.records[len(h.records)-h.limit:]
+	}
+
+	if err := h.save(); err != nil {
+		log.Printf("保存运行历史失败: %v", err)
+	}
+	return record.ID
+}
+
+// save 将运行历史写入临时文件后替换，避免写入中断导致文件损坏
+func (h *RunHistory) save() error {
+	if h.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(h.records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(h.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp := h.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, h.path)
+}
+
+// Recent 返回最近的limit条记录，最新的在前
+func (h *RunHistory) Recent(limit int) []RunRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if limit <= 0 || limit > len(h.records) {
+		limit = len(h.records)
+	}
+
+	result := make([]RunRecord, 0, limit)
+	for i := len(h.records) - 1; i >= len(h.records)-limit; i-- {
+		result = append(result, h.records[i])
+	}
+	return result
+}
+
+// Last 返回最近一次运行记录
+func (h *RunHistory) Last() (RunRecord, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.records) == 0 {
+		return RunRecord{}, false
+	}
+	return h.records[len(h.records)-1], true
+}
@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// systemd通知的状态
+const (
+	sdNotifyReady    = "READY=1"
+	sdNotifyStopping = "STOPPING=1"
+	sdNotifyWatchdog = "WATCHDOG=1"
+)
+
+// sdNotify 向systemd发送通知（sd_notify协议）
+// 没有NOTIFY_SOCKET环境变量（未以Type=notify运行）时不发送，返回false
+func sdNotify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// 以@开头的抽象命名空间地址由net包处理
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sdWatchdogInterval 返回systemd看门狗的超时时间，未启用看门狗（WatchdogSec）时返回0
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	// WATCHDOG_PID指定了其他进程时，看门狗不是发给本进程的
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
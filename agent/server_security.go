@@ -0,0 +1,108 @@
+package agent
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// TLSConfig 表示监听端口的TLS配置
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`      // 服务端证书，为空时不启用TLS
+	KeyFile      string `yaml:"key_file"`       // 服务端私钥
+	ClientCAFile string `yaml:"client_ca_file"` // 客户端证书的CA，非空时要求客户端出示证书（mTLS）
+}
+
+// Enabled 判断是否启用TLS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// buildTLSConfig 根据配置创建tls.Config，未启用TLS时返回nil
+func buildTLSConfig(config TLSConfig) (*tls.Config, error) {
+	if !config.Enabled() {
+		return nil, nil
+	}
+	if config.KeyFile == "" {
+		return nil, fmt.Errorf("server.tls.key_file 不能为空")
+	}
+
+	cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("加载TLS证书失败: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if config.ClientCAFile != "" {
+		data, err := os.ReadFile(config.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取客户端CA证书失败: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("客户端CA证书 %s 中没有有效的证书", config.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// checkListenSecurity 拒绝在非回环地址上不加密地监听，除非显式允许
+func checkListenSecurity(config ServerConfig) error {
+	if config.TLS.Enabled() || config.AllowInsecure || isLoopbackAddr(config.ListenAddr) {
+		return nil
+	}
+	return fmt.Errorf("拒绝在非回环地址 %s 上以明文监听: 请配置 server.tls，或设置 server.allow_insecure: true", config.ListenAddr)
+}
+
+// isLoopbackAddr 判断监听地址是否只绑定在回环接口上
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newOriginChecker 创建WebSocket升级和REST请求的来源检查函数
+// 没有Origin头的请求（非浏览器客户端）和同源请求总是允许；
+// 其他来源必须在allowed列表中，"*" 表示允许所有来源
+func newOriginChecker(allowed []string) func(r *http.Request) bool {
+	allowAll := false
+	allowedSet := make(map[string]bool)
+	for _, origin := range allowed {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowedSet[strings.TrimRight(strings.ToLower(origin), "/")] = true
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || allowAll {
+			return true
+		}
+		if allowedSet[strings.ToLower(origin)] {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		if err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		return false
+	}
+}
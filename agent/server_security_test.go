@@ -0,0 +1,295 @@
+package agent
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCheckListenSecurity(t *testing.T) {
+	tests := []struct {
+		name     string
+		addr     string
+		tls      bool
+		insecure bool
+		wantErr  bool
+	}{
+		{name: "ipv4 loopback", addr: "127.0.0.1:8080"},
+		{name: "other loopback", addr: "127.0.0.2:8080"},
+		{name: "ipv6 loopback", addr: "[::1]:8080"},
+		{name: "localhost", addr: "localhost:8080"},
+		{name: "all interfaces", addr: ":8080", wantErr: true},
+		{name: "unspecified", addr: "0.0.0.0:8080", wantErr: true},
+		{name: "lan address", addr: "192.168.1.10:8080", wantErr: true},
+		{name: "hostname", addr: "flash.internal:8080", wantErr: true},
+		{name: "missing port", addr: "127.0.0.1", wantErr: true},
+		{name: "tls", addr: "0.0.0.0:8443", tls: true},
+		{name: "allow insecure", addr: "0.0.0.0:8080", insecure: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := ServerConfig{ListenAddr: tt.addr, AllowInsecure: tt.insecure}
+			if tt.tls {
+				config.TLS = TLSConfig{CertFile: "server.pem", KeyFile: "server-key.pem"}
+			}
+			err := checkListenSecurity(config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("错误 %v，期望出错: %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOriginChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{name: "no origin", origin: "", want: true},
+		{name: "same origin", origin: "http://agent.example.com:8080", want: true},
+		{name: "same origin https", origin: "https://agent.example.com:8080", want: true},
+		{name: "same host other port", origin: "http://agent.example.com:9090", want: false},
+		{name: "cross origin", origin: "https://evil.example.net", want: false},
+		{name: "null origin", origin: "null", want: false},
+		{name: "allowed", allowed: []string{"https://dashboard.example.com"}, origin: "https://dashboard.example.com", want: true},
+		{name: "allowed case insensitive", allowed: []string{"https://Dashboard.example.com/"}, origin: "HTTPS://dashboard.EXAMPLE.com", want: true},
+		{name: "allowed needs scheme match", allowed: []string{"https://dashboard.example.com"}, origin: "http://dashboard.example.com", want: false},
+		{name: "allowed needs port match", allowed: []string{"https://dashboard.example.com"}, origin: "https://dashboard.example.com:8443", want: false},
+		{name: "not a suffix match", allowed: []string{"https://example.com"}, origin: "https://evil-example.com", want: false},
+		{name: "wildcard", allowed: []string{"*"}, origin: "https://anything.example.org", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := newOriginChecker(tt.allowed)
+			req := httptest.NewRequest(http.MethodGet, "http://agent.example.com:8080/ws", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if got := check(req); got != tt.want {
+				t.Errorf("来源 %q 检查结果 %v，期望 %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+// 浏览器从不允许的来源发起的WebSocket升级被拒绝
+func TestWSUpgradeRejectsCrossOrigin(t *testing.T) {
+	_, url := newTestServer(t, testServerConfig(16, SlowClientDrop))
+
+	header := http.Header{"Origin": []string{"https://evil.example.net"}}
+	conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+	if err == nil {
+		conn.Close()
+		t.Fatal("跨站WebSocket连接未被拒绝")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("响应 %v，期望403", resp)
+	}
+}
+
+// testPKI 测试用的CA、服务端证书和客户端证书
+type testPKI struct {
+	dir        string
+	caPool     *x509.CertPool
+	caFile     string
+	serverCert string
+	serverKey  string
+	client     tls.Certificate
+}
+
+// newTestPKI 在临时目录中生成CA，以及由它签发的127.0.0.1服务端证书和客户端证书
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	pki := &testPKI{dir: dir, caPool: x509.NewCertPool()}
+
+	caKey := newTestKey(t)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "flash test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pki.caPool.AddCert(caCert)
+	pki.caFile = writePEM(t, dir, "ca.pem", "CERTIFICATE", caDER)
+
+	issue := func(serial int64, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key := newTestKey(t)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "flash test"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der, key
+	}
+
+	serverDER, serverKey := issue(2, x509.ExtKeyUsageServerAuth)
+	pki.serverCert = writePEM(t, dir, "server.pem", "CERTIFICATE", serverDER)
+	pki.serverKey = writePEM(t, dir, "server-key.pem", "EC PRIVATE KEY", marshalTestKey(t, serverKey))
+
+	clientDER, clientKey := issue(3, x509.ExtKeyUsageClientAuth)
+	pki.client = tls.Certificate{Certificate: [][]byte{clientDER}, PrivateKey: clientKey}
+	return pki
+}
+
+// newTestKey 生成P-256私钥
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// marshalTestKey 将私钥编码为DER
+func marshalTestKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// writePEM 将DER数据以PEM格式写入目录，返回文件路径
+func writePEM(t *testing.T, dir, name, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuildTLSConfigErrors(t *testing.T) {
+	pki := newTestPKI(t)
+	garbage := filepath.Join(pki.dir, "garbage.pem")
+	if err := os.WriteFile(garbage, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  TLSConfig
+		wantErr string
+	}{
+		{name: "disabled"},
+		{name: "server only", config: TLSConfig{CertFile: pki.serverCert, KeyFile: pki.serverKey}},
+		{name: "mtls", config: TLSConfig{CertFile: pki.serverCert, KeyFile: pki.serverKey, ClientCAFile: pki.caFile}},
+		{name: "missing key file", config: TLSConfig{CertFile: pki.serverCert}, wantErr: "key_file"},
+		{name: "bad certificate", config: TLSConfig{CertFile: garbage, KeyFile: pki.serverKey}, wantErr: "加载TLS证书失败"},
+		{name: "missing client ca", config: TLSConfig{CertFile: pki.serverCert, KeyFile: pki.serverKey, ClientCAFile: filepath.Join(pki.dir, "missing.pem")},
+			wantErr: "读取客户端CA证书失败"},
+		{name: "bad client ca", config: TLSConfig{CertFile: pki.serverCert, KeyFile: pki.serverKey, ClientCAFile: garbage},
+			wantErr: "没有有效的证书"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := buildTLSConfig(tt.config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("错误 %v，期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.config.Enabled() {
+				if config != nil {
+					t.Error("未启用TLS时应返回nil")
+				}
+				return
+			}
+			if config.MinVersion != tls.VersionTLS12 {
+				t.Errorf("最低TLS版本 %x", config.MinVersion)
+			}
+			wantClientAuth := tls.NoClientCert
+			if tt.config.ClientCAFile != "" {
+				wantClientAuth = tls.RequireAndVerifyClientCert
+			}
+			if config.ClientAuth != wantClientAuth {
+				t.Errorf("客户端认证方式 %v，期望 %v", config.ClientAuth, wantClientAuth)
+			}
+		})
+	}
+}
+
+// 配置client_ca_file后，只有出示由该CA签发的证书的客户端可以完成握手
+func TestMutualTLSHandshake(t *testing.T) {
+	pki := newTestPKI(t)
+	tlsConfig, err := buildTLSConfig(TLSConfig{CertFile: pki.serverCert, KeyFile: pki.serverKey, ClientCAFile: pki.caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	defer srv.Close()
+
+	get := func(certs []tls.Certificate) error {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      pki.caPool,
+			Certificates: certs,
+		}}}
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	}
+
+	if err := get([]tls.Certificate{pki.client}); err != nil {
+		t.Errorf("出示客户端证书的请求失败: %v", err)
+	}
+	if err := get(nil); err == nil {
+		t.Error("没有客户端证书的请求应在握手时被拒绝")
+	}
+
+	// 由其他CA签发的客户端证书同样被拒绝
+	other := newTestPKI(t)
+	if err := get([]tls.Certificate{other.client}); err == nil {
+		t.Error("其他CA签发的客户端证书应被拒绝")
+	}
+}
@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// 热门代币的排序键
+const (
+	SortByBuyVolume15m = "buy_volume_15m" // 15分钟买入量
+	SortByVolume15m    = "volume_15m"     // 15分钟交易量
+	SortByBuyVolume5m  = "buy_volume_5m"  // 5分钟买入量
+	SortByVolume24h    = "volume_24h"     // 24小时交易量
+)
+
+// TokenSelectionPolicy 描述热门代币跟踪器如何选择写入MintConfig的代币
+// 选择分两步：SelectHotTokens 在查询池之前过滤、排序和截取代币，SelectTokenPools 在查询池之后按池类型数量选择最终的代币
+type TokenSelectionPolicy struct {
+	SortBy          string   `yaml:"sort_by"`            // 排序键，相同时按24小时交易量排序
+	TopN            int      `yaml:"top_n"`              // 排序后查询池信息的代币数
+	MintSuffixes    []string `yaml:"mint_suffixes"`      // 铸币地址必须以其中之一结尾，为空表示不限制
+	AllowMints      []string `yaml:"allow_mints"`        // 非空时只选择这些代币
+	DenyMints       []string `yaml:"deny_mints"`         // 从不选择这些代币，优先于 allow_mints
+	MinVolume5m     float64  `yaml:"min_volume_5m"`      // 5分钟买入量下限（美元）
+	MinVolume15m    float64  `yaml:"min_volume_15m"`     // 15分钟交易量下限（美元）
+	MinVolume24h    float64  `yaml:"min_volume_24h"`     // 24小时交易量下限（美元）
+	MinPoolTypes    int      `yaml:"min_pool_types"`     // 至少有几种类型的池才写入配置
+	MaxTokens       int      `yaml:"max_tokens"`         // 最多写入配置的代币数
+	MaxPoolsPerKind int      `yaml:"max_pools_per_kind"` // 每种池类型最多写入的池数
+}
+
+// Validate 检查策略中的排序键
+func (p TokenSelectionPolicy) Validate() error {
+	switch p.SortBy {
+	case SortByBuyVolume15m, SortByVolume15m, SortByBuyVolume5m, SortByVolume24h:
+		return nil
+	}
+	return fmt.Errorf("hottoken.selection.sort_by: 未知的排序键 %q", p.SortBy)
+}
+
+// sortValue 返回代币在排序键上的值
+func (p TokenSelectionPolicy) sortValue(token HotToken) float64 {
+	switch p.SortBy {
+	case SortByVolume15m:
+		return token.Volume15m
+	case SortByBuyVolume5m:
+		return token.BuyVolumeUSD5m
+	case SortByVolume24h:
+		return token.VolumeUSD24h
+	}
+	return token.BuyVolumeUSD15m
+}
+
+// rejectReason 检查代币是否满足铸币地址和交易量条件，返回不满足的原因
+func (p TokenSelectionPolicy) rejectReason(token HotToken) string {
+	if containsString(p.DenyMints, token.TargetToken) {
+		return "在 deny_mints 中"
+	}
+	if len(p.AllowMints) > 0 && !containsString(p.AllowMints, token.TargetToken) {
+		return "不在 allow_mints 中"
+	}
+	if len(p.MintSuffixes) > 0 {
+		matched := false
+		for _, suffix := range p.MintSuffixes {
+			if strings.HasSuffix(token.TargetToken, suffix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Sprintf("铸币地址不以 %s 结尾", strings.Join(p.MintSuffixes, "、"))
+		}
+	}
+	if token.BuyVolumeUSD5m < p.MinVolume5m {
+		return fmt.Sprintf("5分钟买入量 $%.2f 低于 $%.2f", token.BuyVolumeUSD5m, p.MinVolume5m)
+	}
+	if token.Volume15m < p.MinVolume15m {
+		return fmt.Sprintf("15分钟交易量 $%.2f 低于 $%.2f", token.Volume15m, p.MinVolume15m)
+	}
+	if token.VolumeUSD24h < p.MinVolume24h {
+		return fmt.Sprintf("24小时交易量 $%.2f 低于 $%.2f", token.VolumeUSD24h, p.MinVolume24h)
+	}
+	return ""
+}
+
+// SelectHotTokens 过滤不满足条件的代币，按排序键从高到低排序后保留前 TopN 个
+// 返回选中的代币和被过滤的代币地址及原因，不修改输入
+func (p TokenSelectionPolicy) SelectHotTokens(tokens []HotToken) ([]HotToken, map[string]string) {
+	selected := make([]HotToken, 0, len(tokens))
+	rejected := make(map[string]string)
+	for _, token := range tokens {
+		if reason := p.rejectReason(token); reason != "" {
+			rejected[token.TargetToken] = reason
+			continue
+		}
+		selected = append(selected, token)
+	}
+
+	sort.SliceStable(selected, func(i, j int) bool {
+		a, b := p.sortValue(selected[i]), p.sortValue(selected[j])
+		if a != b {
+			return a > b
+		}
+		return selected[i].VolumeUSD24h > selected[j].VolumeUSD24h
+	})
+
+	if p.TopN > 0 && len(selected) > p.TopN {
+		selected = selected[:p.TopN]
+	}
+	return selected, rejected
+}
+
+// poolTypeCount 返回代币拥有的池类型数量
+func (info TokenPoolsInfo) poolTypeCount() int {
+	count := 0
+	for _, pools := range [][]string{info.PumpPools, info.MeteoraLists, info.RaydiumPools, info.RaydiumCPPools} {
+		if len(pools) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// SelectTokenPools 从已按 SelectHotTokens 顺序排列的代币中，选择池类型不少于 MinPoolTypes 的前 MaxTokens 个
+// 每种池类型的列表截断到 MaxPoolsPerKind 个；返回选中的代币，不修改输入
+func (p TokenSelectionPolicy) SelectTokenPools(infos []TokenPoolsInfo) []TokenPoolsInfo {
+	var selected []TokenPoolsInfo
+	for _, info := range infos {
+		if p.MaxTokens > 0 && len(selected) >= p.MaxTokens {
+			break
+		}
+		if info.poolTypeCount() < p.MinPoolTypes {
+			continue
+		}
+		info.PumpPools = truncateStrings(info.PumpPools, p.MaxPoolsPerKind)
+		info.MeteoraLists = truncateStrings(info.MeteoraLists, p.MaxPoolsPerKind)
+		info.RaydiumPools = truncateStrings(info.RaydiumPools, p.MaxPoolsPerKind)
+		info.RaydiumCPPools = truncateStrings(info.RaydiumCPPools, p.MaxPoolsPerKind)
+		selected = append(selected, info)
+	}
+	return selected
+}
+
+// truncateStrings 返回最多n个元素的副本，n不大于0时不截断
+func truncateStrings(values []string, n int) []string {
+	if n > 0 && len(values) > n {
+		values = values[:n]
+	}
+	return append([]string(nil), values...)
+}
@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+// selectionFixture 热门代币样例，按数据源返回的顺序排列
+var selectionFixture = []HotToken{
+	{TargetToken: "alpha111pump", Volume15m: 900, VolumeUSD24h: 9000, BuyVolumeUSD15m: 500, BuyVolumeUSD5m: 100},
+	{TargetToken: "bravo2222222", Volume15m: 5000, VolumeUSD24h: 90000, BuyVolumeUSD15m: 3000, BuyVolumeUSD5m: 900},
+	{TargetToken: "charlie3pump", Volume15m: 2000, VolumeUSD24h: 1000, BuyVolumeUSD15m: 800, BuyVolumeUSD5m: 50},
+	{TargetToken: "delta4444bonk", Volume15m: 100, VolumeUSD24h: 500000, BuyVolumeUSD15m: 1200, BuyVolumeUSD5m: 400},
+	{TargetToken: "echo5555pump", Volume15m: 300, VolumeUSD24h: 20000, BuyVolumeUSD15m: 800, BuyVolumeUSD5m: 300},
+	{TargetToken: "foxtrot6pump", Volume15m: 50, VolumeUSD24h: 100, BuyVolumeUSD15m: 10, BuyVolumeUSD5m: 1},
+}
+
+// tokenMints 返回代币的铸币地址列表
+func tokenMints(tokens []HotToken) []string {
+	var mints []string
+	for _, token := range tokens {
+		mints = append(mints, token.TargetToken)
+	}
+	return mints
+}
+
+func TestSelectHotTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   TokenSelectionPolicy
+		want     []string
+		rejected []string
+	}{
+		{
+			name:   "no filters",
+			policy: TokenSelectionPolicy{SortBy: SortByBuyVolume15m},
+			// charlie和echo的15分钟买入量相同，按24小时交易量排序
+			want: []string{"bravo2222222", "delta4444bonk", "echo5555pump", "charlie3pump", "alpha111pump", "foxtrot6pump"},
+		},
+		{
+			name:   "top n",
+			policy: TokenSelectionPolicy{SortBy: SortByBuyVolume15m, TopN: 3},
+			want:   []string{"bravo2222222", "delta4444bonk", "echo5555pump"},
+		},
+		{
+			// 后缀过滤在截取前N个之前执行：成交量最高的两个非pump代币不占用名额
+			name:     "suffix before top n",
+			policy:   TokenSelectionPolicy{SortBy: SortByBuyVolume15m, TopN: 3, MintSuffixes: []string{"pump"}},
+			want:     []string{"echo5555pump", "charlie3pump", "alpha111pump"},
+			rejected: []string{"bravo2222222", "delta4444bonk"},
+		},
+		{
+			name:     "multiple suffixes",
+			policy:   TokenSelectionPolicy{SortBy: SortByVolume24h, MintSuffixes: []string{"pump", "bonk"}},
+			want:     []string{"delta4444bonk", "echo5555pump", "alpha111pump", "charlie3pump", "foxtrot6pump"},
+			rejected: []string{"bravo2222222"},
+		},
+		{
+			name:     "allow list",
+			policy:   TokenSelectionPolicy{SortBy: SortByVolume15m, AllowMints: []string{"alpha111pump", "bravo2222222", "unknown"}},
+			want:     []string{"bravo2222222", "alpha111pump"},
+			rejected: []string{"charlie3pump", "delta4444bonk", "echo5555pump", "foxtrot6pump"},
+		},
+		{
+			name:     "deny list",
+			policy:   TokenSelectionPolicy{SortBy: SortByBuyVolume15m, TopN: 2, DenyMints: []string{"bravo2222222", "echo5555pump"}},
+			want:     []string{"delta4444bonk", "charlie3pump"},
+			rejected: []string{"bravo2222222", "echo5555pump"},
+		},
+		{
+			name: "deny overrides allow",
+			policy: TokenSelectionPolicy{SortBy: SortByBuyVolume15m,
+				AllowMints: []string{"alpha111pump", "charlie3pump"}, DenyMints: []string{"charlie3pump"}},
+			want:     []string{"alpha111pump"},
+			rejected: []string{"bravo2222222", "charlie3pump", "delta4444bonk", "echo5555pump", "foxtrot6pump"},
+		},
+		{
+			name:     "volume minimums",
+			policy:   TokenSelectionPolicy{SortBy: SortByBuyVolume5m, MinVolume5m: 50, MinVolume15m: 200, MinVolume24h: 5000},
+			want:     []string{"bravo2222222", "echo5555pump", "alpha111pump"},
+			rejected: []string{"charlie3pump", "delta4444bonk", "foxtrot6pump"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := append([]HotToken(nil), selectionFixture...)
+			selected, rejected := tt.policy.SelectHotTokens(input)
+			if got := tokenMints(selected); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("选中 %v，期望 %v", got, tt.want)
+			}
+			if len(rejected) != len(tt.rejected) {
+				t.Errorf("过滤 %v，期望 %v", rejected, tt.rejected)
+			}
+			for _, mint := range tt.rejected {
+				if rejected[mint] == "" {
+					t.Errorf("%s 没有被过滤", mint)
+				}
+			}
+			if !reflect.DeepEqual(input, selectionFixture) {
+				t.Error("SelectHotTokens修改了输入")
+			}
+		})
+	}
+}
+
+func TestSelectTokenPools(t *testing.T) {
+	infos := []TokenPoolsInfo{
+		{TokenAddress: "one", PumpPools: []string{"p1", "p2", "p3"}},
+		{TokenAddress: "two", PumpPools: []string{"p1", "p2", "p3"}, RaydiumPools: []string{"r1"}, MeteoraLists: []string{"m1", "m2", "m3"}},
+		{TokenAddress: "three", RaydiumCPPools: []string{"c1", "c2", "c3"}, RaydiumPools: []string{"r1", "r2"}},
+		{TokenAddress: "four", PumpPools: []string{"p1"}, RaydiumCPPools: []string{"c1"}},
+	}
+
+	tests := []struct {
+		name   string
+		policy TokenSelectionPolicy
+		want   []TokenPoolsInfo
+	}{
+		{
+			name:   "defaults",
+			policy: TokenSelectionPolicy{MinPoolTypes: 2, MaxTokens: 2, MaxPoolsPerKind: 2},
+			want: []TokenPoolsInfo{
+				{TokenAddress: "two", PumpPools: []string{"p1", "p2"}, RaydiumPools: []string{"r1"}, MeteoraLists: []string{"m1", "m2"}},
+				{TokenAddress: "three", RaydiumCPPools: []string{"c1", "c2"}, RaydiumPools: []string{"r1", "r2"}},
+			},
+		},
+		{
+			name:   "one pool per kind",
+			policy: TokenSelectionPolicy{MinPoolTypes: 1, MaxTokens: 3, MaxPoolsPerKind: 1},
+			want: []TokenPoolsInfo{
+				{TokenAddress: "one", PumpPools: []string{"p1"}},
+				{TokenAddress: "two", PumpPools: []string{"p1"}, RaydiumPools: []string{"r1"}, MeteoraLists: []string{"m1"}},
+				{TokenAddress: "three", RaydiumCPPools: []string{"c1"}, RaydiumPools: []string{"r1"}},
+			},
+		},
+		{
+			name:   "three pool types",
+			policy: TokenSelectionPolicy{MinPoolTypes: 3, MaxTokens: 2, MaxPoolsPerKind: 5},
+			want: []TokenPoolsInfo{
+				{TokenAddress: "two", PumpPools: []string{"p1", "p2", "p3"}, RaydiumPools: []string{"r1"}, MeteoraLists: []string{"m1", "m2", "m3"}},
+			},
+		},
+		{
+			name:   "no caps",
+			policy: TokenSelectionPolicy{MinPoolTypes: 2},
+			want:   infos[1:],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.policy.SelectTokenPools(infos)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("选中\n%+v\n期望\n%+v", got, tt.want)
+			}
+			if len(infos[0].PumpPools) != 3 || len(infos[1].MeteoraLists) != 3 {
+				t.Error("SelectTokenPools修改了输入")
+			}
+		})
+	}
+}
+
+func TestTokenSelectionPolicyValidate(t *testing.T) {
+	for _, sortBy := range []string{SortByBuyVolume15m, SortByVolume15m, SortByBuyVolume5m, SortByVolume24h} {
+		if err := (TokenSelectionPolicy{SortBy: sortBy}).Validate(); err != nil {
+			t.Errorf("%s: %v", sortBy, err)
+		}
+	}
+	if err := (TokenSelectionPolicy{SortBy: "market_cap"}).Validate(); err == nil {
+		t.Error("未知排序键没有返回错误")
+	}
+}
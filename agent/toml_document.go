@@ -0,0 +1,800 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// tomlDocument 保存TOML配置文件的原始文本
+// 修改配置时只改写发生变化的键，注释、键的顺序、格式以及Config中未建模的键都原样保留
+type tomlDocument struct {
+	lines   []string
+	newline string
+	entries map[string]*tomlEntry
+	tables  []*tomlTable
+}
+
+// tomlEntry 表示文档中的一个键值对
+type tomlEntry struct {
+	path  string // 完整路径，表数组元素带下标，如 routing.mint_config_list[0].mint
+	key   string // 键的原始文本
+	start int    // 起始行
+	end   int    // 结束行（不含），多行数组会跨越多行
+}
+
+// tomlTable 表示文档中的一个表或表数组元素
+type tomlTable struct {
+	name    string // 表名，根表为空
+	path    string // 路径，表数组元素带下标
+	array   bool   // 是否为表数组元素 [[...]]
+	header  int    // 表头所在行，根表为-1
+	end     int    // 表内容结束行（不含），即下一个表头所在行
+	lastKey int    // 最后一个键值对的结束行（不含），没有键值对时为表头的下一行
+}
+
+// parseTOMLDocument 解析TOML文本的行结构
+func parseTOMLDocument(data []byte) *tomlDocument {
+	text := string(data)
+	doc := &tomlDocument{newline: "\n"}
+	if strings.Contains(text, "\r\n") {
+		doc.newline = "\r\n"
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+	doc.lines = strings.Split(text, "\n")
+	doc.index()
+	return doc
+}
+
+// Bytes 返回文档文本
+func (d *tomlDocument) Bytes() []byte {
+	return []byte(strings.Join(d.lines, d.newline))
+}
+
+// index 重新建立键值对和表的索引
+func (d *tomlDocument) index() {
+	d.entries = make(map[string]*tomlEntry)
+	d.tables = nil
+
+	current := &tomlTable{header: -1, lastKey: 0}
+	d.tables = append(d.tables, current)
+	arrayCounts := make(map[string]int)
+
+	for i := 0; i < len(d.lines); i++ {
+		trimmed := strings.TrimSpace(d.lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "[") {
+			current.end = i
+			name, array := parseTableHeader(trimmed)
+			current = &tomlTable{
+				name:    name,
+				path:    name,
+				array:   array,
+				header:  i,
+				lastKey: i + 1,
+			}
+			if array {
+				current.path = fmt.Sprintf("%s[%d]", name, arrayCounts[name])
+				arrayCounts[name]++
+			}
+			d.tables = append(d.tables, current)
+			continue
+		}
+
+		eq := indexOutsideQuotes(d.lines[i], '=')
+		if eq < 0 {
+			continue
+		}
+		key := strings.TrimSpace(d.lines[i][:eq])
+
+		// 找到值的结束行，多行数组和多行字符串会跨越多行
+		var state scanState
+		end := i + 1
+		state.scan(d.lines[i][eq+1:])
+		for !state.complete() && end < len(d.lines) {
+			state.scan(d.lines[end])
+			end++
+		}
+
+		path := normalizeKey(key)
+		if current.path != "" {
+			path = current.path + "." + path
+		}
+		d.entries[path] = &tomlEntry{path: path, key: key, start: i, end: end}
+		current.lastKey = end
+		i = end - 1
+	}
+	current.end = len(d.lines)
+}
+
+// Apply 将配置从old修改为updated，只改写发生变化的键，返回新的文档文本
+func (d *tomlDocument) Apply(old, updated *Config) ([]byte, error) {
+	oldFields, err := flattenConfig(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flattenConfig(updated)
+	if err != nil {
+		return nil, err
+	}
+	oldTree, err := configToMap(old)
+	if err != nil {
+		return nil, err
+	}
+	newTree, err := configToMap(updated)
+	if err != nil {
+		return nil, err
+	}
+
+	// 找出表数组，表数组任意元素变化时按元素更新
+	arrays := make(map[string]bool)
+	for _, fields := range []map[string]interface{}{oldFields, newFields} {
+		for p := range fields {
+			if idx := strings.Index(p, "["); idx >= 0 {
+				arrays[p[:idx]] = false
+			}
+		}
+	}
+	for _, fields := range []map[string]interface{}{oldFields, newFields} {
+		for p := range fields {
+			base := p
+			if idx := strings.Index(p, "["); idx >= 0 {
+				base = p[:idx]
+			}
+			if _, ok := arrays[base]; ok && !reflect.DeepEqual(oldFields[p], newFields[p]) {
+				arrays[base] = true
+			}
+		}
+	}
+
+	// 按路径顺序改写标量键，保证结果稳定
+	paths := make([]string, 0, len(newFields))
+	for p := range newFields {
+		if _, isArray := arrays[arrayBase(p)]; isArray {
+			continue
+		}
+		if !reflect.DeepEqual(oldFields[p], newFields[p]) {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		d.set(p, newFields[p], newTree)
+	}
+
+	bases := make([]string, 0, len(arrays))
+	for base, changed := range arrays {
+		if changed {
+			bases = append(bases, base)
+		}
+	}
+	sort.Strings(bases)
+	for _, base := range bases {
+		oldTables, _ := lookupPath(oldTree, base).([]interface{})
+		newTables, _ := lookupPath(newTree, base).([]interface{})
+		if !d.updateTableArray(base, oldTables, newTables) {
+			d.replaceTableArray(base, newTables)
+		}
+	}
+
+	return d.Bytes(), nil
+}
+
+// set 改写或插入一个键
+func (d *tomlDocument) set(path string, value interface{}, tree map[string]interface{}) {
+	if entry, ok := d.entries[path]; ok {
+		d.replaceEntry(entry, formatTOMLValue(value))
+		return
+	}
+
+	// 键位于内联表或点分键中时，整体重写该内联表
+	for prefix := parentPath(path); prefix != ""; prefix = parentPath(prefix) {
+		if entry, ok := d.entries[prefix]; ok {
+			d.replaceEntry(entry, formatTOMLValue(lookupPath(tree, prefix)))
+			return
+		}
+	}
+
+	parent, key := parentPath(path), lastSegment(path)
+	line := formatTOMLKey(key) + " = " + formatTOMLValue(value)
+	for _, table := range d.tables {
+		if !table.array && table.path == parent {
+			d.insertLines(table.lastKey, line)
+			return
+		}
+	}
+
+	// 表不存在时追加到文档末尾
+	d.appendBlock("["+parent+"]", line)
+}
+
+// replaceEntry 替换键值对的值，保留键的原始写法、缩进和行尾注释
+func (d *tomlDocument) replaceEntry(entry *tomlEntry, value string) {
+	first := d.lines[entry.start]
+	indent := first[:len(first)-len(strings.TrimLeft(first, " \t"))]
+
+	line := indent + entry.key + " = " + value
+	if entry.end-entry.start == 1 {
+		if comment := indexOutsideQuotes(first, '#'); comment >= 0 {
+			line += " " + first[comment:]
+		}
+	}
+
+	d.spliceLines(entry.start, entry.end, strings.Split(line, "\n"))
+}
+
+// updateTableArray 逐个元素更新表数组：按 mint（没有mint时按下标）匹配的元素原样保留并只改写变化的键，
+// 新增的元素渲染后插入，删除的元素连同其注释一起移除；无法逐个匹配时返回false，由调用方整体重写
+func (d *tomlDocument) updateTableArray(base string, oldTables, newTables []interface{}) bool {
+	blocks := d.arrayBlocks(base)
+	if len(blocks) == 0 || len(blocks) != len(oldTables) || len(newTables) == 0 {
+		return false
+	}
+
+	oldIndex := make(map[string]int, len(oldTables))
+	for i, t := range oldTables {
+		id := tableArrayID(t, i)
+		if _, dup := oldIndex[id]; dup {
+			return false
+		}
+		oldIndex[id] = i
+	}
+	order := make([]int, len(newTables)) // 新元素对应的旧元素下标，新增的元素为-1
+	matched := make(map[int]bool, len(newTables))
+	for j, t := range newTables {
+		if _, ok := t.(map[string]interface{}); !ok {
+			return false
+		}
+		i, ok := oldIndex[tableArrayID(t, j)]
+		if !ok || matched[i] {
+			i = -1
+		}
+		order[j] = i
+		if i >= 0 {
+			matched[i] = true
+		}
+	}
+
+	// 先在保留的元素中改写变化的键
+	for j, i := range order {
+		if i >= 0 {
+			oldTable, _ := oldTables[i].(map[string]interface{})
+			d.updateTableArrayElement(fmt.Sprintf("%s[%d]", base, i), oldTable, newTables[j].(map[string]interface{}))
+		}
+	}
+
+	identity := len(order) == len(blocks)
+	for j, i := range order {
+		if i != j {
+			identity = false
+		}
+	}
+	if identity {
+		return true
+	}
+
+	// 元素有增删或顺序变化时，按新的顺序重新拼接各元素的原始文本
+	blocks = d.arrayBlocks(base)
+	var region []string
+	for j, i := range order {
+		if j > 0 {
+			region = append(region, "")
+		}
+		if i >= 0 {
+			block := blocks[i]
+			region = append(region, d.lines[block.header:d.trimBlankTail(block.header, block.end)]...)
+		} else {
+			region = append(region, renderTableArrayElement(base, newTables[j].(map[string]interface{}))...)
+		}
+	}
+	last := blocks[len(blocks)-1]
+	d.spliceLines(blocks[0].header, d.trimBlankTail(last.header, last.end), region)
+	return true
+}
+
+// updateTableArrayElement 改写表数组元素中变化的键，path为元素在文档中的路径
+func (d *tomlDocument) updateTableArrayElement(path string, oldTable, newTable map[string]interface{}) {
+	keys := make([]string, 0, len(newTable)+len(oldTable))
+	for k := range newTable {
+		keys = append(keys, k)
+	}
+	for k := range oldTable {
+		if _, ok := newTable[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		value, ok := newTable[k]
+		if reflect.DeepEqual(oldTable[k], value) {
+			continue
+		}
+		entry, exists := d.entries[path+"."+k]
+		switch {
+		case !ok && exists:
+			d.spliceLines(entry.start, entry.end, nil)
+		case exists:
+			d.replaceEntry(entry, formatTOMLValue(value))
+		case ok:
+			for _, table := range d.tables {
+				if table.array && table.path == path {
+					d.insertLines(table.lastKey, formatTOMLKey(k)+" = "+formatTOMLValue(value))
+					break
+				}
+			}
+		}
+	}
+}
+
+// arrayBlocks 返回表数组在文档中的各个元素，按出现顺序
+func (d *tomlDocument) arrayBlocks(base string) []*tomlTable {
+	var blocks []*tomlTable
+	for _, table := range d.tables {
+		if table.array && table.name == base {
+			blocks = append(blocks, table)
+		}
+	}
+	return blocks
+}
+
+// tableArrayID 返回表数组元素的标识：有mint时为mint，否则为下标
+func tableArrayID(table interface{}, index int) string {
+	if m, ok := table.(map[string]interface{}); ok {
+		if mint, ok := m["mint"].(string); ok {
+			return strconv.Quote(mint)
+		}
+	}
+	return fmt.Sprintf("#%d", index)
+}
+
+// renderTableArrayElement 渲染表数组的一个元素，键按字母顺序输出
+func renderTableArrayElement(base string, table map[string]interface{}) []string {
+	lines := []string{"[[" + base + "]]"}
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		lines = append(lines, strings.Split(formatTOMLKey(k)+" = "+formatTOMLValue(table[k]), "\n")...)
+	}
+	return lines
+}
+
+// replaceTableArray 用新的元素整体替换表数组
+// 旧元素中Config未建模的键按 mint（没有mint时按下标）匹配后保留
+func (d *tomlDocument) replaceTableArray(base string, tables []interface{}) {
+	blocks := d.arrayBlocks(base)
+
+	// 收集旧元素中未建模的键
+	extras := make(map[string][]string)
+	for i, block := range blocks {
+		known := map[string]bool{}
+		if i < len(tables) {
+			if m, ok := tables[i].(map[string]interface{}); ok {
+				for k := range m {
+					known[k] = true
+				}
+			}
+		}
+		id := fmt.Sprintf("#%d", i)
+		if entry, ok := d.entries[block.path+".mint"]; ok {
+			id = d.entryValue(entry)
+		}
+		for _, entry := range d.sortedEntries(block.path + ".") {
+			if !known[normalizeKey(entry.key)] {
+				extras[id] = append(extras[id], d.lines[entry.start:entry.end]...)
+			}
+		}
+	}
+
+	// 渲染新元素
+	var rendered []string
+	for i, t := range tables {
+		m, ok := t.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if i > 0 {
+			rendered = append(rendered, "")
+		}
+		rendered = append(rendered, renderTableArrayElement(base, m)...)
+
+		id := fmt.Sprintf("#%d", i)
+		if mint, ok := m["mint"].(string); ok {
+			id = strconv.Quote(mint)
+		}
+		rendered = append(rendered, extras[id]...)
+	}
+
+	// 同名的标量键（空数组）也一并移除
+	if entry, ok := d.entries[base]; ok {
+		d.spliceLines(entry.start, entry.end, nil)
+		blocks = d.arrayBlocks(base)
+	}
+
+	if len(blocks) == 0 {
+		if len(rendered) == 0 {
+			return
+		}
+		parent := parentPath(base)
+		for _, table := range d.tables {
+			if !table.array && table.path == parent && parent != "" {
+				d.insertBlock(d.trimBlankTail(table.header, table.end), rendered)
+				return
+			}
+		}
+		d.appendBlock(rendered...)
+		return
+	}
+
+	// 从后往前删除旧元素，再在第一个元素的位置插入新元素
+	insertAt := blocks[0].header
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+		end := d.trimBlankTail(block.header, block.end)
+		if i > 0 {
+			// 连同前面的空行一起删除，避免留下多余的空行
+			start := block.header
+			for start > 0 && strings.TrimSpace(d.lines[start-1]) == "" {
+				start--
+			}
+			d.spliceLines(start, end, nil)
+		} else {
+			d.spliceLines(block.header, end, nil)
+		}
+	}
+
+	if len(rendered) == 0 {
+		// 删除留下的空行
+		for insertAt < len(d.lines) && insertAt > 0 &&
+			strings.TrimSpace(d.lines[insertAt]) == "" && strings.TrimSpace(d.lines[insertAt-1]) == "" {
+			d.spliceLines(insertAt, insertAt+1, nil)
+		}
+		return
+	}
+	d.spliceLines(insertAt, insertAt, rendered)
+}
+
+// entryValue 返回单行键值对的值文本（去掉行尾注释）
+func (d *tomlDocument) entryValue(entry *tomlEntry) string {
+	line := d.lines[entry.start]
+	value := line[indexOutsideQuotes(line, '=')+1:]
+	if comment := indexOutsideQuotes(value, '#'); comment >= 0 {
+		value = value[:comment]
+	}
+	return strings.TrimSpace(value)
+}
+
+// sortedEntries 返回路径以prefix开头的键值对，按行号排序
+func (d *tomlDocument) sortedEntries(prefix string) []*tomlEntry {
+	var result []*tomlEntry
+	for p, entry := range d.entries {
+		if strings.HasPrefix(p, prefix) {
+			result = append(result, entry)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].start < result[j].start })
+	return result
+}
+
+// trimBlankTail 返回去掉末尾空行后的结束行
+func (d *tomlDocument) trimBlankTail(start, end int) int {
+	for end > start+1 && strings.TrimSpace(d.lines[end-1]) == "" {
+		end--
+	}
+	return end
+}
+
+// insertLines 在指定行前插入一行
+func (d *tomlDocument) insertLines(at int, line string) {
+	d.spliceLines(at, at, strings.Split(line, "\n"))
+}
+
+// insertBlock 在指定行前插入一段内容，前后用空行分隔
+func (d *tomlDocument) insertBlock(at int, block []string) {
+	lines := append([]string{""}, block...)
+	if at < len(d.lines) && strings.TrimSpace(d.lines[at]) != "" {
+		lines = append(lines, "")
+	}
+	d.spliceLines(at, at, lines)
+}
+
+// appendBlock 在文档末尾追加一段内容
+func (d *tomlDocument) appendBlock(block ...string) {
+	// 文档以换行结尾时最后一个元素为空字符串，在它之前插入
+	at := len(d.lines)
+	if at > 0 && d.lines[at-1] == "" {
+		at--
+	}
+	lines := block
+	if at > 0 && strings.TrimSpace(d.lines[at-1]) != "" {
+		lines = append([]string{""}, block...)
+	}
+	d.spliceLines(at, at, lines)
+}
+
+// spliceLines 用新行替换 [start, end) 范围内的行，并重建索引
+func (d *tomlDocument) spliceLines(start, end int, lines []string) {
+	result := make([]string, 0, len(d.lines)-(end-start)+len(lines))
+	result = append(result, d.lines[:start]...)
+	result = append(result, lines...)
+	result = append(result, d.lines[end:]...)
+	d.lines = result
+	d.index()
+}
+
+// scanState 跟踪值跨行扫描时的括号深度和字符串状态
+type scanState struct {
+	depth       int
+	multiBasic  bool // 位于 """ 多行字符串中
+	multiString bool // 位于 ''' 多行字符串中
+}
+
+// complete 判断值是否已经结束
+func (s *scanState) complete() bool {
+	return s.depth <= 0 && !s.multiBasic && !s.multiString
+}
+
+// scan 扫描一行值文本
+func (s *scanState) scan(line string) {
+	for i := 0; i < len(line); i++ {
+		switch {
+		case s.multiBasic:
+			if line[i] == '\\' {
+				i++
+			} else if strings.HasPrefix(line[i:], `"""`) {
+				s.multiBasic = false
+				i += 2
+			}
+		case s.multiString:
+			if strings.HasPrefix(line[i:], `'''`) {
+				s.multiString = false
+				i += 2
+			}
+		case strings.HasPrefix(line[i:], `"""`):
+			s.multiBasic = true
+			i += 2
+		case strings.HasPrefix(line[i:], `'''`):
+			s.multiString = true
+			i += 2
+		case line[i] == '"' || line[i] == '\'':
+			i = skipString(line, i)
+		case line[i] == '#':
+			return
+		case line[i] == '[' || line[i] == '{':
+			s.depth++
+		case line[i] == ']' || line[i] == '}':
+			s.depth--
+		}
+	}
+}
+
+// skipString 跳过从quote位置开始的单行字符串，返回结束引号的位置
+func skipString(line string, quote int) int {
+	q := line[quote]
+	for i := quote + 1; i < len(line); i++ {
+		if q == '"' && line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] == q {
+			return i
+		}
+	}
+	return len(line)
+}
+
+// indexOutsideQuotes 返回字符c在引号之外第一次出现的位置
+func indexOutsideQuotes(line string, c byte) int {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"', '\'':
+			i = skipString(line, i)
+		case c:
+			return i
+		}
+	}
+	return -1
+}
+
+// parseTableHeader 解析表头，返回表名和是否为表数组
+func parseTableHeader(line string) (string, bool) {
+	array := strings.HasPrefix(line, "[[")
+	inner := strings.TrimPrefix(line, "[")
+	if array {
+		inner = strings.TrimPrefix(inner, "[")
+	}
+	if end := indexOutsideQuotes(inner, ']'); end >= 0 {
+		inner = inner[:end]
+	}
+	return normalizeKey(inner), array
+}
+
+// normalizeKey 规范化键：去掉点分键各段周围的空白和引号
+func normalizeKey(key string) string {
+	var parts []string
+	for {
+		dot := indexOutsideQuotes(key, '.')
+		part := key
+		if dot >= 0 {
+			part = key[:dot]
+		}
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && (part[0] == '"' || part[0] == '\'') && part[len(part)-1] == part[0] {
+			part = part[1 : len(part)-1]
+		}
+		parts = append(parts, part)
+		if dot < 0 {
+			break
+		}
+		key = key[dot+1:]
+	}
+	return strings.Join(parts, ".")
+}
+
+// parentPath 返回路径的父路径
+func parentPath(path string) string {
+	if idx := strings.LastIndex(path, "."); idx >= 0 {
+		return path[:idx]
+	}
+	return ""
+}
+
+// lastSegment 返回路径的最后一段
+func lastSegment(path string) string {
+	return path[strings.LastIndex(path, ".")+1:]
+}
+
+// arrayBase 返回表数组路径的基础部分，如 routing.mint_config_list[0].mint -> routing.mint_config_list
+func arrayBase(path string) string {
+	if idx := strings.Index(path, "["); idx >= 0 {
+		return path[:idx]
+	}
+	return path
+}
+
+// configToMap 将配置转换为TOML树对应的map
+func configToMap(config *Config) (map[string]interface{}, error) {
+	data, err := toml.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := toml.LoadBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return tree.ToMap(), nil
+}
+
+// lookupPath 按点分路径查找map中的值
+func lookupPath(tree map[string]interface{}, path string) interface{} {
+	var current interface{} = tree
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+	return current
+}
+
+// formatTOMLKey 格式化键，非裸键时加引号
+func formatTOMLKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return formatTOMLString(key)
+		}
+	}
+	return key
+}
+
+// formatTOMLValue 格式化值；包含多个元素的数组按每行一个元素输出
+func formatTOMLValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return `""`
+	case string:
+		return formatTOMLString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return "nan"
+		case math.IsInf(v, 1):
+			return "inf"
+		case math.IsInf(v, -1):
+			return "-inf"
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		return s
+	case []interface{}:
+		if len(v) == 0 {
+			return "[]"
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = formatTOMLValue(item)
+		}
+		if len(items) == 1 {
+			return "[" + items[0] + "]"
+		}
+		return "[\n  " + strings.Join(items, ",\n  ") + ",\n]"
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		items := make([]string, len(keys))
+		for i, k := range keys {
+			items[i] = formatTOMLKey(k) + " = " + strings.ReplaceAll(formatTOMLValue(v[k]), "\n", " ")
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// formatTOMLString 格式化为TOML基本字符串
+func formatTOMLString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// clone 返回文档的副本
+func (d *tomlDocument) clone() *tomlDocument {
+	c := &tomlDocument{
+		lines:   append([]string(nil), d.lines...),
+		newline: d.newline,
+	}
+	c.index()
+	return c
+}
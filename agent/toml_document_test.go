@@ -0,0 +1,330 @@
+package agent
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+const (
+	testMintA = "9BB6NFEcjBCtnNLFko2FqVQBq8HHM13kCyYcdQbgpump"
+	testMintB = "So11111111111111111111111111111111111111112"
+	testPump  = "pAMMBay6oceH9fJKBRHGP5D4bD4sWpmSwMn52FMfXEA"
+)
+
+// applyToDocument 解析data，用edit修改配置后按字段改写文档，并确认结果解析后与修改后的配置一致
+func applyToDocument(t *testing.T, data []byte, edit func(*Config)) string {
+	t.Helper()
+	old, err := ParseConfig(data)
+	if err != nil {
+		t.Fatalf("解析配置失败: %v", err)
+	}
+	updated := old.Copy()
+	edit(updated)
+
+	out, err := parseTOMLDocument(data).Apply(old, updated)
+	if err != nil {
+		t.Fatalf("Apply失败: %v", err)
+	}
+	parsed, err := ParseConfig(out)
+	if err != nil {
+		t.Fatalf("改写后的文档无法解析: %v\n%s", err, out)
+	}
+	changes, err := DiffConfigs(parsed, updated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) > 0 {
+		t.Fatalf("改写后的文档与目标配置不一致: %+v\n%s", changes, out)
+	}
+	return string(out)
+}
+
+// replaceOnce 替换text中唯一出现的old，用于从原文构造期望结果
+func replaceOnce(t *testing.T, text, old, new string) string {
+	t.Helper()
+	if n := strings.Count(text, old); n != 1 {
+		t.Fatalf("期望 %q 出现1次，实际 %d 次", old, n)
+	}
+	return strings.Replace(text, old, new, 1)
+}
+
+func TestTOMLDocumentTemplate(t *testing.T) {
+	data, err := os.ReadFile("../config.template.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := string(data)
+
+	newMintBlock := `[[routing.mint_config_list]]
+lookup_table_accounts = []
+meteora_dlmm_pool_list = []
+mint = "` + testMintB + `"
+process_delay = 1000
+pump_pool_list = ["` + testPump + `"]
+raydium_cp_pool_list = []
+raydium_pool_list = []`
+
+	tests := []struct {
+		name string
+		edit func(*Config)
+		want func(string) string
+	}{
+		{
+			name: "unchanged",
+			edit: func(c *Config) {},
+			want: func(s string) string { return s },
+		},
+		{
+			name: "scalar",
+			edit: func(c *Config) {
+				c.RPC.URL = "https://rpc.example.com"
+				c.Jito.TipConfig.Count = 5
+				c.Bot.MergeMints = true
+			},
+			want: func(s string) string {
+				s = replaceOnce(t, s, "url = \"https://api.mainnet-beta.solana.com\"", "url = \"https://rpc.example.com\"")
+				s = replaceOnce(t, s, "count = 3", "count = 5")
+				return replaceOnce(t, s, "merge_mints = false", "merge_mints = true")
+			},
+		},
+		{
+			name: "array",
+			edit: func(c *Config) {
+				c.Jito.BlockEngineURLs = c.Jito.BlockEngineURLs[:2]
+				c.Jito.IPAddresses = []string{"10.0.0.1"}
+			},
+			want: func(s string) string {
+				s = replaceOnce(t, s, `block_engine_urls = [
+  "https://ny.mainnet.block-engine.jito.wtf/api/v1",
+  "https://tokyo.mainnet.block-engine.jito.wtf/api/v1",
+  "https://slc.mainnet.block-engine.jito.wtf/api/v1",
+  "https://amsterdam.mainnet.block-engine.jito.wtf/api/v1",
+  "https://frankfurt.mainnet.block-engine.jito.wtf/api/v1",
+]`, `block_engine_urls = [
+  "https://ny.mainnet.block-engine.jito.wtf/api/v1",
+  "https://tokyo.mainnet.block-engine.jito.wtf/api/v1",
+]`)
+				return replaceOnce(t, s, "ip_addresses = []", `ip_addresses = ["10.0.0.1"]`)
+			},
+		},
+		{
+			name: "mint pool list",
+			edit: func(c *Config) {
+				c.Routing.MintConfigList[0].PumpPoolList = []string{testPump}
+				c.Routing.MintConfigList[0].ProcessDelay = 500
+			},
+			want: func(s string) string {
+				s = replaceOnce(t, s, `pump_pool_list = [
+  "AmmpSnW5xVeKHTAU9fMjyKEMPgrzmUj3ah5vgvHhAB5J",
+  "pAMMBay6oceH9fJKBRHGP5D4bD4sWpmSwMn52FMfXEA",
+]`, `pump_pool_list = ["`+testPump+`"]`)
+				return replaceOnce(t, s, "process_delay = 1000", "process_delay = 500")
+			},
+		},
+		{
+			name: "add mint",
+			edit: func(c *Config) {
+				c.Routing.MintConfigList = append(c.Routing.MintConfigList, MintConfig{
+					Mint:                testMintB,
+					PumpPoolList:        []string{testPump},
+					LookupTableAccounts: []string{},
+					ProcessDelay:        1000,
+				})
+			},
+			want: func(s string) string {
+				last := `raydium_pool_list = ["675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8"]`
+				return replaceOnce(t, s, last+"\n", last+"\n\n"+newMintBlock+"\n")
+			},
+		},
+		{
+			name: "add mint first",
+			edit: func(c *Config) {
+				c.Routing.MintConfigList = append([]MintConfig{{
+					Mint:                testMintB,
+					PumpPoolList:        []string{testPump},
+					LookupTableAccounts: []string{},
+					ProcessDelay:        1000,
+				}}, c.Routing.MintConfigList...)
+			},
+			want: func(s string) string {
+				return replaceOnce(t, s, "[routing]\n\n[[routing.mint_config_list]]\n",
+					"[routing]\n\n"+newMintBlock+"\n\n[[routing.mint_config_list]]\n")
+			},
+		},
+		{
+			name: "remove all mints",
+			edit: func(c *Config) {
+				c.Routing.MintConfigList = nil
+			},
+			want: func(s string) string {
+				start := strings.Index(s, "[[routing.mint_config_list]]")
+				end := strings.Index(s, "[rpc]")
+				return s[:start] + s[end:]
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyToDocument(t, data, tt.edit)
+			if want := tt.want(original); got != want {
+				t.Errorf("改写结果不符合预期\n--- got ---\n%s\n--- want ---\n%s", got, want)
+			}
+		})
+	}
+}
+
+// 带注释、行尾注释和Config未建模的键的配置文件
+const (
+	testDocHead = `# MEV Bot配置，由运维手工维护
+[bot]
+compute_unit_limit = 680000 # 计算单元上限
+custom_flag = true          # Config未建模的键
+merge_mints = false
+
+[jito]
+block_engine_urls = [
+  "https://ny.mainnet.block-engine.jito.wtf/api/v1", # 纽约
+]
+enabled = true
+ip_addresses = []
+uuid = ""
+
+[jito.tip_config]
+count = 3
+from = 10000
+strategy = "Random"
+to = 100000
+
+[kamino_flashloan]
+enabled = true
+
+[routing]
+
+`
+	testDocMintA = `[[routing.mint_config_list]]
+# 主交易代币
+mint = "` + testMintA + `"
+pump_pool_list = ["AmmpSnW5xVeKHTAU9fMjyKEMPgrzmUj3ah5vgvHhAB5J"] # 主池
+raydium_pool_list = []
+raydium_cp_pool_list = []
+meteora_dlmm_pool_list = []
+lookup_table_accounts = []
+process_delay = 1000
+note = "手动添加"
+`
+	testDocMintB = `[[routing.mint_config_list]]
+mint = "` + testMintB + `"
+pump_pool_list = ["` + testPump + `"]
+raydium_pool_list = []
+raydium_cp_pool_list = []
+meteora_dlmm_pool_list = []
+lookup_table_accounts = []
+process_delay = 2000
+`
+	testDocTail = `
+[rpc]
+url = "https://rpc.example.com" # 主RPC
+
+[spam]
+enable_simple_send = false
+enabled = true
+max_retries = 10
+sending_rpc_urls = ["https://send.example.com"]
+
+[spam.compute_unit_price]
+count = 1
+from = 100
+strategy = "Random"
+to = 100
+
+[telemetry]
+# 整个表都不在Config中
+endpoint = "http://localhost:9000"
+
+[wallet]
+private = ""
+`
+)
+
+func TestTOMLDocumentKeepsCommentsAndUnknownKeys(t *testing.T) {
+	original := testDocHead + testDocMintA + "\n" + testDocMintB + testDocTail
+
+	tests := []struct {
+		name string
+		edit func(*Config)
+		want string
+	}{
+		{
+			name: "unchanged",
+			edit: func(c *Config) {},
+			want: original,
+		},
+		{
+			name: "scalar with trailing comment",
+			edit: func(c *Config) {
+				c.Bot.ComputeUnitLimit = 700000
+				c.RPC.URL = "https://other.example.com"
+			},
+			want: strings.NewReplacer(
+				"compute_unit_limit = 680000 # 计算单元上限", "compute_unit_limit = 700000 # 计算单元上限",
+				`url = "https://rpc.example.com" # 主RPC`, `url = "https://other.example.com" # 主RPC`,
+			).Replace(original),
+		},
+		{
+			name: "edit one mint",
+			edit: func(c *Config) {
+				c.Routing.MintConfigList[0].PumpPoolList = append(c.Routing.MintConfigList[0].PumpPoolList, testPump)
+				c.Routing.MintConfigList[1].ProcessDelay = 3000
+			},
+			want: strings.NewReplacer(
+				`pump_pool_list = ["AmmpSnW5xVeKHTAU9fMjyKEMPgrzmUj3ah5vgvHhAB5J"] # 主池`,
+				"pump_pool_list = [\n  \"AmmpSnW5xVeKHTAU9fMjyKEMPgrzmUj3ah5vgvHhAB5J\",\n  \""+testPump+"\",\n] # 主池",
+				"process_delay = 2000", "process_delay = 3000",
+			).Replace(original),
+		},
+		{
+			name: "remove first mint",
+			edit: func(c *Config) {
+				c.Routing.MintConfigList = c.Routing.MintConfigList[1:]
+			},
+			want: testDocHead + testDocMintB + testDocTail,
+		},
+		{
+			name: "remove last mint",
+			edit: func(c *Config) {
+				c.Routing.MintConfigList = c.Routing.MintConfigList[:1]
+			},
+			want: testDocHead + testDocMintA + testDocTail,
+		},
+		{
+			name: "swap mints",
+			edit: func(c *Config) {
+				list := c.Routing.MintConfigList
+				list[0], list[1] = list[1], list[0]
+			},
+			want: testDocHead + testDocMintB + "\n" + testDocMintA + testDocTail,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyToDocument(t, []byte(original), tt.edit)
+			if got != tt.want {
+				t.Errorf("改写结果不符合预期\n--- got ---\n%s\n--- want ---\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTOMLDocumentCRLF(t *testing.T) {
+	original := strings.ReplaceAll(testDocHead+testDocMintA+testDocTail, "\n", "\r\n")
+	got := applyToDocument(t, []byte(original), func(c *Config) {
+		c.Jito.Enabled = false
+	})
+	want := replaceOnce(t, original, "enabled = true\r\nip_addresses", "enabled = false\r\nip_addresses")
+	if got != want {
+		t.Errorf("改写结果不符合预期\n--- got ---\n%q\n--- want ---\n%q", got, want)
+	}
+}
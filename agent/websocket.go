@@ -1,71 +1,107 @@
 package agent
 
 import (
+	"crypto/tls"
 	"encoding/json"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 // WebSocketServer 提供WebSocket服务
 type WebSocketServer struct {
-	addr      string
-	upgrader  websocket.Upgrader
-	clients   map[*websocket.Conn]bool
-	broadcast chan string
-	agent     *Agent
-	server    *http.Server
-	mu        sync.Mutex
+	config      ServerConfig
+	auth        *Authenticator
+	upgrader    websocket.Upgrader
+	checkOrigin func(r *http.Request) bool // WebSocket升级和REST接口共用的来源检查
+	clients     map[*wsClient]bool
+	broadcast   chan string
+	agent       *Agent
+	server      *http.Server
+	mu          sync.Mutex             // 保护clients
+	commands    map[string]commandSpec // 已注册的命令，键为 type/action
 }
 
 // Command 表示WebSocket命令
 type Command struct {
+	ID      string          `json:"id,omitempty"` // 客户端指定的请求ID，原样返回在响应中
 	Type    string          `json:"type"`
 	Action  string          `json:"action"`
 	Section string          `json:"section,omitempty"`
 	Key     string          `json:"key,omitempty"`
 	Value   json.RawMessage `json:"value,omitempty"`
+	Reason  string          `json:"reason,omitempty"` // 修改配置的原因，记录到配置历史
 }
 
 // NewWebSocketServer 创建新的WebSocket服务器
-func NewWebSocketServer(addr string, agent *Agent) *WebSocketServer {
-	return &WebSocketServer{
-		addr: addr,
+func NewWebSocketServer(config ServerConfig, auth *Authenticator, agent *Agent) *WebSocketServer {
+	checkOrigin := newOriginChecker(config.AllowedOrigins)
+	ws := &WebSocketServer{
+		config: config,
+		auth:   auth,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
-			CheckOrigin: func(r *http.Request) bool {
-				return true // 允许所有来源的连接
-			},
+			CheckOrigin:     checkOrigin,
 		},
-		clients:   make(map[*websocket.Conn]bool),
-		broadcast: make(chan string, 100),
-		agent:     agent,
+		checkOrigin: checkOrigin,
+		clients:     make(map[*wsClient]bool),
+		broadcast:   make(chan string, 100),
+		agent:       agent,
+		commands:    make(map[string]commandSpec),
 	}
+	ws.registerCommands()
+	return ws
 }
 
 // Start 启动WebSocket服务器
+// 监听失败、TLS配置错误或在非回环地址上明文监听时返回错误
 func (ws *WebSocketServer) Start() error {
+	if err := checkListenSecurity(ws.config); err != nil {
+		return err
+	}
+	tlsConfig, err := buildTLSConfig(ws.config.TLS)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", ws.config.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %w", ws.config.ListenAddr, err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
 	mux := http.NewServeMux()
 
 	// WebSocket端点
 	mux.HandleFunc("/ws", ws.handleConnections)
 
+	// REST接口
+	ws.registerRoutes(mux)
+
 	// 启动广播器
 	go ws.broadcastMessages()
 
 	// 创建HTTP服务器
 	ws.server = &http.Server{
-		Addr:    ws.addr,
-		Handler: mux,
+		Addr:      ws.config.ListenAddr,
+		Handler:   mux,
+		TLSConfig: tlsConfig,
 	}
 
 	// 启动HTTP服务器
 	go func() {
-		log.Printf("WebSocket服务器开始监听: %s", ws.addr)
-		if err := ws.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("WebSocket服务器开始监听: %s (TLS: %v)", listener.Addr(), tlsConfig != nil)
+		if err := ws.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			log.Printf("WebSocket服务器错误: %v", err)
 		}
 	}()
@@ -84,11 +120,9 @@ func (ws *WebSocketServer) Stop() error {
 // handleConnections 处理新的WebSocket连接
 func (ws *WebSocketServer) handleConnections(w http.ResponseWriter, r *http.Request) {
 	// 验证token
-	token := r.URL.Query().Get("token")
-	expectedToken := ws.agent.agentConfig.Wechat.VerifyToken
-
-	if expectedToken != "" && token != expectedToken {
-		log.Printf("WebSocket连接验证失败: 无效的token")
+	principal, ok := ws.auth.Authenticate(r)
+	if !ok {
+		log.Printf("WebSocket连接验证失败: 无效的token (%s)", r.RemoteAddr)
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -101,34 +135,39 @@ func (ws *WebSocketServer) handleConnections(w http.ResponseWriter, r *http.Requ
 	}
 
 	// 注册新客户端
+	client := newWSClient(conn, principal, ws.config)
 	ws.mu.Lock()
-	ws.clients[conn] = true
+	ws.clients[client] = true
 	ws.mu.Unlock()
 
 	// 发送欢迎消息
-	conn.WriteJSON(map[string]string{
-		"type":    "system",
-		"message": "已连接到MEV Bot代理",
+	client.reply(Event{
+		Type:    "system",
+		Message: "已连接到MEV Bot代理",
+		Data: map[string]interface{}{
+			"protocol_version": ProtocolVersion,
+			"role":             principal.Role,
+		},
 	})
 
 	// 处理客户端消息
-	go ws.handleMessages(conn)
+	go ws.handleMessages(client)
 }
 
 // handleMessages 处理来自客户端的消息
-func (ws *WebSocketServer) handleMessages(conn *websocket.Conn) {
+func (ws *WebSocketServer) handleMessages(client *wsClient) {
 	defer func() {
 		// 客户端断开连接时清理
 		ws.mu.Lock()
-		delete(ws.clients, conn)
+		delete(ws.clients, client)
 		ws.mu.Unlock()
-		conn.Close()
+		client.close()
 	}()
 
+	client.prepareRead()
 	for {
 		// 读取消息
-		var cmd Command
-		err := conn.ReadJSON(&cmd)
+		_, data, err := client.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket错误: %v", err)
@@ -136,140 +175,454 @@ func (ws *WebSocketServer) handleMessages(conn *websocket.Conn) {
 			break
 		}
 
+		// 无法解析的消息返回错误响应，不断开连接
+		var cmd Command
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			client.reply(&Response{
+				Type:  "response",
+				Error: &ErrorInfo{Code: ErrCodeBadRequest, Message: "无法解析命令: " + err.Error()},
+			})
+			continue
+		}
+
 		// 处理命令
-		ws.handleCommand(conn, &cmd)
+		ws.handleCommand(client, &cmd)
 	}
 }
 
-// handleCommand 处理客户端发送的命令
-func (ws *WebSocketServer) handleCommand(conn *websocket.Conn, cmd *Command) {
-	log.Printf("收到命令: %+v", cmd)
+// commandRequest 一次命令调用的上下文
+type commandRequest struct {
+	cmd       *Command
+	principal *Principal // 通过认证的调用方
+	remote    string     // 调用方地址
+	client    *wsClient  // 通过WebSocket调用时的连接，HTTP调用时为nil
+}
 
-	response := map[string]interface{}{
-		"type":   "response",
-		"action": cmd.Action,
-	}
+// commandFunc 命令处理函数，返回响应数据
+type commandFunc func(req *commandRequest) (interface{}, error)
 
-	var err error
+// commandSpec 注册的命令
+type commandSpec struct {
+	handler  commandFunc
+	role     Role   // 执行命令所需的最低角色
+	message  string // 成功时的提示信息
+	revision bool   // 成功时在响应中返回当前配置版本号
+}
+
+// register 注册命令处理程序
+func (ws *WebSocketServer) register(cmdType, action string, spec commandSpec) {
+	ws.commands[cmdType+"/"+action] = spec
+}
 
-	switch cmd.Type {
-	case "config":
-		switch cmd.Action {
-		case "get":
-			// 获取当前配置
-			response["data"] = ws.agent.mevConfig
-		case "update":
-			// 更新配置
-			err = ws.handleConfigUpdate(cmd)
+// registerCommands 注册所有支持的命令
+func (ws *WebSocketServer) registerCommands() {
+	ws.register("hello", "", commandSpec{handler: ws.handleHello, role: RoleViewer})
+
+	// 配置
+	ws.register("config", "get", commandSpec{
+		role: RoleViewer,
+		handler: func(req *commandRequest) (interface{}, error) {
+			// 敏感字段脱敏
+			return RedactConfig(ws.agent.store.Current()), nil
+		},
+		revision: true,
+	})
+	ws.register("config", "update", commandSpec{
+		role: RoleAdmin,
+		handler: func(req *commandRequest) (interface{}, error) {
+			return nil, ws.handleConfigUpdate(req.cmd, ws.changeInfo(req))
+		},
+		message:  "配置已更新",
+		revision: true,
+	})
+	ws.register("config", "updateSection", commandSpec{
+		role: RoleAdmin,
+		handler: func(req *commandRequest) (interface{}, error) {
+			return nil, ws.handleSectionUpdate(req.cmd, ws.changeInfo(req))
+		},
+		message:  "配置节已更新",
+		revision: true,
+	})
+	ws.register("config", "addMint", commandSpec{
+		role: RoleOperator,
+		handler: func(req *commandRequest) (interface{}, error) {
+			return nil, ws.handleAddMint(req.cmd, ws.changeInfo(req))
+		},
+		message:  "铸币配置已添加",
+		revision: true,
+	})
+	ws.register("config", "removeMint", commandSpec{
+		role: RoleOperator,
+		handler: func(req *commandRequest) (interface{}, error) {
+			return nil, ws.handleRemoveMint(req.cmd, ws.changeInfo(req))
+		},
+		message:  "铸币配置已删除",
+		revision: true,
+	})
+	ws.register("config", "history", commandSpec{
+		role: RoleViewer,
+		handler: func(req *commandRequest) (interface{}, error) {
+			return ws.handleConfigHistory(req.cmd)
+		},
+		revision: true,
+	})
+	ws.register("config", "diff", commandSpec{
+		role: RoleViewer,
+		handler: func(req *commandRequest) (interface{}, error) {
+			changes, err := ws.handleConfigDiff(req.cmd)
 			if err != nil {
-				response["error"] = err.Error()
-			} else {
-				response["message"] = "配置已更新"
+				return nil, err
 			}
-		case "updateSection":
-			// 更新配置节
-			err = ws.handleSectionUpdate(cmd)
-			if err != nil {
-				response["error"] = err.Error()
-			} else {
-				response["message"] = "配置节已更新"
+			return RedactChanges(changes), nil
+		},
+		revision: true,
+	})
+	ws.register("config", "rollback", commandSpec{
+		role: RoleAdmin,
+		handler: func(req *commandRequest) (interface{}, error) {
+			// 回滚到指定配置版本并重启MEV Bot
+			return nil, ws.handleConfigRollback(req.cmd, ws.changeInfo(req))
+		},
+		message:  "配置已回滚",
+		revision: true,
+	})
+
+	// MEV Bot
+	ws.register("bot", "status", commandSpec{
+		role: RoleViewer,
+		handler: func(req *commandRequest) (interface{}, error) {
+			return ws.agent.Status(), nil
+		},
+	})
+	ws.register("bot", "start", commandSpec{
+		role: RoleOperator,
+		handler: func(req *commandRequest) (interface{}, error) {
+			if err := ws.agent.StartMEVBot(); err != nil {
+				return nil, err
 			}
-		case "addMint":
-			// 添加铸币配置
-			err = ws.handleAddMint(cmd)
-			if err != nil {
-				response["error"] = err.Error()
-			} else {
-				response["message"] = "铸币配置已添加"
+			return ws.agent.Status(), nil
+		},
+		message: "MEV Bot已启动",
+	})
+	ws.register("bot", "stop", commandSpec{
+		role: RoleOperator,
+		handler: func(req *commandRequest) (interface{}, error) {
+			if err := ws.agent.StopMEVBot(); err != nil {
+				return nil, err
 			}
-		case "removeMint":
-			// 删除铸币配置
-			err = ws.handleRemoveMint(cmd)
-			if err != nil {
-				response["error"] = err.Error()
-			} else {
-				response["message"] = "铸币配置已删除"
+			return ws.agent.Status(), nil
+		},
+		message: "MEV Bot已停止",
+	})
+	ws.register("bot", "restart", commandSpec{
+		role: RoleOperator,
+		handler: func(req *commandRequest) (interface{}, error) {
+			if err := ws.agent.RestartMEVBot(StopCauseManual); err != nil {
+				return nil, err
 			}
-		}
-	case "bot":
-		switch cmd.Action {
-		case "status":
-			// 请求状态检查
-			ws.agent.statusChecks <- struct{}{}
-			response["message"] = "状态检查已触发"
-		case "restart":
-			// 重启MEV Bot
-			err = ws.agent.RestartMEVBot()
-			if err != nil {
-				response["error"] = err.Error()
-			} else {
-				response["message"] = "MEV Bot已重启"
+			return ws.agent.Status(), nil
+		},
+		message: "MEV Bot已重启",
+	})
+	ws.register("bot", "history", commandSpec{
+		role: RoleViewer,
+		handler: func(req *commandRequest) (interface{}, error) {
+			return ws.handleBotHistory(req.cmd)
+		},
+	})
+	ws.register("bot", "updateRPC", commandSpec{
+		role: RoleAdmin,
+		handler: func(req *commandRequest) (interface{}, error) {
+			return nil, ws.handleUpdateRPC(req.cmd, ws.changeInfo(req))
+		},
+		message:  "RPC地址已更新",
+		revision: true,
+	})
+	ws.register("bot", "toggleFeature", commandSpec{
+		role: RoleOperator,
+		handler: func(req *commandRequest) (interface{}, error) {
+			return nil, ws.handleToggleFeature(req.cmd, ws.changeInfo(req))
+		},
+		message:  "功能状态已更新",
+		revision: true,
+	})
+
+	// 审计日志
+	ws.register("audit", "query", commandSpec{
+		role: RoleOperator,
+		handler: func(req *commandRequest) (interface{}, error) {
+			var query AuditQuery
+			if len(req.cmd.Value) > 0 {
+				if err := json.Unmarshal(req.cmd.Value, &query); err != nil {
+					return nil, err
+				}
 			}
-		case "updateRPC":
-			// 更新RPC地址
-			err = ws.handleUpdateRPC(cmd)
-			if err != nil {
-				response["error"] = err.Error()
-			} else {
-				response["message"] = "RPC地址已更新"
+			return ws.agent.audit.Query(query)
+		},
+	})
+
+	// 日志
+	ws.register("logs", "tail", commandSpec{
+		role: RoleViewer,
+		handler: func(req *commandRequest) (interface{}, error) {
+			return ws.handleLogsTail(req.cmd)
+		},
+	})
+	ws.register("logs", "follow", commandSpec{
+		role: RoleViewer,
+		handler: func(req *commandRequest) (interface{}, error) {
+			if req.client == nil {
+				return nil, newCommandError(ErrCodeBadRequest, "实时日志只能通过WebSocket订阅")
 			}
-		case "toggleFeature":
-			// 切换功能开关
-			err = ws.handleToggleFeature(cmd)
-			if err != nil {
-				response["error"] = err.Error()
-			} else {
-				response["message"] = "功能状态已更新"
+			ws.startFollowing(req.client)
+			return nil, nil
+		},
+		message: "已开始跟踪MEV Bot日志",
+	})
+	ws.register("logs", "unfollow", commandSpec{
+		role: RoleViewer,
+		handler: func(req *commandRequest) (interface{}, error) {
+			if req.client != nil {
+				req.client.stopFollowing()
 			}
+			return nil, nil
+		},
+		message: "已停止跟踪MEV Bot日志",
+	})
+}
+
+// commandNames 返回角色有权执行的命令名，按字母排序
+func (ws *WebSocketServer) commandNames(role Role) []string {
+	names := make([]string, 0, len(ws.commands))
+	for name, spec := range ws.commands {
+		if role.Allows(spec.role) {
+			names = append(names, strings.TrimSuffix(name, "/"))
 		}
-	default:
-		response["error"] = "未知命令类型"
 	}
+	sort.Strings(names)
+	return names
+}
+
+// handleCommand 处理客户端发送的命令
+func (ws *WebSocketServer) handleCommand(client *wsClient, cmd *Command) {
+	// 命令的值可能包含RPC密钥或私钥，日志中只记录命令本身
+	log.Printf("收到命令: id=%s type=%s action=%s section=%s key=%s value=%d字节",
+		cmd.ID, cmd.Type, cmd.Action, cmd.Section, cmd.Key, len(cmd.Value))
+
+	response := ws.dispatch(&commandRequest{
+		cmd:       cmd,
+		principal: client.principal,
+		remote:    client.RemoteAddr(),
+		client:    client,
+	})
 
 	// 发送响应
-	conn.WriteJSON(response)
+	client.reply(response)
+}
+
+// audit 将命令及其结果记录到审计日志
+func (ws *WebSocketServer) audit(req *commandRequest, response *Response) {
+	entry := AuditEntry{
+		Actor:     req.principal.Name,
+		Remote:    req.remote,
+		Command:   response.Command,
+		RequestID: req.cmd.ID,
+		Payload:   sanitizePayload(req.cmd),
+		Result:    AuditResultOK,
+		Revision:  ws.agent.store.Revision(),
+	}
+	if response.Error != nil {
+		entry.Result = AuditResultError
+		entry.Error = response.Error.Message
+	}
+	ws.agent.audit.Record(entry)
+}
+
+// dispatch 查找并执行命令处理程序，生成响应并记录审计日志
+// WebSocket和HTTP接口共用同一套处理程序、权限检查和校验
+func (ws *WebSocketServer) dispatch(req *commandRequest) *Response {
+	start := time.Now()
+	response := ws.execute(req)
+	ws.audit(req, response)
+
+	// 未知命令统一计入unknown，避免客户端任意的类型和动作产生大量指标
+	command := req.cmd.Type + "/" + req.cmd.Action
+	if _, ok := ws.commands[command]; !ok {
+		command = "unknown/unknown"
+	}
+	ws.agent.metrics.ObserveCommand(command, response.OK, time.Since(start))
+	return response
+}
+
+// execute 执行命令并生成响应
+func (ws *WebSocketServer) execute(req *commandRequest) *Response {
+	cmd := req.cmd
+	name := cmd.Type + "/" + cmd.Action
+	response := &Response{
+		Type:    "response",
+		ID:      cmd.ID,
+		Command: strings.TrimSuffix(name, "/"),
+		Action:  cmd.Action,
+	}
+
+	spec, ok := ws.commands[name]
+	if !ok {
+		response.Error = &ErrorInfo{Code: ErrCodeUnknownCommand, Message: "未知命令: " + response.Command}
+		return response
+	}
+	if principal := req.principal; !principal.Role.Allows(spec.role) {
+		log.Printf("拒绝命令 %s: %s (%s) 的角色 %s 权限不足", response.Command, principal.Name, req.remote, principal.Role)
+		response.Error = &ErrorInfo{Code: ErrCodeForbidden, Message: fmt.Sprintf("角色 %s 无权执行 %s，需要 %s", principal.Role, response.Command, spec.role)}
+		return response
+	}
+
+	data, err := spec.handler(req)
+	if err != nil {
+		response.Error = errorInfoOf(err)
+		return response
+	}
+
+	response.OK = true
+	response.Message = spec.message
+	response.Data = data
+	if spec.revision {
+		response.Revision = ws.agent.store.Revision()
+	}
+	return response
+}
+
+// handleHello 返回协议版本和支持的命令列表
+func (ws *WebSocketServer) handleHello(req *commandRequest) (interface{}, error) {
+	return HelloInfo{
+		ProtocolVersion: ProtocolVersion,
+		Server:          "stonehenge-flash",
+		Role:            req.principal.Role,
+		Commands:        ws.commandNames(req.principal.Role),
+	}, nil
 }
 
 // broadcastMessages 广播消息到所有连接的客户端
 func (ws *WebSocketServer) broadcastMessages() {
 	for {
 		msg := <-ws.broadcast
+		event := Event{
+			Type:    "notification",
+			Message: msg,
+		}
 
-		ws.mu.Lock()
-		for client := range ws.clients {
-			err := client.WriteJSON(map[string]string{
-				"type":    "notification",
-				"message": msg,
-			})
-			if err != nil {
-				log.Printf("广播消息失败: %v", err)
-				client.Close()
-				delete(ws.clients, client)
-			}
+		// 只在复制客户端列表时持有锁，推送不会阻塞，慢客户端不影响其他客户端
+		for _, client := range ws.clientList() {
+			client.push(event)
 		}
-		ws.mu.Unlock()
 	}
 }
 
+// clientList 返回当前所有客户端
+func (ws *WebSocketServer) clientList() []*wsClient {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	clients := make([]*wsClient, 0, len(ws.clients))
+	for client := range ws.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
 // BroadcastMessage 发送消息到所有客户端
+// 不会阻塞：调用方可能持有代理的锁，广播队列已满时丢弃消息并记录日志
 func (ws *WebSocketServer) BroadcastMessage(message string) {
-	ws.broadcast <- message
+	select {
+	case ws.broadcast <- message:
+	default:
+		log.Printf("广播队列已满，丢弃消息: %s", message)
+	}
+}
+
+// changeInfo 根据客户端和命令生成配置修改信息
+func (ws *WebSocketServer) changeInfo(req *commandRequest) ChangeInfo {
+	cmd := req.cmd
+	reason := cmd.Reason
+	if reason == "" {
+		reason = cmd.Type + "/" + cmd.Action
+	}
+	return ChangeInfo{
+		Author: req.principal.Name + "@" + req.remote,
+		Reason: reason,
+	}
+}
+
+// 配置版本历史查询处理程序
+func (ws *WebSocketServer) handleConfigHistory(cmd *Command) ([]ConfigRevision, error) {
+	var historyConfig struct {
+		Limit int `json:"limit"`
+	}
+
+	if len(cmd.Value) > 0 {
+		if err := json.Unmarshal(cmd.Value, &historyConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	return ws.agent.store.History().List(historyConfig.Limit)
+}
+
+// 配置版本比较处理程序，to为空时与当前版本比较
+func (ws *WebSocketServer) handleConfigDiff(cmd *Command) ([]FieldChange, error) {
+	var diffConfig struct {
+		From int64 `json:"from"`
+		To   int64 `json:"to"`
+	}
+
+	if err := json.Unmarshal(cmd.Value, &diffConfig); err != nil {
+		return nil, err
+	}
+	if diffConfig.To == 0 {
+		diffConfig.To = ws.agent.store.Revision()
+	}
+
+	from, err := ws.agent.store.History().Get(diffConfig.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := ws.agent.store.History().Get(diffConfig.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return DiffConfigs(from.Config, to.Config)
+}
+
+// 配置回滚处理程序
+func (ws *WebSocketServer) handleConfigRollback(cmd *Command, info ChangeInfo) error {
+	var rollbackConfig struct {
+		Revision int64 `json:"revision"`
+	}
+
+	if err := json.Unmarshal(cmd.Value, &rollbackConfig); err != nil {
+		return err
+	}
+	if cmd.Reason == "" {
+		info.Reason = ""
+	}
+
+	return ws.agent.RollbackConfig(rollbackConfig.Revision, info)
 }
 
 // 配置更新处理程序
-func (ws *WebSocketServer) handleConfigUpdate(cmd *Command) error {
+func (ws *WebSocketServer) handleConfigUpdate(cmd *Command, info ChangeInfo) error {
 	var updatedConfig Config
 	if err := json.Unmarshal(cmd.Value, &updatedConfig); err != nil {
 		return err
 	}
 
-	return ws.agent.UpdateConfig(&updatedConfig)
+	return ws.agent.UpdateConfig(&updatedConfig, info)
 }
 
 // 配置节更新处理程序
-func (ws *WebSocketServer) handleSectionUpdate(cmd *Command) error {
+func (ws *WebSocketServer) handleSectionUpdate(cmd *Command, info ChangeInfo) error {
 	// 复制当前配置
-	updatedConfig := ws.agent.mevConfig.Copy()
+	updatedConfig := ws.agent.store.Current()
 
 	// 根据节和键更新值
 	var value interface{}
@@ -282,35 +635,35 @@ func (ws *WebSocketServer) handleSectionUpdate(cmd *Command) error {
 	}
 
 	// 保存更新后的配置
-	return ws.agent.UpdateConfig(updatedConfig)
+	return ws.agent.UpdateConfig(updatedConfig, info)
 }
 
 // 添加铸币配置处理程序
-func (ws *WebSocketServer) handleAddMint(cmd *Command) error {
+func (ws *WebSocketServer) handleAddMint(cmd *Command, info ChangeInfo) error {
 	var mintConfig MintConfig
 	if err := json.Unmarshal(cmd.Value, &mintConfig); err != nil {
 		return err
 	}
 
 	// 复制当前配置
-	updatedConfig := ws.agent.mevConfig.Copy()
+	updatedConfig := ws.agent.store.Current()
 
 	// 添加铸币配置
 	updatedConfig.Routing.MintConfigList = append(updatedConfig.Routing.MintConfigList, mintConfig)
 
 	// 保存更新后的配置
-	return ws.agent.UpdateConfig(updatedConfig)
+	return ws.agent.UpdateConfig(updatedConfig, info)
 }
 
 // 删除铸币配置处理程序
-func (ws *WebSocketServer) handleRemoveMint(cmd *Command) error {
+func (ws *WebSocketServer) handleRemoveMint(cmd *Command, info ChangeInfo) error {
 	var mintAddress string
 	if err := json.Unmarshal(cmd.Value, &mintAddress); err != nil {
 		return err
 	}
 
 	// 复制当前配置
-	updatedConfig := ws.agent.mevConfig.Copy()
+	updatedConfig := ws.agent.store.Current()
 
 	// 查找并删除铸币配置
 	newMintList := make([]MintConfig, 0)
@@ -323,11 +676,11 @@ func (ws *WebSocketServer) handleRemoveMint(cmd *Command) error {
 	updatedConfig.Routing.MintConfigList = newMintList
 
 	// 保存更新后的配置
-	return ws.agent.UpdateConfig(updatedConfig)
+	return ws.agent.UpdateConfig(updatedConfig, info)
 }
 
 // 更新RPC地址处理程序
-func (ws *WebSocketServer) handleUpdateRPC(cmd *Command) error {
+func (ws *WebSocketServer) handleUpdateRPC(cmd *Command, info ChangeInfo) error {
 	var rpcConfig struct {
 		URL string `json:"url"`
 	}
@@ -337,17 +690,17 @@ func (ws *WebSocketServer) handleUpdateRPC(cmd *Command) error {
 	}
 
 	// 复制当前配置
-	updatedConfig := ws.agent.mevConfig.Copy()
+	updatedConfig := ws.agent.store.Current()
 
 	// 更新RPC URL
 	updatedConfig.RPC.URL = rpcConfig.URL
 
 	// 保存更新后的配置
-	return ws.agent.UpdateConfig(updatedConfig)
+	return ws.agent.UpdateConfig(updatedConfig, info)
 }
 
 // 切换功能开关处理程序
-func (ws *WebSocketServer) handleToggleFeature(cmd *Command) error {
+func (ws *WebSocketServer) handleToggleFeature(cmd *Command, info ChangeInfo) error {
 	var featureConfig struct {
 		Feature string `json:"feature"`
 		Enabled bool   `json:"enabled"`
@@ -358,7 +711,7 @@ func (ws *WebSocketServer) handleToggleFeature(cmd *Command) error {
 	}
 
 	// 复制当前配置
-	updatedConfig := ws.agent.mevConfig.Copy()
+	updatedConfig := ws.agent.store.Current()
 
 	// 更新功能开关
 	switch featureConfig.Feature {
@@ -373,5 +726,56 @@ func (ws *WebSocketServer) handleToggleFeature(cmd *Command) error {
 	}
 
 	// 保存更新后的配置
-	return ws.agent.UpdateConfig(updatedConfig)
+	return ws.agent.UpdateConfig(updatedConfig, info)
+}
+
+// 运行历史查询处理程序
+func (ws *WebSocketServer) handleBotHistory(cmd *Command) ([]RunRecord, error) {
+	var historyConfig struct {
+		Limit int `json:"limit"`
+	}
+
+	if len(cmd.Value) > 0 {
+		if err := json.Unmarshal(cmd.Value, &historyConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	return ws.agent.history.Recent(historyConfig.Limit), nil
+}
+
+// 获取最近日志处理程序
+func (ws *WebSocketServer) handleLogsTail(cmd *Command) ([]LogLine, error) {
+	var tailConfig struct {
+		Lines int `json:"lines"`
+	}
+
+	if len(cmd.Value) > 0 {
+		if err := json.Unmarshal(cmd.Value, &tailConfig); err != nil {
+			return nil, err
+		}
+	}
+	if tailConfig.Lines <= 0 {
+		tailConfig.Lines = 100
+	}
+
+	return ws.agent.proc.Logs().Tail(tailConfig.Lines), nil
+}
+
+// startFollowing 为客户端订阅实时日志，重复订阅时忽略
+func (ws *WebSocketServer) startFollowing(client *wsClient) {
+	lines, cancel := ws.agent.proc.Logs().Subscribe(256)
+	if !client.startFollowing(cancel) {
+		cancel()
+		return
+	}
+
+	go func() {
+		for line := range lines {
+			client.push(Event{
+				Type: "log",
+				Data: line,
+			})
+		}
+	}()
 }
@@ -0,0 +1,167 @@
+package agent
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// 慢客户端策略：发送队列已满时如何处理推送消息
+const (
+	SlowClientDrop       = "drop"       // 丢弃推送消息，连接保持
+	SlowClientDisconnect = "disconnect" // 断开连接
+)
+
+// 单条客户端消息的最大长度
+const maxMessageSize = 1 << 20
+
+// wsClient 表示一个WebSocket连接
+// gorilla/websocket 不允许并发写，所有写操作都经由发送队列交给writePump串行执行
+type wsClient struct {
+	conn      *websocket.Conn
+	principal *Principal // 通过认证的调用方
+	config    ServerConfig
+	send      chan interface{} // 发送队列
+	done      chan struct{}    // 连接关闭时关闭
+
+	closeOnce sync.Once
+	mu        sync.Mutex
+	follow    func() // 日志订阅的取消函数，未订阅时为nil
+	dropped   int    // 因队列已满被丢弃的推送消息数
+}
+
+// newWSClient 为连接创建客户端并启动写goroutine
+func newWSClient(conn *websocket.Conn, principal *Principal, config ServerConfig) *wsClient {
+	c := &wsClient{
+		conn:      conn,
+		principal: principal,
+		config:    config,
+		send:      make(chan interface{}, config.SendQueue),
+		done:      make(chan struct{}),
+	}
+	go c.writePump()
+	return c
+}
+
+// RemoteAddr 返回客户端地址
+func (c *wsClient) RemoteAddr() string {
+	return c.conn.RemoteAddr().String()
+}
+
+// reply 将命令响应放入发送队列，队列已满时等待，连接关闭时放弃
+// 响应只会阻塞发出命令的这个客户端自己的读goroutine
+func (c *wsClient) reply(v interface{}) {
+	select {
+	case c.send <- v:
+	case <-c.done:
+	}
+}
+
+// push 将推送消息放入发送队列，不会阻塞
+// 队列已满时按慢客户端策略丢弃消息或断开连接
+func (c *wsClient) push(v interface{}) {
+	select {
+	case <-c.done:
+		return
+	default:
+	}
+
+	select {
+	case c.send <- v:
+		return
+	default:
+	}
+
+	if c.config.SlowClientPolicy == SlowClientDrop {
+		c.mu.Lock()
+		c.dropped++
+		dropped := c.dropped
+		c.mu.Unlock()
+		if dropped == 1 || dropped%100 == 0 {
+			log.Printf("客户端 %s 处理过慢，已丢弃%d条推送消息", c.RemoteAddr(), dropped)
+		}
+		return
+	}
+
+	log.Printf("客户端 %s 处理过慢，发送队列已满，断开连接", c.RemoteAddr())
+	c.close()
+}
+
+// close 通知writePump发送关闭帧并断开连接，可以重复调用
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.stopFollowing()
+	})
+}
+
+// startFollowing 记录日志订阅的取消函数，已订阅时返回false
+func (c *wsClient) startFollowing(cancel func()) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.follow != nil {
+		return false
+	}
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+	c.follow = cancel
+	return true
+}
+
+// stopFollowing 取消日志订阅
+func (c *wsClient) stopFollowing() {
+	c.mu.Lock()
+	cancel := c.follow
+	c.follow = nil
+	c.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// writePump 串行写出发送队列中的消息，并定期发送ping
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(time.Duration(c.config.PingInterval) * time.Second)
+	defer func() {
+		ticker.Stop()
+		c.close()
+		// 关闭底层连接，使读goroutine退出
+		c.conn.Close()
+	}()
+
+	writeTimeout := time.Duration(c.config.WriteTimeout) * time.Second
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				log.Printf("向客户端 %s 发送消息失败: %v", c.RemoteAddr(), err)
+				return
+			}
+		case <-ticker.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout)); err != nil {
+				return
+			}
+		case <-c.done:
+			c.conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(writeTimeout))
+			return
+		}
+	}
+}
+
+// prepareRead 设置读取限制和超时，收到pong时延长读取超时
+func (c *wsClient) prepareRead() {
+	pongTimeout := time.Duration(c.config.PongTimeout) * time.Second
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+}
@@ -0,0 +1,285 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newTestAgent 创建只包含命令处理所需组件的代理，MEV Bot进程不会启动
+// 配置文件、配置历史、审计日志和运行历史都放在临时目录中
+func newTestAgent(t *testing.T) *Agent {
+	t.Helper()
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(configPath, []byte(testDocHead+testDocMintA+testDocTail), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	agentConfig := DefaultFlashAgentConfig()
+	agentConfig.ConfigHistory.Dir = filepath.Join(dir, "config_history")
+	agentConfig.Audit.Path = filepath.Join(dir, "audit.log")
+	agentConfig.Process.HistoryPath = filepath.Join(dir, "run_history.json")
+
+	history, err := NewConfigHistory(agentConfig.ConfigHistory.Dir, agentConfig.ConfigHistory.Limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewConfigStore(configPath, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit, err := NewAuditLog(agentConfig.Audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { audit.Close() })
+
+	return &Agent{
+		store:         store,
+		agentConfig:   agentConfig,
+		proc:          NewProcessManager("MEV Bot", "true"),
+		history:       NewRunHistory(agentConfig.Process.HistoryPath, agentConfig.Process.HistorySize),
+		audit:         audit,
+		metrics:       NewMetrics(),
+		restartPolicy: NewRestartPolicy(agentConfig.RestartPolicy),
+		state:         BotStateStopped,
+		stateSince:    time.Now(),
+	}
+}
+
+// newTestServer 启动只提供 /ws 的测试服务器，返回服务器和WebSocket地址
+func newTestServer(t *testing.T, config ServerConfig) (*WebSocketServer, string) {
+	t.Helper()
+	agent := newTestAgent(t)
+	auth, err := NewAuthenticator(agent.agentConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := NewWebSocketServer(config, auth, agent)
+	agent.ws = ws
+	go ws.broadcastMessages()
+
+	srv := httptest.NewServer(http.HandlerFunc(ws.handleConnections))
+	t.Cleanup(srv.Close)
+	return ws, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// testServerConfig 返回测试用的服务配置
+func testServerConfig(queue int, policy string) ServerConfig {
+	config := DefaultFlashAgentConfig().Server
+	config.SendQueue = queue
+	config.SlowClientPolicy = policy
+	config.WriteTimeout = 1
+	return config
+}
+
+// dialTest 连接测试服务器并读掉欢迎消息
+func dialTest(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	var welcome Event
+	if err := conn.ReadJSON(&welcome); err != nil || welcome.Type != "system" {
+		t.Fatalf("没有收到欢迎消息: %+v %v", welcome, err)
+	}
+	return conn
+}
+
+// clientCount 返回服务器当前的客户端数
+func clientCount(ws *WebSocketServer) int {
+	return len(ws.clientList())
+}
+
+// waitFor 等待条件成立，超时后测试失败
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// 多个客户端并发发送命令，同时多个goroutine并发广播；每条命令都必须收到对应的响应
+func TestWSConcurrentBroadcastAndCommands(t *testing.T) {
+	ws, url := newTestServer(t, testServerConfig(256, SlowClientDrop))
+
+	const clients, commands, broadcasters, broadcasts = 4, 50, 4, 50
+	actions := []Command{
+		{Type: "hello"},
+		{Type: "config", Action: "get"},
+		{Type: "bot", Action: "status"},
+		{Type: "config", Action: "history"},
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, clients*2+broadcasters)
+	for c := 0; c < clients; c++ {
+		conn := dialTest(t, url)
+		defer conn.Close()
+
+		// 读goroutine：收集命令响应，广播消息只计数
+		responses := make(chan Response, commands)
+		wg.Add(1)
+		go func(conn *websocket.Conn) {
+			defer wg.Done()
+			for received := 0; received < commands; {
+				_, data, err := conn.ReadMessage()
+				if err != nil {
+					errs <- fmt.Errorf("读取失败: %w", err)
+					return
+				}
+				var msg Response
+				if err := json.Unmarshal(data, &msg); err != nil {
+					errs <- err
+					return
+				}
+				if msg.Type == "response" {
+					responses <- msg
+					received++
+				}
+			}
+			close(responses)
+		}(conn)
+
+		// 写goroutine：同一连接上只有一个写者
+		wg.Add(1)
+		go func(c int, conn *websocket.Conn) {
+			defer wg.Done()
+			for i := 0; i < commands; i++ {
+				cmd := actions[i%len(actions)]
+				cmd.ID = fmt.Sprintf("%d-%d", c, i)
+				if err := conn.WriteJSON(cmd); err != nil {
+					errs <- fmt.Errorf("发送命令失败: %w", err)
+					return
+				}
+			}
+		}(c, conn)
+
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+			seen := make(map[string]bool)
+			for msg := range responses {
+				if !msg.OK {
+					errs <- fmt.Errorf("命令 %s 失败: %+v", msg.ID, msg.Error)
+				}
+				seen[msg.ID] = true
+			}
+			if len(seen) != commands {
+				errs <- fmt.Errorf("客户端 %d 收到 %d 个不同的响应，期望 %d", c, len(seen), commands)
+			}
+		}(c)
+	}
+
+	for b := 0; b < broadcasters; b++ {
+		wg.Add(1)
+		go func(b int) {
+			defer wg.Done()
+			for i := 0; i < broadcasts; i++ {
+				ws.BroadcastMessage(fmt.Sprintf("广播 %d-%d", b, i))
+			}
+		}(b)
+	}
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if n := clientCount(ws); n != clients {
+		t.Errorf("客户端数 %d，期望 %d", n, clients)
+	}
+}
+
+// 不读取消息的客户端在队列满后被断开，其他客户端不受影响
+func TestWSSlowClientDisconnected(t *testing.T) {
+	ws, url := newTestServer(t, testServerConfig(4, SlowClientDisconnect))
+
+	slow := dialTest(t, url)
+	defer slow.Close()
+	waitFor(t, time.Second, "客户端注册", func() bool { return clientCount(ws) == 1 })
+
+	// 消息足够大，使套接字缓冲区很快写满，writePump阻塞后发送队列随之写满
+	payload := strings.Repeat("x", 64<<10)
+	for i := 0; i < 300 && clientCount(ws) > 0; i++ {
+		ws.BroadcastMessage(payload)
+	}
+	waitFor(t, 10*time.Second, "慢客户端被断开", func() bool { return clientCount(ws) == 0 })
+
+	// 新客户端仍然可以正常执行命令
+	conn := dialTest(t, url)
+	defer conn.Close()
+	if err := conn.WriteJSON(Command{ID: "after", Type: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg Response
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("读取响应失败: %v", err)
+		}
+		if msg.Type == "response" {
+			if msg.ID != "after" || !msg.OK {
+				t.Fatalf("响应不符合预期: %+v", msg)
+			}
+			break
+		}
+	}
+}
+
+// drop策略下慢客户端只丢弃推送消息，连接保持，之后仍能收到命令响应
+func TestWSSlowClientDropsPushes(t *testing.T) {
+	ws, url := newTestServer(t, testServerConfig(4, SlowClientDrop))
+
+	slow := dialTest(t, url)
+	defer slow.Close()
+	waitFor(t, time.Second, "客户端注册", func() bool { return clientCount(ws) == 1 })
+	client := ws.clientList()[0]
+
+	dropped := func() int {
+		client.mu.Lock()
+		defer client.mu.Unlock()
+		return client.dropped
+	}
+	payload := strings.Repeat("x", 64<<10)
+	for i := 0; i < 300 && dropped() == 0; i++ {
+		ws.BroadcastMessage(payload)
+	}
+	waitFor(t, 10*time.Second, "推送消息被丢弃", func() bool { return dropped() > 0 })
+	if n := clientCount(ws); n != 1 {
+		t.Fatalf("drop策略下客户端被断开，客户端数 %d", n)
+	}
+
+	// 读掉积压的推送后，命令响应正常返回
+	if err := slow.WriteJSON(Command{ID: "drop", Type: "hello"}); err != nil {
+		t.Fatal(err)
+	}
+	slow.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		var msg Response
+		if err := slow.ReadJSON(&msg); err != nil {
+			t.Fatalf("读取响应失败: %v", err)
+		}
+		if msg.Type == "response" {
+			if msg.ID != "drop" || !msg.OK {
+				t.Fatalf("响应不符合预期: %+v", msg)
+			}
+			break
+		}
+	}
+}
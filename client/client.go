@@ -0,0 +1,279 @@
+// Package client 实现MEV Bot代理WebSocket命令协议的Go客户端，供运维工具使用
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"stonehenge-flash/agent"
+)
+
+// ErrClosed 连接已关闭
+var ErrClosed = errors.New("连接已关闭")
+
+// Response 命令的响应，Data保留原始JSON，由调用方按命令解码
+type Response struct {
+	ID       string           `json:"id"`
+	Command  string           `json:"command"`
+	OK       bool             `json:"ok"`
+	Message  string           `json:"message"`
+	Data     json.RawMessage  `json:"data"`
+	Revision int64            `json:"revision"`
+	Error    *agent.ErrorInfo `json:"error"`
+}
+
+// Decode 将响应数据解码到v
+func (r *Response) Decode(v interface{}) error {
+	if len(r.Data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Data, v)
+}
+
+// Event 服务端主动推送的消息，Data保留原始JSON
+type Event struct {
+	Type    string          `json:"type"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// Client 代理的WebSocket客户端，可以被多个goroutine并发使用
+type Client struct {
+	conn   *websocket.Conn
+	hello  agent.HelloInfo
+	events chan Event
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[string]chan *Response
+	err     error // 读循环退出的原因
+	done    chan struct{}
+}
+
+// Dial 连接代理并完成hello握手
+// url形如 ws://127.0.0.1:8080/ws，header可以为nil
+// 服务端协议版本与客户端不一致时返回错误
+func Dial(ctx context.Context, url string, header http.Header) (*Client, error) {
+	return DialWithDialer(ctx, websocket.DefaultDialer, url, header)
+}
+
+// DialWithDialer 使用指定的Dialer连接代理，用于自定义TLS配置（如mTLS客户端证书）
+func DialWithDialer(ctx context.Context, dialer *websocket.Dialer, url string, header http.Header) (*Client, error) {
+	conn, _, err := dialer.DialContext(ctx, url, header)
+	if err != nil {
+		return nil, fmt.Errorf("连接代理失败: %w", err)
+	}
+
+	c := &Client{
+		conn:    conn,
+		events:  make(chan Event, 256),
+		pending: make(map[string]chan *Response),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+
+	resp, err := c.Call(ctx, "hello", "", nil)
+	if err == nil {
+		err = resp.Decode(&c.hello)
+	}
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("握手失败: %w", err)
+	}
+	if c.hello.ProtocolVersion != agent.ProtocolVersion {
+		c.Close()
+		return nil, fmt.Errorf("协议版本不兼容: 服务端 %d, 客户端 %d", c.hello.ProtocolVersion, agent.ProtocolVersion)
+	}
+	return c, nil
+}
+
+// Hello 返回握手时服务端声明的协议信息
+func (c *Client) Hello() agent.HelloInfo {
+	return c.hello
+}
+
+// Events 返回服务端推送消息的通道，调用方处理不过来时新消息会被丢弃
+// 连接关闭后通道被关闭
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Close 关闭连接
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Do 发送命令并等待对应id的响应，cmd.ID为空时自动分配
+// 命令执行失败时返回 *agent.ErrorInfo，同时返回响应
+func (c *Client) Do(ctx context.Context, cmd agent.Command) (*Response, error) {
+	ch := make(chan *Response, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	if cmd.ID == "" {
+		c.nextID++
+		cmd.ID = strconv.FormatUint(c.nextID, 10)
+	}
+	c.pending[cmd.ID] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, cmd.ID)
+		c.mu.Unlock()
+	}()
+
+	c.writeMu.Lock()
+	err := c.conn.WriteJSON(cmd)
+	c.writeMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("发送命令失败: %w", err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp, resp.Error
+		}
+		return resp, nil
+	case <-c.done:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return nil, c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Call 发送命令，value会被编码为命令的value字段，可以为nil
+func (c *Client) Call(ctx context.Context, cmdType, action string, value interface{}) (*Response, error) {
+	cmd := agent.Command{Type: cmdType, Action: action}
+	if value != nil {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("编码命令参数失败: %w", err)
+		}
+		cmd.Value = data
+	}
+	return c.Do(ctx, cmd)
+}
+
+// GetConfig 获取当前配置（敏感字段为占位符）
+func (c *Client) GetConfig(ctx context.Context) (*agent.Config, int64, error) {
+	resp, err := c.Call(ctx, "config", "get", nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	var config agent.Config
+	if err := resp.Decode(&config); err != nil {
+		return nil, 0, err
+	}
+	return &config, resp.Revision, nil
+}
+
+// UpdateConfig 保存完整配置并重启MEV Bot，返回新的配置版本
+func (c *Client) UpdateConfig(ctx context.Context, config *agent.Config, reason string) (int64, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return 0, fmt.Errorf("编码配置失败: %w", err)
+	}
+	resp, err := c.Do(ctx, agent.Command{Type: "config", Action: "update", Value: data, Reason: reason})
+	if err != nil {
+		return 0, err
+	}
+	return resp.Revision, nil
+}
+
+// RestartBot 重启MEV Bot
+func (c *Client) RestartBot(ctx context.Context) error {
+	_, err := c.Call(ctx, "bot", "restart", nil)
+	return err
+}
+
+// BotHistory 查询最近limit次运行记录，最新的在前
+func (c *Client) BotHistory(ctx context.Context, limit int) ([]agent.RunRecord, error) {
+	resp, err := c.Call(ctx, "bot", "history", map[string]int{"limit": limit})
+	if err != nil {
+		return nil, err
+	}
+	var records []agent.RunRecord
+	return records, resp.Decode(&records)
+}
+
+// TailLogs 获取最近n行MEV Bot日志
+func (c *Client) TailLogs(ctx context.Context, n int) ([]agent.LogLine, error) {
+	resp, err := c.Call(ctx, "logs", "tail", map[string]int{"lines": n})
+	if err != nil {
+		return nil, err
+	}
+	var lines []agent.LogLine
+	return lines, resp.Decode(&lines)
+}
+
+// readLoop 读取服务端消息，响应按id分发给等待的调用，其他消息作为事件推送
+func (c *Client) readLoop() {
+	var err error
+	defer func() {
+		c.mu.Lock()
+		if errors.Is(err, net.ErrClosed) || websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+			err = ErrClosed
+		}
+		c.err = err
+		c.mu.Unlock()
+		close(c.done)
+		close(c.events)
+	}()
+
+	for {
+		var data []byte
+		if _, data, err = c.conn.ReadMessage(); err != nil {
+			return
+		}
+
+		var msg struct {
+			Type string `json:"type"`
+			ID   string `json:"id"`
+		}
+		if json.Unmarshal(data, &msg) != nil {
+			continue
+		}
+
+		if msg.Type != "response" {
+			var event Event
+			if json.Unmarshal(data, &event) == nil {
+				select {
+				case c.events <- event:
+				default:
+				}
+			}
+			continue
+		}
+
+		var resp Response
+		if json.Unmarshal(data, &resp) != nil {
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[resp.ID]
+		c.mu.Unlock()
+		if ok {
+			select {
+			case ch <- &resp:
+			default:
+			}
+		}
+	}
+}
@@ -5,9 +5,6 @@ go 1.18
 require (
 	github.com/gorilla/websocket v1.5.3
 	github.com/pelletier/go-toml v1.9.5
-)
-
-require (
-	gopkg.in/natefinch/lumberjack.v2 v2.2.1 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
+	gopkg.in/yaml.v3 v3.0.1
 )
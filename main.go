@@ -1,24 +1,74 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"stonehenge-flash/agent"
 )
 
+// stringList 可重复指定的字符串参数
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// envList 可重复指定的 KEY=VALUE 环境变量参数
+type envList map[string]string
+
+func (e envList) String() string {
+	pairs := make([]string, 0, len(e))
+	for k, v := range e {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (e envList) Set(value string) error {
+	kv := strings.SplitN(value, "=", 2)
+	if len(kv) != 2 || kv[0] == "" {
+		return fmt.Errorf("环境变量格式应为 KEY=VALUE: %s", value)
+	}
+	e[kv[0]] = kv[1]
+	return nil
+}
+
 func main() {
-	// mevbot 配置文件路径
-	tomlConfigPath := "config.toml"
+	var (
+		overrides agent.Overrides
+		botArgs   stringList
+		botEnv    = envList{}
+	)
+
 	// flash agent 配置文件路径
-	yamlConfigPath := "config.yaml"
+	yamlConfigPath := flag.String("agent-config", "config.yaml", "FlashAgent YAML配置文件路径")
+	// mevbot 配置文件路径，未指定时使用YAML中的 process.config_path
+	flag.StringVar(&overrides.MevConfigPath, "config", "", "MEV Bot TOML配置文件路径")
+	flag.StringVar(&overrides.ListenAddr, "listen", "", "WebSocket服务监听地址")
+	flag.StringVar(&overrides.Executable, "bot", "", "MEV Bot可执行文件路径")
+	flag.StringVar(&overrides.WorkDir, "workdir", "", "MEV Bot工作目录")
+	flag.Var(&botArgs, "bot-arg", "MEV Bot启动参数，可重复指定，{config} 替换为TOML配置文件路径")
+	flag.Var(botEnv, "env", "MEV Bot额外环境变量 KEY=VALUE，可重复指定")
+	flag.Parse()
+
+	overrides.Args = botArgs
+	overrides.Env = botEnv
 
 	log.Println("启动MEV Bot监控代理...")
 
 	// 创建代理实例
-	a, err := agent.NewAgent(tomlConfigPath, yamlConfigPath)
+	a, err := agent.NewAgent(*yamlConfigPath, overrides)
 	if err != nil {
 		log.Fatalf("初始化代理失败: %v", err)
 	}